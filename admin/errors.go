@@ -0,0 +1,12 @@
+package admin
+
+import "errors"
+
+var (
+	ErrUnknownAction    = errors.New("action is unknown")
+	ErrUnknownState     = errors.New("state is unknown")
+	ErrInvalidLimit     = errors.New("limit must be a positive integer")
+	ErrInvalidQuery     = errors.New("pending must be a boolean and updatedBefore must be RFC 3339 time")
+	ErrNotFound         = errors.New("resource not found")
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
@@ -0,0 +1,381 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/violetpay-org/go-saga"
+	"github.com/violetpay-org/go-saga/messageRelayer"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLimit is the number of sessions or messages listed when the request does not set a limit.
+const DefaultLimit = 100
+
+// Handler serves an HTTP API to inspect the sagas of a registry and the channels of a relayer,
+// and to act on the sessions of the sagas. Mount it under a prefix with http.StripPrefix.
+//
+//	GET  /sagas
+//	GET  /sagas/{saga}
+//	GET  /sagas/{saga}/sessions?state={state}&pending={bool}&step={step}&updatedBefore={time}&after={id}&limit={limit}
+//	GET  /sagas/{saga}/sessions/{id}
+//	POST /sagas/{saga}/sessions/{id}/{action}
+//	GET  /channels
+//	GET  /channels/{channel}/outbox?limit={limit}
+//	GET  /channels/{channel}/dead-letters?limit={limit}
+//
+// The action is one of pause, resume, cancel, skip, complete, fail and retry. Sessions can be listed only if
+// the repository of the saga implements saga.SessionQueryRepository. The parameters of the list are the fields of
+// saga.SessionQuery: state can be repeated and takes the names of saga.State, like NeedsIntervention,
+// updatedBefore takes RFC 3339 time, and after takes the ID of the last session of the previous page.
+type Handler[Tx saga.TxContext] struct {
+	registry *saga.Registry[Tx]
+	channels messageRelayer.ChannelRegistry[Tx]
+}
+
+// NewHandler returns the handler of the registry. channels can be nil if no relayer is used.
+func NewHandler[Tx saga.TxContext](registry *saga.Registry[Tx], channels messageRelayer.ChannelRegistry[Tx]) *Handler[Tx] {
+	return &Handler[Tx]{
+		registry: registry,
+		channels: channels,
+	}
+}
+
+func (h *Handler[Tx]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments, err := pathSegments(r.URL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch {
+	case len(segments) == 1 && segments[0] == "sagas":
+		h.get(w, r, h.listSagas)
+	case len(segments) == 2 && segments[0] == "sagas":
+		h.get(w, r, func(r *http.Request) (interface{}, error) {
+			return h.getSaga(segments[1])
+		})
+	case len(segments) == 3 && segments[0] == "sagas" && segments[2] == "sessions":
+		h.get(w, r, func(r *http.Request) (interface{}, error) {
+			return h.listSessions(r, segments[1])
+		})
+	case len(segments) == 4 && segments[0] == "sagas" && segments[2] == "sessions":
+		h.get(w, r, func(r *http.Request) (interface{}, error) {
+			return h.getSession(r.Context(), segments[1], segments[3])
+		})
+	case len(segments) == 5 && segments[0] == "sagas" && segments[2] == "sessions":
+		h.post(w, r, func(r *http.Request) (interface{}, error) {
+			return h.act(r.Context(), segments[1], segments[3], segments[4])
+		})
+	case len(segments) == 1 && segments[0] == "channels" && h.channels != nil:
+		h.get(w, r, h.listChannels)
+	case len(segments) == 3 && segments[0] == "channels" && segments[2] == "outbox" && h.channels != nil:
+		h.get(w, r, func(r *http.Request) (interface{}, error) {
+			return h.listMessages(r, segments[1], false)
+		})
+	case len(segments) == 3 && segments[0] == "channels" && segments[2] == "dead-letters" && h.channels != nil:
+		h.get(w, r, func(r *http.Request) (interface{}, error) {
+			return h.listMessages(r, segments[1], true)
+		})
+	default:
+		writeError(w, http.StatusNotFound, ErrNotFound)
+	}
+}
+
+func (h *Handler[Tx]) get(w http.ResponseWriter, r *http.Request, fn func(r *http.Request) (interface{}, error)) {
+	h.serve(w, r, http.MethodGet, fn)
+}
+
+func (h *Handler[Tx]) post(w http.ResponseWriter, r *http.Request, fn func(r *http.Request) (interface{}, error)) {
+	h.serve(w, r, http.MethodPost, fn)
+}
+
+func (h *Handler[Tx]) serve(w http.ResponseWriter, r *http.Request, method string, fn func(r *http.Request) (interface{}, error)) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+		return
+	}
+
+	body, err := fn(r)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (h *Handler[Tx]) listSagas(r *http.Request) (interface{}, error) {
+	sagas := h.registry.Sagas()
+
+	views := make([]SagaView, 0, len(sagas))
+	for _, s := range sagas {
+		views = append(views, newSagaView(s))
+	}
+
+	return views, nil
+}
+
+func (h *Handler[Tx]) getSaga(sagaName string) (interface{}, error) {
+	s, ok := h.registry.Saga(sagaName)
+	if !ok {
+		return nil, saga.ErrSagaNotFound
+	}
+
+	return newSagaView(s), nil
+}
+
+func (h *Handler[Tx]) listSessions(r *http.Request, sagaName string) (interface{}, error) {
+	query, err := sessionQueryOf(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := h.registry.QuerySessions(r.Context(), sagaName, query)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]SessionView, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, newSessionView(sagaName, session, false))
+	}
+
+	return views, nil
+}
+
+func (h *Handler[Tx]) getSession(ctx context.Context, sagaName string, sessionID string) (interface{}, error) {
+	s, ok := h.registry.Saga(sagaName)
+	if !ok {
+		return nil, saga.ErrSagaNotFound
+	}
+
+	session, err := loadSession(ctx, s, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSessionView(sagaName, session, true), nil
+}
+
+// act applies the action to the session, and returns the session after the action.
+func (h *Handler[Tx]) act(ctx context.Context, sagaName string, sessionID string, action string) (interface{}, error) {
+	var err error
+	switch action {
+	case "pause":
+		err = h.registry.PauseSession(ctx, sagaName, sessionID)
+	case "resume":
+		err = h.registry.ResumeSession(ctx, sagaName, sessionID)
+	case "cancel":
+		err = h.registry.CancelSession(ctx, sagaName, sessionID)
+	case "skip":
+		err = h.registry.SkipStep(ctx, sagaName, sessionID)
+	case "complete":
+		err = h.registry.CompleteSession(ctx, sagaName, sessionID)
+	case "fail":
+		err = h.registry.FailSession(ctx, sagaName, sessionID)
+	case "retry":
+		err = h.registry.RetryStep(ctx, sagaName, sessionID)
+	default:
+		return nil, ErrUnknownAction
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return h.getSession(ctx, sagaName, sessionID)
+}
+
+func (h *Handler[Tx]) listChannels(r *http.Request) (interface{}, error) {
+	var names []string
+	h.channels.Range(func(name saga.ChannelName, channel messageRelayer.Channel[Tx]) bool {
+		names = append(names, string(name))
+		return true
+	})
+
+	sort.Strings(names)
+	return ChannelsView{Channels: names}, nil
+}
+
+func (h *Handler[Tx]) listMessages(r *http.Request, channelName string, deadLetter bool) (interface{}, error) {
+	channel := h.channels.Find(saga.ChannelName(channelName))
+	if channel == nil {
+		return nil, saga.ErrChannelNotFound
+	}
+
+	limit, err := limitOf(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := loadMessages(r.Context(), channel.Repository(), limit, deadLetter)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]MessageView, 0, len(messages))
+	for _, message := range messages {
+		views = append(views, newMessageView(message))
+	}
+
+	return views, nil
+}
+
+func loadSession[Tx saga.TxContext](ctx context.Context, s saga.Saga[saga.Session, Tx], sessionID string) (saga.Session, error) {
+	if repository, ok := s.Repository().(saga.SessionContextRepository[saga.Session]); ok {
+		return repository.LoadContext(ctx, sessionID)
+	}
+
+	return s.Repository().Load(sessionID)
+}
+
+// loadMessages peeks at the messages if the repository can, so that listing them does not claim them.
+func loadMessages[Tx saga.TxContext](ctx context.Context, repository saga.AbstractMessageRepository[saga.Message, Tx], limit int, deadLetter bool) ([]saga.Message, error) {
+	peekRepository, canPeek := repository.(saga.AbstractMessagePeekRepository[saga.Message])
+	contextRepository, ok := repository.(saga.AbstractMessageLoadContextRepository[saga.Message])
+
+	switch {
+	case canPeek && deadLetter:
+		return peekRepository.PeekMessagesFromDeadLetter(ctx, limit)
+	case canPeek:
+		return peekRepository.PeekMessagesFromOutbox(ctx, limit)
+	case ok && deadLetter:
+		return contextRepository.GetMessagesFromDeadLetterContext(ctx, limit)
+	case ok:
+		return contextRepository.GetMessagesFromOutboxContext(ctx, limit)
+	case deadLetter:
+		return repository.GetMessagesFromDeadLetter(limit)
+	default:
+		return repository.GetMessagesFromOutbox(limit)
+	}
+}
+
+// pathSegments returns the unescaped segments of the path, so that names and IDs can contain escaped slashes.
+func pathSegments(u *url.URL) ([]string, error) {
+	path := strings.Trim(u.EscapedPath(), "/")
+	if path == "" {
+		return nil, nil
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, err
+		}
+		segments[i] = unescaped
+	}
+
+	return segments, nil
+}
+
+func sessionQueryOf(values url.Values) (saga.SessionQuery, error) {
+	limit, err := limitOf(values)
+	if err != nil {
+		return saga.SessionQuery{}, err
+	}
+
+	query := saga.SessionQuery{
+		CurrentStep: values.Get("step"),
+		After:       values.Get("after"),
+		Limit:       limit,
+	}
+
+	for _, name := range values["state"] {
+		state, ok := parseState(name)
+		if !ok {
+			return saga.SessionQuery{}, ErrUnknownState
+		}
+		query.States = append(query.States, state)
+	}
+
+	if values.Has("pending") {
+		pending, err := strconv.ParseBool(values.Get("pending"))
+		if err != nil {
+			return saga.SessionQuery{}, ErrInvalidQuery
+		}
+		query.Pending = &pending
+	}
+
+	if values.Has("updatedBefore") {
+		updatedBefore, err := time.Parse(time.RFC3339, values.Get("updatedBefore"))
+		if err != nil {
+			return saga.SessionQuery{}, ErrInvalidQuery
+		}
+		query.UpdatedBefore = updatedBefore
+	}
+
+	return query, nil
+}
+
+func limitOf(values url.Values) (int, error) {
+	if !values.Has("limit") {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(values.Get("limit"))
+	if err != nil || limit <= 0 {
+		return 0, ErrInvalidLimit
+	}
+
+	return limit, nil
+}
+
+func parseState(name string) (saga.State, bool) {
+	for _, state := range []saga.State{
+		saga.StateCommon,
+		saga.StateCompleted,
+		saga.StateFailed,
+		saga.StateIsCompensating,
+		saga.StateIsRetrying,
+		saga.StateNeedsIntervention,
+	} {
+		if state.String() == name {
+			return state, true
+		}
+	}
+
+	return 0, false
+}
+
+// statusOf returns the status code of the response failed with err.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, saga.ErrSagaNotFound),
+		errors.Is(err, saga.ErrSessionNotFound),
+		errors.Is(err, saga.ErrChannelNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUnknownAction),
+		errors.Is(err, ErrUnknownState),
+		errors.Is(err, ErrInvalidLimit),
+		errors.Is(err, ErrInvalidQuery):
+		return http.StatusBadRequest
+	case errors.Is(err, saga.ErrDeadSession),
+		errors.Is(err, saga.ErrSessionAfterPivot),
+		errors.Is(err, saga.ErrSessionNotPausable),
+		errors.Is(err, saga.ErrSessionVersionConflict),
+		errors.Is(err, saga.ErrSessionStepAndDefinitionMismatch):
+		return http.StatusConflict
+	case errors.Is(err, saga.ErrSessionQueryNotSupported):
+		return http.StatusNotImplemented
+	}
+
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorView{Error: err.Error()})
+}
@@ -0,0 +1,247 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/violetpay-org/go-saga"
+	"github.com/violetpay-org/go-saga/inmemory"
+	"github.com/violetpay-org/go-saga/messageRelayer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testSession struct {
+	saga.BaseSession
+}
+
+type testMessage struct {
+	saga.AbstractMessage
+}
+
+func newTestMessage(session *testSession) testMessage {
+	return testMessage{AbstractMessage: saga.NewAbstractMessageFromSession("message-"+session.ID(), session, "test")}
+}
+
+// newTestHandler returns the handler of a registry with the saga "order", whose session "order-1" waits for
+// the response of its first step, and a relayer whose command channel holds the command of the step.
+func newTestHandler(t *testing.T) *Handler[*inmemory.Tx] {
+	store := inmemory.NewStore()
+	registry := saga.NewRegistry(saga.NewOrchestrator[*inmemory.Tx](saga.NewUnitOfWorkFactory[*inmemory.Tx](store)))
+
+	commands := inmemory.NewMessageRepository[testMessage](store)
+	channels := messageRelayer.NewChannelRegistry[*inmemory.Tx]()
+	assert.Nil(t, channels.Register(messageRelayer.NewChannel[testMessage, *inmemory.Tx](
+		"commands",
+		registry,
+		commands,
+		func(message saga.Message) error {
+			return nil
+		},
+	)))
+
+	endpoint := saga.NewEndpoint[*testSession, testMessage, testMessage, testMessage, *inmemory.Tx](
+		"commands",
+		newTestMessage,
+		commands,
+		"success",
+		newTestMessage,
+		"failure",
+		newTestMessage,
+	)
+
+	def, err := saga.NewStepBuilder[*inmemory.Tx]().
+		Step("Pay").
+		Invoke(endpoint).
+		WithCompensation(endpoint).
+		Step("Ship").
+		Invoke(endpoint).
+		Build()
+	assert.Nil(t, err)
+
+	sessions := inmemory.NewSessionRepository[*testSession](store, func(session *testSession) *testSession {
+		return &testSession{BaseSession: session.BaseSession.Clone()}
+	})
+	factory := func(args map[string]interface{}) *testSession {
+		return &testSession{BaseSession: saga.NewBaseSession(args)}
+	}
+	assert.Nil(t, saga.RegisterSagaTo(registry, saga.NewSaga[*testSession, *inmemory.Tx]("order", def, factory, sessions)))
+	assert.Nil(t, registry.StartSaga("order", map[string]interface{}{"id": "order-1"}))
+
+	return NewHandler[*inmemory.Tx](registry, channels)
+}
+
+func serve(handler http.Handler, method string, target string, body interface{}) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+
+	if body != nil {
+		_ = json.Unmarshal(recorder.Body.Bytes(), body)
+	}
+
+	return recorder
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("should describe the sagas", func(t *testing.T) {
+		handler := newTestHandler(t)
+
+		var sagas []SagaView
+		res := serve(handler, http.MethodGet, "/sagas", &sagas)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+		assert.Equal(t, 1, len(sagas))
+		assert.Equal(t, "order", sagas[0].Name)
+
+		var view SagaView
+		res = serve(handler, http.MethodGet, "/sagas/order", &view)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, []string{"Pay", "Ship"}, []string{view.Steps[0].Name, view.Steps[1].Name})
+		assert.True(t, view.Steps[0].Compensable)
+
+		var errView ErrorView
+		res = serve(handler, http.MethodGet, "/sagas/unknown", &errView)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+		assert.Equal(t, saga.ErrSagaNotFound.Error(), errView.Error)
+	})
+
+	t.Run("should list and get sessions", func(t *testing.T) {
+		handler := newTestHandler(t)
+
+		var sessions []SessionView
+		res := serve(handler, http.MethodGet, "/sagas/order/sessions?state=Common&pending=true&step=Pay", &sessions)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, 1, len(sessions))
+		assert.Equal(t, "order-1", sessions[0].ID)
+
+		sessions = nil
+		res = serve(handler, http.MethodGet, "/sagas/order/sessions?state=Failed", &sessions)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, 0, len(sessions))
+
+		var session SessionView
+		res = serve(handler, http.MethodGet, "/sagas/order/sessions/order-1", &session)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "order", session.Saga)
+		assert.Equal(t, "Pay", session.CurrentStep)
+		assert.True(t, session.Pending)
+		assert.NotEmpty(t, session.History)
+
+		res = serve(handler, http.MethodGet, "/sagas/order/sessions/order-2", nil)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("should reject invalid queries", func(t *testing.T) {
+		handler := newTestHandler(t)
+
+		for _, target := range []string{
+			"/sagas/order/sessions?state=Unknown",
+			"/sagas/order/sessions?pending=maybe",
+			"/sagas/order/sessions?updatedBefore=yesterday",
+			"/sagas/order/sessions?limit=0",
+			"/channels/commands/outbox?limit=many",
+		} {
+			res := serve(handler, http.MethodGet, target, nil)
+			assert.Equal(t, http.StatusBadRequest, res.Code, target)
+		}
+	})
+
+	t.Run("should act on sessions", func(t *testing.T) {
+		handler := newTestHandler(t)
+
+		var session SessionView
+		res := serve(handler, http.MethodPost, "/sagas/order/sessions/order-1/pause", &session)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.True(t, session.Paused)
+
+		session = SessionView{}
+		res = serve(handler, http.MethodPost, "/sagas/order/sessions/order-1/resume", &session)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.False(t, session.Paused)
+
+		session = SessionView{}
+		res = serve(handler, http.MethodPost, "/sagas/order/sessions/order-1/cancel", &session)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, saga.StateIsCompensating.String(), session.State)
+
+		session = SessionView{}
+		res = serve(handler, http.MethodPost, "/sagas/order/sessions/order-1/fail", &session)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, saga.StateFailed.String(), session.State)
+
+		var errView ErrorView
+		res = serve(handler, http.MethodPost, "/sagas/order/sessions/order-1/retry", &errView)
+		assert.Equal(t, http.StatusConflict, res.Code)
+		assert.Equal(t, saga.ErrDeadSession.Error(), errView.Error)
+	})
+
+	t.Run("should reject unknown actions", func(t *testing.T) {
+		handler := newTestHandler(t)
+
+		var errView ErrorView
+		res := serve(handler, http.MethodPost, "/sagas/order/sessions/order-1/explode", &errView)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+		assert.Equal(t, ErrUnknownAction.Error(), errView.Error)
+	})
+
+	t.Run("should reject methods other than the one of the route", func(t *testing.T) {
+		handler := newTestHandler(t)
+
+		for _, request := range []struct {
+			method string
+			target string
+			allow  string
+		}{
+			{http.MethodGet, "/sagas/order/sessions/order-1/pause", http.MethodPost},
+			{http.MethodPut, "/sagas/order/sessions/order-1/cancel", http.MethodPost},
+			{http.MethodPost, "/sagas", http.MethodGet},
+			{http.MethodPost, "/sagas/order/sessions/order-1", http.MethodGet},
+			{http.MethodDelete, "/channels/commands/outbox", http.MethodGet},
+		} {
+			res := serve(handler, request.method, request.target, nil)
+			assert.Equal(t, http.StatusMethodNotAllowed, res.Code, request.target)
+			assert.Equal(t, request.allow, res.Header().Get("Allow"), request.target)
+		}
+
+		// The session is not paused by the rejected request.
+		var session SessionView
+		serve(handler, http.MethodGet, "/sagas/order/sessions/order-1", &session)
+		assert.False(t, session.Paused)
+	})
+
+	t.Run("should list channels and their messages", func(t *testing.T) {
+		handler := newTestHandler(t)
+
+		var channels ChannelsView
+		res := serve(handler, http.MethodGet, "/channels", &channels)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, []string{"commands"}, channels.Channels)
+
+		var messages []MessageView
+		res = serve(handler, http.MethodGet, "/channels/commands/outbox?limit=10", &messages)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, 1, len(messages))
+		assert.Equal(t, "order-1", messages[0].SessionID)
+		assert.Equal(t, "Pay", messages[0].Step)
+
+		messages = nil
+		res = serve(handler, http.MethodGet, "/channels/commands/dead-letters", &messages)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, 0, len(messages))
+
+		res = serve(handler, http.MethodGet, "/channels/unknown/outbox", nil)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+
+	t.Run("should not serve unknown routes", func(t *testing.T) {
+		handler := newTestHandler(t)
+
+		for _, target := range []string{"/", "/unknown", "/sagas/order/steps", "/sagas/order/sessions/order-1/pause/now"} {
+			res := serve(handler, http.MethodGet, target, nil)
+			assert.Equal(t, http.StatusNotFound, res.Code, target)
+		}
+
+		res := serve(NewHandler[*inmemory.Tx](saga.NewRegistry(saga.NewOrchestrator[*inmemory.Tx](nil)), nil), http.MethodGet, "/channels", nil)
+		assert.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
@@ -0,0 +1,164 @@
+package admin
+
+import (
+	"github.com/violetpay-org/go-saga"
+	"time"
+)
+
+// SagaView is the response body of a saga.
+type SagaView struct {
+	Name  string     `json:"name"`
+	Steps []StepView `json:"steps"`
+}
+
+// StepView is a step of the definition of a saga.
+type StepView struct {
+	Name        string `json:"name"`
+	Invocable   bool   `json:"invocable"`
+	Compensable bool   `json:"compensable"`
+	Retriable   bool   `json:"retriable"`
+	Pivot       bool   `json:"pivot"`
+
+	// Timeout is how long the step waits for a response, in Go duration format. It is empty if the step has no timeout.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// SessionView is the response body of a session. The fields of the optional session interfaces are set
+// only if the session implements them, and the history is set only when a single session is requested.
+type SessionView struct {
+	ID          string `json:"id"`
+	Saga        string `json:"saga"`
+	CurrentStep string `json:"currentStep"`
+	State       string `json:"state"`
+	Pending     bool   `json:"pending"`
+
+	Paused   bool       `json:"paused,omitempty"`
+	Attempt  int        `json:"attempt,omitempty"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+	Version  int64      `json:"version,omitempty"`
+
+	History []HistoryEntryView `json:"history,omitempty"`
+}
+
+// HistoryEntryView is an entry of the history of a session.
+type HistoryEntryView struct {
+	Step      string    `json:"step"`
+	Branch    string    `json:"branch,omitempty"`
+	Direction string    `json:"direction"`
+	Outcome   string    `json:"outcome"`
+	Timestamp time.Time `json:"timestamp"`
+	MessageID string    `json:"messageId,omitempty"`
+}
+
+// ChannelsView is the response body of the channels of the relayer.
+type ChannelsView struct {
+	Channels []string `json:"channels"`
+}
+
+// MessageView is a message in the outbox or the dead letters of a channel.
+type MessageView struct {
+	ID        string            `json:"id"`
+	SessionID string            `json:"sessionId"`
+	Trigger   string            `json:"trigger"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Step      string            `json:"step,omitempty"`
+	Attempt   int               `json:"attempt,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// ErrorView is the response body of a failed request.
+type ErrorView struct {
+	Error string `json:"error"`
+}
+
+func newSagaView[Tx saga.TxContext](s saga.Saga[saga.Session, Tx]) SagaView {
+	def := s.Definition()
+	pivot := def.Pivot()
+
+	steps := make([]StepView, 0)
+	for _, step := range def.Steps() {
+		view := StepView{
+			Name:        step.Name(),
+			Invocable:   step.IsInvocable(),
+			Compensable: step.IsCompensable(),
+			Retriable:   step.MustBeCompleted(),
+			Pivot:       pivot != nil && pivot.Name() == step.Name(),
+		}
+
+		if step.Timeout() > 0 {
+			view.Timeout = step.Timeout().String()
+		}
+
+		steps = append(steps, view)
+	}
+
+	return SagaView{
+		Name:  s.Name(),
+		Steps: steps,
+	}
+}
+
+func newSessionView(sagaName string, session saga.Session, withHistory bool) SessionView {
+	view := SessionView{
+		ID:      session.ID(),
+		Saga:    sagaName,
+		State:   session.State().String(),
+		Pending: session.IsPending(),
+	}
+
+	if step := session.CurrentStep(); step != nil {
+		view.CurrentStep = step.Name()
+	}
+
+	if pausable, ok := session.(saga.PausableSession); ok {
+		view.Paused = pausable.Paused()
+	}
+
+	if attemptSession, ok := session.(saga.AttemptSession); ok {
+		view.Attempt = attemptSession.Attempt()
+	}
+
+	if deadlineSession, ok := session.(saga.DeadlineSession); ok && !deadlineSession.Deadline().IsZero() {
+		deadline := deadlineSession.Deadline()
+		view.Deadline = &deadline
+	}
+
+	if versionedSession, ok := session.(saga.VersionedSession); ok {
+		view.Version = versionedSession.Version()
+	}
+
+	if historySession, ok := session.(saga.HistorySession); ok && withHistory {
+		for _, entry := range historySession.History() {
+			view.History = append(view.History, HistoryEntryView{
+				Step:      entry.Step,
+				Branch:    entry.Branch,
+				Direction: entry.Direction.String(),
+				Outcome:   entry.Outcome.String(),
+				Timestamp: entry.Timestamp,
+				MessageID: entry.MessageID,
+			})
+		}
+	}
+
+	return view
+}
+
+func newMessageView(message saga.Message) MessageView {
+	view := MessageView{
+		ID:        message.ID(),
+		SessionID: message.SessionID(),
+		Trigger:   message.Trigger(),
+		CreatedAt: message.CreatedAt(),
+	}
+
+	if stepMessage, ok := message.(saga.StepMessage); ok {
+		view.Step = stepMessage.StepName()
+		view.Attempt = stepMessage.Attempt()
+	}
+
+	if headerMessage, ok := message.(saga.HeaderMessage); ok && len(headerMessage.Headers()) > 0 {
+		view.Headers = headerMessage.Headers()
+	}
+
+	return view
+}
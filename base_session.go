@@ -0,0 +1,284 @@
+package saga
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// sessionData is the state of a BaseSession, as MarshalJSON encodes it.
+type sessionData struct {
+	ID       string
+	SagaName string
+
+	// CurrentStep is the name of the current step, or empty if the session has none.
+	CurrentStep string
+
+	Pending         bool
+	State           State
+	Deadline        time.Time
+	Attempt         int
+	BranchStates    map[string]BranchState
+	Conditions      map[string]bool
+	History         []HistoryEntry
+	ParentSagaName  string
+	ParentSessionID string
+	ChildSessionIDs map[string]string
+	Version         int64
+	Paused          bool
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// BaseSession implements Session and every optional interface of sessions: DeadlineSession, AttemptSession,
+// ParallelSession, ConditionSession, HistorySession, SubSagaSession, VersionedSession, PausableSession,
+// SagaSession and TimestampedSession. Embed it in the session of a saga to keep only the fields of the saga:
+//
+//	type OrderSession struct {
+//		saga.BaseSession
+//		Amount int
+//	}
+//
+//	var factory saga.SessionFactory[*OrderSession] = func(args map[string]interface{}) *OrderSession {
+//		return &OrderSession{BaseSession: saga.NewBaseSession(args)}
+//	}
+//
+// BaseSession encodes its state by MarshalJSON and UnmarshalJSON, which the type embedding it inherits.
+// A type with fields of its own encodes them next to the BaseSession by its own methods:
+//
+//	func (s *OrderSession) MarshalJSON() ([]byte, error) {
+//		return json.Marshal(s.fields())
+//	}
+//
+//	func (s *OrderSession) UnmarshalJSON(data []byte) error {
+//		return json.Unmarshal(data, s.fields())
+//	}
+//
+//	func (s *OrderSession) fields() interface{} {
+//		return &struct {
+//			Session *saga.BaseSession
+//			Amount  *int
+//		}{&s.BaseSession, &s.Amount}
+//	}
+//
+// A decoded session has no current step until RestoreCurrentStep finds it in the definition of the saga.
+type BaseSession struct {
+	data sessionData
+
+	currentStep Step
+}
+
+// NewBaseSession returns the session with the ID and the saga name of the arguments given to SessionFactory.
+func NewBaseSession(args map[string]interface{}) BaseSession {
+	id, _ := args["id"].(string)
+	sagaName, _ := args["sagaName"].(string)
+
+	return BaseSession{
+		data: sessionData{
+			ID:        id,
+			SagaName:  sagaName,
+			CreatedAt: time.Now(),
+		},
+	}
+}
+
+func (s *BaseSession) ID() string {
+	return s.data.ID
+}
+
+func (s *BaseSession) SagaName() string {
+	return s.data.SagaName
+}
+
+func (s *BaseSession) CurrentStep() Step {
+	return s.currentStep
+}
+
+func (s *BaseSession) UpdateCurrentStep(step Step) error {
+	s.currentStep = step
+	s.data.CurrentStep = ""
+	if step != nil {
+		s.data.CurrentStep = step.Name()
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the state of the session, with the name of its current step in place of the step.
+func (s *BaseSession) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.data)
+}
+
+// UnmarshalJSON restores the state of the session encoded by MarshalJSON. Call RestoreCurrentStep afterwards
+// to restore the current step.
+func (s *BaseSession) UnmarshalJSON(data []byte) error {
+	var decoded sessionData
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	s.data = decoded
+	s.currentStep = nil
+	return nil
+}
+
+// RestoreCurrentStep finds the current step of a decoded session in the definition by its name.
+// It fails with ErrSessionStepAndDefinitionMismatch if the definition has no step with the name.
+func (s *BaseSession) RestoreCurrentStep(def Definition) error {
+	if s.data.CurrentStep == "" {
+		s.currentStep = nil
+		return nil
+	}
+
+	step := def.FindStep(s.data.CurrentStep)
+	if step == nil {
+		return fmt.Errorf("%w: step %q of session %s is not defined", ErrSessionStepAndDefinitionMismatch, s.data.CurrentStep, s.data.ID)
+	}
+
+	s.currentStep = step
+	return nil
+}
+
+func (s *BaseSession) IsPending() bool {
+	return s.data.Pending
+}
+
+func (s *BaseSession) SetPending(pending bool) {
+	s.data.Pending = pending
+}
+
+func (s *BaseSession) State() State {
+	return s.data.State
+}
+
+func (s *BaseSession) SetState(state State) {
+	s.data.State = state
+}
+
+func (s *BaseSession) Deadline() time.Time {
+	return s.data.Deadline
+}
+
+func (s *BaseSession) SetDeadline(deadline time.Time) {
+	s.data.Deadline = deadline
+}
+
+func (s *BaseSession) Attempt() int {
+	return s.data.Attempt
+}
+
+func (s *BaseSession) SetAttempt(attempt int) {
+	s.data.Attempt = attempt
+}
+
+func (s *BaseSession) BranchState(step string, branch string) (BranchState, bool) {
+	state, ok := s.data.BranchStates[step+"/"+branch]
+	return state, ok
+}
+
+func (s *BaseSession) SetBranchState(step string, branch string, state BranchState) {
+	if s.data.BranchStates == nil {
+		s.data.BranchStates = make(map[string]BranchState)
+	}
+	s.data.BranchStates[step+"/"+branch] = state
+}
+
+func (s *BaseSession) Condition(name string) (bool, bool) {
+	result, ok := s.data.Conditions[name]
+	return result, ok
+}
+
+func (s *BaseSession) SetCondition(name string, result bool) {
+	if s.data.Conditions == nil {
+		s.data.Conditions = make(map[string]bool)
+	}
+	s.data.Conditions[name] = result
+}
+
+func (s *BaseSession) History() []HistoryEntry {
+	return s.data.History
+}
+
+func (s *BaseSession) AppendHistory(entry HistoryEntry) {
+	s.data.History = append(s.data.History, entry)
+}
+
+func (s *BaseSession) ParentSession() (string, string) {
+	return s.data.ParentSagaName, s.data.ParentSessionID
+}
+
+func (s *BaseSession) SetParentSession(sagaName string, sessionID string) {
+	s.data.ParentSagaName = sagaName
+	s.data.ParentSessionID = sessionID
+}
+
+func (s *BaseSession) ChildSessionID(step string) (string, bool) {
+	id, ok := s.data.ChildSessionIDs[step]
+	return id, ok
+}
+
+func (s *BaseSession) SetChildSessionID(step string, id string) {
+	if s.data.ChildSessionIDs == nil {
+		s.data.ChildSessionIDs = make(map[string]string)
+	}
+	s.data.ChildSessionIDs[step] = id
+}
+
+func (s *BaseSession) Version() int64 {
+	return s.data.Version
+}
+
+func (s *BaseSession) SetVersion(version int64) {
+	s.data.Version = version
+}
+
+func (s *BaseSession) Paused() bool {
+	return s.data.Paused
+}
+
+func (s *BaseSession) SetPaused(paused bool) {
+	s.data.Paused = paused
+}
+
+// CreatedAt returns when the session was created by NewBaseSession.
+func (s *BaseSession) CreatedAt() time.Time {
+	return s.data.CreatedAt
+}
+
+func (s *BaseSession) UpdatedAt() time.Time {
+	return s.data.UpdatedAt
+}
+
+func (s *BaseSession) SetUpdatedAt(updatedAt time.Time) {
+	s.data.UpdatedAt = updatedAt
+}
+
+// Clone returns a copy of the session which shares no map or slice with it,
+// like the repositories which keep sessions in memory need.
+func (s *BaseSession) Clone() BaseSession {
+	cloned := *s
+
+	if s.data.BranchStates != nil {
+		cloned.data.BranchStates = make(map[string]BranchState, len(s.data.BranchStates))
+		for key, state := range s.data.BranchStates {
+			cloned.data.BranchStates[key] = state
+		}
+	}
+
+	if s.data.Conditions != nil {
+		cloned.data.Conditions = make(map[string]bool, len(s.data.Conditions))
+		for name, result := range s.data.Conditions {
+			cloned.data.Conditions[name] = result
+		}
+	}
+
+	if s.data.ChildSessionIDs != nil {
+		cloned.data.ChildSessionIDs = make(map[string]string, len(s.data.ChildSessionIDs))
+		for step, id := range s.data.ChildSessionIDs {
+			cloned.data.ChildSessionIDs[step] = id
+		}
+	}
+
+	cloned.data.History = append([]HistoryEntry(nil), s.data.History...)
+	return cloned
+}
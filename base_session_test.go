@@ -0,0 +1,105 @@
+package saga
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBaseSession(t *testing.T) {
+	newSession := func() *BaseSession {
+		session := NewBaseSession(map[string]interface{}{"id": "OrderSaga-1", "sagaName": "OrderSaga"})
+		session.SetPending(true)
+		session.SetState(StateIsRetrying)
+		session.SetDeadline(time.Now().Add(time.Minute))
+		session.SetAttempt(2)
+		session.SetBranchState("Pay", "Card", BranchStateSucceeded)
+		session.SetCondition("isMember", true)
+		session.AppendHistory(HistoryEntry{Step: "Reserve", Direction: DirectionForward, Outcome: OutcomeSucceeded})
+		session.SetParentSession("ParentSaga", "ParentSaga-1")
+		session.SetChildSessionID("Reserve", "ChildSaga-1")
+		session.SetVersion(3)
+		session.SetPaused(true)
+		session.SetUpdatedAt(time.Now())
+		return &session
+	}
+
+	t.Run("should encode and decode its state as JSON", func(t *testing.T) {
+		session := newSession()
+
+		encoded, err := json.Marshal(session)
+		assert.Nil(t, err)
+
+		decoded := &BaseSession{}
+		assert.Nil(t, json.Unmarshal(encoded, decoded))
+		assert.Equal(t, session.data.ID, decoded.ID())
+		assert.Equal(t, "OrderSaga", decoded.SagaName())
+		assert.True(t, decoded.IsPending())
+		assert.Equal(t, StateIsRetrying, decoded.State())
+		assert.True(t, session.Deadline().Equal(decoded.Deadline()))
+		assert.Equal(t, 2, decoded.Attempt())
+		state, ok := decoded.BranchState("Pay", "Card")
+		assert.True(t, ok)
+		assert.Equal(t, BranchStateSucceeded, state)
+		result, evaluated := decoded.Condition("isMember")
+		assert.True(t, result && evaluated)
+		assert.Equal(t, session.History(), decoded.History())
+		parentSagaName, parentID := decoded.ParentSession()
+		assert.Equal(t, []string{"ParentSaga", "ParentSaga-1"}, []string{parentSagaName, parentID})
+		childID, ok := decoded.ChildSessionID("Reserve")
+		assert.True(t, ok)
+		assert.Equal(t, "ChildSaga-1", childID)
+		assert.Equal(t, int64(3), decoded.Version())
+		assert.True(t, decoded.Paused())
+		assert.True(t, session.CreatedAt().Equal(decoded.CreatedAt()))
+		assert.True(t, session.UpdatedAt().Equal(decoded.UpdatedAt()))
+
+		assert.NotNil(t, json.Unmarshal([]byte(`{"ID": 1}`), decoded))
+		assert.Equal(t, "OrderSaga-1", decoded.ID())
+	})
+
+	t.Run("should encode the fields of the session embedding it next to it", func(t *testing.T) {
+		type orderSession struct {
+			BaseSession
+			Amount int
+		}
+
+		fields := func(session *orderSession) interface{} {
+			return &struct {
+				Session *BaseSession
+				Amount  *int
+			}{&session.BaseSession, &session.Amount}
+		}
+
+		session := &orderSession{BaseSession: *newSession(), Amount: 100}
+		encoded, err := json.Marshal(fields(session))
+		assert.Nil(t, err)
+
+		decoded := &orderSession{}
+		assert.Nil(t, json.Unmarshal(encoded, fields(decoded)))
+		assert.Equal(t, 100, decoded.Amount)
+		assert.Equal(t, "OrderSaga-1", decoded.ID())
+		assert.Equal(t, int64(3), decoded.Version())
+	})
+
+	t.Run("should clone its state without sharing maps and slices", func(t *testing.T) {
+		session := newSession()
+		cloned := session.Clone()
+
+		cloned.SetBranchState("Pay", "Card", BranchStateFailed)
+		cloned.SetCondition("isMember", false)
+		cloned.SetChildSessionID("Reserve", "ChildSaga-2")
+		cloned.AppendHistory(HistoryEntry{Step: "Pay", Direction: DirectionForward, Outcome: OutcomeInvoked})
+		cloned.SetState(StateFailed)
+
+		state, _ := session.BranchState("Pay", "Card")
+		assert.Equal(t, BranchStateSucceeded, state)
+		result, _ := session.Condition("isMember")
+		assert.True(t, result)
+		childID, _ := session.ChildSessionID("Reserve")
+		assert.Equal(t, "ChildSaga-1", childID)
+		assert.Len(t, session.History(), 1)
+		assert.Equal(t, StateIsRetrying, session.State())
+	})
+}
@@ -1,13 +1,66 @@
 package saga
 
+import "time"
+
 type stepBuilder[Tx TxContext] interface {
 	Step(name string) invocableBuild[Tx]
-	Build() Definition
+
+	// If starts a block of steps which are executed only if the condition is true.
+	// The condition is evaluated once, when the saga reaches the first step of the block.
+	If(name string, condition Condition) stepBuilder[Tx]
+
+	// Else starts the block of steps which are executed only if the condition of the current If block is false.
+	Else() stepBuilder[Tx]
+
+	// EndIf closes the current If block.
+	EndIf() stepBuilder[Tx]
+
+	// Build returns the definition of the steps, or a *ValidationError listing every problem of the definition.
+	Build() (Definition, error)
+
+	// MustBuild is like Build but panics if the definition has problems.
+	// It simplifies building the branches of parallel steps inline.
+	MustBuild() Definition
 }
 
 type invocableBuild[Tx TxContext] interface {
 	Invoke(endpoint Endpoint[Tx]) invocationOptionBuild[Tx]
 	LocalInvoke(endpoint LocalEndpoint[Tx]) localInvocationOptionBuild[Tx]
+
+	// Parallel invokes every step of branches at once, and waits for the responses of all of them.
+	// If any branch fails, only the branches which succeeded are compensated. Branches are not retried,
+	// must be remote or local steps, and must respond on channels that are not shared with the other branches.
+	// Since the step is not retried as a whole, it cannot follow the pivot step.
+	Parallel(branches Definition) stepBuilder[Tx]
+
+	// SubSaga starts a session of the saga registered with sagaName, with the arguments returned by args,
+	// and waits until the session is completed or failed. The session is started in the same unit of work
+	// as the parent session, and the parent session steps forward when it is completed.
+	// A failed session of the sub-saga is not started again, so the step cannot follow the pivot step.
+	SubSaga(sagaName string, args SubSagaArgs) subSagaOptionBuild[Tx]
+	stepOptionBuild[Tx]
+}
+
+type subSagaOptionBuild[Tx TxContext] interface {
+	stepBuilder[Tx]
+
+	// WithSubSagaCompensation makes the step compensable. The completed session of the sub-saga
+	// is compensated by stepping backward from its last step.
+	WithSubSagaCompensation() stepBuilder[Tx]
+}
+
+type stepOptionBuild[Tx TxContext] interface {
+	// WithTimeout sets how long the step waits for a response before it is handled as failed.
+	// Timeouts are detected by TimeoutSweeper.
+	WithTimeout(timeout time.Duration) invocableBuild[Tx]
+
+	// When makes the step executed only if the condition is true. Otherwise, the step is skipped.
+	When(condition Condition) invocableBuild[Tx]
+
+	// AsPivot makes the step the pivot of the saga. Once the pivot step succeeds, the saga cannot step backward,
+	// so every step after it must be retriable, and its failures are retried to completion instead of compensated.
+	// Parallel and sub-saga steps are never retried, so they cannot be after the pivot step.
+	AsPivot() invocableBuild[Tx]
 }
 
 type invocationOptionBuild[Tx TxContext] interface {
@@ -33,16 +86,34 @@ type localCompensableBuild[Tx TxContext] interface {
 type invokeOptionWithoutCompensationBuild[Tx TxContext] interface {
 	stepBuilder[Tx]
 	retryableBuild[Tx]
+	compensationRetryableBuild[Tx]
+}
+
+type compensationRetryableBuild[Tx TxContext] interface {
+	// RetryCompensationWith sets how a failed compensation of the step is retried.
+	// Without it, a failed compensation is retried immediately and forever.
+	RetryCompensationWith(policy RetryPolicy) invokeOptionWithoutCompensationRetryBuild[Tx]
+}
+
+type invokeOptionWithoutCompensationRetryBuild[Tx TxContext] interface {
+	stepBuilder[Tx]
+	retryableBuild[Tx]
 }
 
 type retryableBuild[Tx TxContext] interface {
+	// Retry makes the step retried immediately and forever until it is completed.
 	Retry() stepBuilder[Tx]
+
+	// RetryWith makes the step retried until it is completed, as the policy allows.
+	RetryWith(policy RetryPolicy) stepBuilder[Tx]
 }
 
 type StepBuilder[Tx TxContext] struct {
-	steps           []Step
-	currentStep     Step
-	currentStepName string
+	steps              []Step
+	currentStep        Step
+	currentStepName    string
+	currentStepOptions stepOptions
+	conditionBlocks    []stepCondition
 }
 
 func NewStepBuilder[Tx TxContext]() stepBuilder[Tx] {
@@ -52,30 +123,71 @@ func NewStepBuilder[Tx TxContext]() stepBuilder[Tx] {
 }
 
 func (b *StepBuilder[Tx]) Invoke(endpoint Endpoint[Tx]) invocationOptionBuild[Tx] {
-	b.currentStep = newRemoteStep(b.currentStepName, endpoint)
+	b.currentStep = newRemoteStep(b.currentStepName, endpoint, b.currentStepOptions)
 
 	return b
 }
 
 func (b *StepBuilder[Tx]) LocalInvoke(endpoint LocalEndpoint[Tx]) localInvocationOptionBuild[Tx] {
-	b.currentStep = newLocalStep(b.currentStepName, endpoint)
+	b.currentStep = newLocalStep(b.currentStepName, endpoint, b.currentStepOptions)
 
 	return b
 }
 
-func (b *StepBuilder[Tx]) Build() Definition {
-	if b.currentStep != nil {
-		b.steps = append(b.steps, b.currentStep)
-	}
+func (b *StepBuilder[Tx]) Parallel(branches Definition) stepBuilder[Tx] {
+	b.currentStep = newParallelStep[Tx](b.currentStepName, branches, b.currentStepOptions)
+
+	return b
+}
+
+func (b *StepBuilder[Tx]) SubSaga(sagaName string, args SubSagaArgs) subSagaOptionBuild[Tx] {
+	b.currentStep = newSubSagaStep[Tx](b.currentStepName, sagaName, args, b.currentStepOptions)
+
+	return b
+}
+
+func (b *StepBuilder[Tx]) WithSubSagaCompensation() stepBuilder[Tx] {
+	step := b.currentStep.(subSagaStep[Tx])
+	step.compensable = true
+	b.currentStep = step
+
+	return b
+}
+
+func (b *StepBuilder[Tx]) Build() (Definition, error) {
 	defer b.cleanUp()
 
-	return newDefinition(b.steps)
+	b.flushCurrentStep()
+	def := newDefinition(b.steps)
+
+	v := &validator{}
+	if len(b.conditionBlocks) > 0 {
+		v.add(ValidationProblem{Err: ErrUnclosedIfBlock})
+	}
+	v.validateDefinition(def)
+
+	if err := v.err(); err != nil {
+		return Definition{}, err
+	}
+
+	return def, nil
+}
+
+func (b *StepBuilder[Tx]) MustBuild() Definition {
+	def, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+
+	return def
 }
 
 func (b *StepBuilder[Tx]) cleanUp() {
 	b.steps = make([]Step, 0)
 	b.currentStep = nil
 	b.currentStepName = ""
+	b.currentStepOptions = stepOptions{}
+	b.conditionBlocks = nil
 }
 
 func (b *StepBuilder[Tx]) WithCompensation(endpoint Endpoint[Tx]) invokeOptionWithoutCompensationBuild[Tx] {
@@ -89,12 +201,16 @@ func (b *StepBuilder[Tx]) WithLocalCompensation(endpoint LocalEndpoint[Tx]) invo
 }
 
 func (b *StepBuilder[Tx]) Retry() stepBuilder[Tx] {
+	return b.RetryWith(RetryPolicy{})
+}
+
+func (b *StepBuilder[Tx]) RetryWith(policy RetryPolicy) stepBuilder[Tx] {
 	var newRet Step
 	switch b.currentStep.(type) {
 	case remoteStep[Tx]:
-		newRet = newRemoteStepWithRetry(b.currentStep.(remoteStep[Tx]))
+		newRet = newRemoteStepWithRetry(b.currentStep.(remoteStep[Tx]), policy)
 	case localStep[Tx]:
-		newRet = newLocalStepWithRetry(b.currentStep.(localStep[Tx]))
+		newRet = newLocalStepWithRetry(b.currentStep.(localStep[Tx]), policy)
 	default:
 		panic("Unknown step type")
 	}
@@ -103,13 +219,88 @@ func (b *StepBuilder[Tx]) Retry() stepBuilder[Tx] {
 	return b
 }
 
+func (b *StepBuilder[Tx]) RetryCompensationWith(policy RetryPolicy) invokeOptionWithoutCompensationRetryBuild[Tx] {
+	switch step := b.currentStep.(type) {
+	case remoteStep[Tx]:
+		step.compensationRetryPolicy = policy
+		b.currentStep = step
+	case localStep[Tx]:
+		step.compensationRetryPolicy = policy
+		b.currentStep = step
+	default:
+		panic("Unknown step type")
+	}
+
+	return b
+}
+
 func (b *StepBuilder[Tx]) Step(name string) invocableBuild[Tx] {
+	b.flushCurrentStep()
+
+	b.currentStepName = name
+	b.currentStepOptions = stepOptions{}
+	b.currentStepOptions.conditions = append([]stepCondition(nil), b.conditionBlocks...)
+
+	return b
+}
+
+func (b *StepBuilder[Tx]) flushCurrentStep() {
 	if b.currentStep != nil {
 		b.steps = append(b.steps, b.currentStep)
 	}
 
 	b.currentStep = nil
-	b.currentStepName = name
+}
+
+func (b *StepBuilder[Tx]) If(name string, condition Condition) stepBuilder[Tx] {
+	b.flushCurrentStep()
+
+	b.conditionBlocks = append(b.conditionBlocks, stepCondition{name: name, condition: condition, expected: true})
+	return b
+}
+
+func (b *StepBuilder[Tx]) Else() stepBuilder[Tx] {
+	if len(b.conditionBlocks) == 0 {
+		panic("Else called without If")
+	}
+
+	last := len(b.conditionBlocks) - 1
+	if !b.conditionBlocks[last].expected {
+		panic("Else called twice for the same If")
+	}
+
+	b.flushCurrentStep()
+
+	b.conditionBlocks[last].expected = false
+	return b
+}
+
+func (b *StepBuilder[Tx]) EndIf() stepBuilder[Tx] {
+	if len(b.conditionBlocks) == 0 {
+		panic("EndIf called without If")
+	}
+
+	b.flushCurrentStep()
+
+	b.conditionBlocks = b.conditionBlocks[:len(b.conditionBlocks)-1]
+	return b
+}
+
+func (b *StepBuilder[Tx]) When(condition Condition) invocableBuild[Tx] {
+	b.currentStepOptions.conditions = append(b.currentStepOptions.conditions, stepCondition{
+		name:      b.currentStepName,
+		condition: condition,
+		expected:  true,
+	})
+	return b
+}
+
+func (b *StepBuilder[Tx]) WithTimeout(timeout time.Duration) invocableBuild[Tx] {
+	b.currentStepOptions.timeout = timeout
+	return b
+}
 
+func (b *StepBuilder[Tx]) AsPivot() invocableBuild[Tx] {
+	b.currentStepOptions.pivot = true
 	return b
 }
@@ -3,6 +3,7 @@ package saga
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestStepBuilder(t *testing.T) {
@@ -12,12 +13,12 @@ func TestStepBuilder(t *testing.T) {
 	}
 
 	endpoint := NewEndpoint[Session, mockMessage, mockMessage, mockMessage, mockTxContext](
-		"",
+		"command",
 		messageConstructor,
 		repo,
-		"",
+		"success",
 		messageConstructor,
-		"",
+		"failure",
 		messageConstructor,
 	)
 
@@ -26,10 +27,10 @@ func TestStepBuilder(t *testing.T) {
 	}
 
 	localEndpoint := NewLocalEndpoint[Session, mockMessage, mockMessage, mockTxContext](
-		"",
+		"success",
 		messageConstructor,
 		repo,
-		"",
+		"failure",
 		messageConstructor,
 		repo,
 		handler,
@@ -47,12 +48,13 @@ func TestStepBuilder(t *testing.T) {
 	//var localEndpoint LocalEndpoint
 
 	t.Run("Build", func(t *testing.T) {
-		def := builder.
+		def, err := builder.
 			Step(step1Name).
 			Invoke(endpoint).
 			WithCompensation(endpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 1, len(def.steps))
 
@@ -63,12 +65,13 @@ func TestStepBuilder(t *testing.T) {
 	})
 
 	t.Run("Build with local endpoint", func(t *testing.T) {
-		def := builder.
+		def, err := builder.
 			Step(step1Name).
 			LocalInvoke(localEndpoint).
 			WithLocalCompensation(localEndpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 1, len(def.steps))
 
@@ -79,7 +82,7 @@ func TestStepBuilder(t *testing.T) {
 	})
 
 	t.Run("Build with multiple steps", func(t *testing.T) {
-		def := builder.
+		def, err := builder.
 			Step(step1Name).
 			Invoke(endpoint).
 			WithCompensation(endpoint).
@@ -89,6 +92,7 @@ func TestStepBuilder(t *testing.T) {
 			WithCompensation(endpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 2, len(def.steps))
 
@@ -104,7 +108,7 @@ func TestStepBuilder(t *testing.T) {
 	})
 
 	t.Run("Build with multiple steps with local endpoint", func(t *testing.T) {
-		def := builder.
+		def, err := builder.
 			Step(step1Name).
 			LocalInvoke(localEndpoint).
 			WithLocalCompensation(localEndpoint).
@@ -114,6 +118,7 @@ func TestStepBuilder(t *testing.T) {
 			WithLocalCompensation(localEndpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 2, len(def.steps))
 
@@ -129,7 +134,7 @@ func TestStepBuilder(t *testing.T) {
 	})
 
 	t.Run("Build with multiple steps with mixed endpoint", func(t *testing.T) {
-		def := builder.
+		def, err := builder.
 			Step(step1Name).
 			Invoke(endpoint).
 			WithCompensation(endpoint).
@@ -139,6 +144,7 @@ func TestStepBuilder(t *testing.T) {
 			WithLocalCompensation(localEndpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 2, len(def.steps))
 		assert.Equal(t, step1Name, def.steps[0].Name())
@@ -151,7 +157,7 @@ func TestStepBuilder(t *testing.T) {
 		assert.True(t, def.steps[1].IsInvocable())
 		assert.True(t, def.steps[1].MustBeCompleted())
 
-		def = builder.
+		def, err = builder.
 			Step(step1Name).
 			LocalInvoke(localEndpoint).
 			WithLocalCompensation(localEndpoint).
@@ -161,6 +167,7 @@ func TestStepBuilder(t *testing.T) {
 			WithCompensation(endpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 2, len(def.steps))
 
@@ -176,11 +183,12 @@ func TestStepBuilder(t *testing.T) {
 	})
 
 	t.Run("Build with no options", func(t *testing.T) {
-		def := builder.
+		def, err := builder.
 			Step(step1Name).
 			Invoke(endpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 1, len(def.steps))
 
@@ -189,11 +197,12 @@ func TestStepBuilder(t *testing.T) {
 		assert.True(t, def.steps[0].IsInvocable())
 		assert.True(t, def.steps[0].MustBeCompleted())
 
-		def = builder.
+		def, err = builder.
 			Step(step1Name).
 			LocalInvoke(localEndpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 1, len(def.steps))
 
@@ -202,7 +211,7 @@ func TestStepBuilder(t *testing.T) {
 		assert.True(t, def.steps[0].IsInvocable())
 		assert.True(t, def.steps[0].MustBeCompleted())
 
-		def = builder.
+		def, err = builder.
 			Step(step1Name).
 			Invoke(endpoint).
 			Retry().
@@ -210,6 +219,7 @@ func TestStepBuilder(t *testing.T) {
 			Invoke(endpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 2, len(def.steps))
 
@@ -223,11 +233,12 @@ func TestStepBuilder(t *testing.T) {
 		assert.True(t, def.steps[1].IsInvocable())
 		assert.True(t, def.steps[1].MustBeCompleted())
 
-		def = builder.
+		def, err = builder.
 			Step(step1Name).
 			Invoke(endpoint).
 			WithCompensation(endpoint).
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 1, len(def.steps))
 
@@ -236,11 +247,12 @@ func TestStepBuilder(t *testing.T) {
 		assert.True(t, def.steps[0].IsInvocable())
 		assert.False(t, def.steps[0].MustBeCompleted())
 
-		def = builder.
+		def, err = builder.
 			Step(step1Name).
 			LocalInvoke(localEndpoint).
 			WithLocalCompensation(localEndpoint).
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 1, len(def.steps))
 
@@ -249,7 +261,7 @@ func TestStepBuilder(t *testing.T) {
 		assert.True(t, def.steps[0].IsInvocable())
 		assert.False(t, def.steps[0].MustBeCompleted())
 
-		def = builder.
+		def, err = builder.
 			Step(step1Name).
 			Invoke(endpoint).
 			WithCompensation(endpoint).
@@ -257,6 +269,7 @@ func TestStepBuilder(t *testing.T) {
 			Invoke(endpoint).
 			WithCompensation(endpoint).
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 2, len(def.steps))
 
@@ -272,7 +285,7 @@ func TestStepBuilder(t *testing.T) {
 	})
 
 	t.Run("Build 3 steps with mixed endpoint", func(t *testing.T) {
-		def := builder.
+		def, err := builder.
 			Step(step1Name).
 			Invoke(endpoint).
 			WithCompensation(endpoint).
@@ -283,6 +296,7 @@ func TestStepBuilder(t *testing.T) {
 			Invoke(endpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 3, len(def.steps))
 
@@ -301,7 +315,7 @@ func TestStepBuilder(t *testing.T) {
 		assert.True(t, def.steps[2].IsInvocable())
 		assert.True(t, def.steps[2].MustBeCompleted())
 
-		def = builder.
+		def, err = builder.
 			Step(step1Name).
 			LocalInvoke(localEndpoint).
 			WithLocalCompensation(localEndpoint).
@@ -312,6 +326,7 @@ func TestStepBuilder(t *testing.T) {
 			LocalInvoke(localEndpoint).
 			Retry().
 			Build()
+		assert.Nil(t, err)
 
 		assert.Equal(t, 3, len(def.steps))
 
@@ -331,4 +346,276 @@ func TestStepBuilder(t *testing.T) {
 		assert.True(t, def.steps[2].MustBeCompleted())
 	})
 
+	t.Run("Build with timeout", func(t *testing.T) {
+		def, err := builder.
+			Step(step1Name).
+			WithTimeout(time.Second).
+			Invoke(endpoint).
+			WithCompensation(endpoint).
+			Step(step2Name).
+			LocalInvoke(localEndpoint).
+			Build()
+		assert.Nil(t, err)
+
+		assert.Equal(t, 2, len(def.steps))
+
+		assert.Equal(t, time.Second, def.steps[0].Timeout())
+		assert.True(t, def.steps[0].IsCompensable())
+		assert.Equal(t, time.Duration(0), def.steps[1].Timeout())
+	})
+
+	t.Run("Build with parallel step", func(t *testing.T) {
+		secondLocalEndpoint := NewLocalEndpoint[Session, mockMessage, mockMessage, mockTxContext](
+			"secondSuccess",
+			messageConstructor,
+			repo,
+			"secondFailure",
+			messageConstructor,
+			repo,
+			handler,
+		)
+
+		branches, err := NewStepBuilder[mockTxContext]().
+			Step(step2Name).
+			Invoke(endpoint).
+			WithCompensation(endpoint).
+			Step(step3Name).
+			LocalInvoke(secondLocalEndpoint).
+			Build()
+		assert.Nil(t, err)
+
+		_, err = builder.
+			Step(step1Name).
+			Parallel(NewStepBuilder[mockTxContext]().
+				Step(step2Name).
+				Invoke(endpoint).
+				Step(step3Name).
+				LocalInvoke(localEndpoint).
+				MustBuild()).
+			Build()
+		assert.ErrorIs(t, err, ErrBranchResponseChannelShared)
+
+		_, err = builder.
+			Step(step1Name).
+			Parallel(NewStepBuilder[mockTxContext]().
+				Step(step2Name).
+				Parallel(branches).
+				Step(step3Name).
+				SubSaga("ChildSaga", nil).
+				MustBuild()).
+			Build()
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []ValidationProblem{
+			{Step: step1Name, Branch: step2Name, Err: ErrUnsupportedBranchStep},
+			{Step: step1Name, Branch: step3Name, Err: ErrUnsupportedBranchStep},
+		}, validationErr.Problems)
+
+		def, err := builder.
+			Step(step1Name).
+			Parallel(branches).
+			Build()
+		assert.Nil(t, err)
+
+		assert.Equal(t, 1, len(def.steps))
+
+		assert.Equal(t, step1Name, def.steps[0].Name())
+		assert.True(t, def.steps[0].IsCompensable())
+		assert.True(t, def.steps[0].IsInvocable())
+		assert.False(t, def.steps[0].MustBeCompleted())
+
+		parallel := def.steps[0].(parallelStep[mockTxContext])
+		assert.Equal(t, 2, len(parallel.Branches()))
+		assert.Equal(t, step2Name, parallel.Branches()[0].Name())
+		assert.Equal(t, step3Name, parallel.Branches()[1].Name())
+	})
+
+	t.Run("Build with sub-saga steps", func(t *testing.T) {
+		def, err := builder.
+			Step(step1Name).
+			SubSaga("ChildSaga", nil).
+			WithSubSagaCompensation().
+			Step(step2Name).
+			SubSaga("ChildSaga", nil).
+			Build()
+		assert.Nil(t, err)
+
+		assert.Equal(t, 2, len(def.steps))
+
+		assert.Equal(t, step1Name, def.steps[0].Name())
+		assert.True(t, def.steps[0].IsCompensable())
+		assert.True(t, def.steps[0].IsInvocable())
+		assert.False(t, def.steps[0].MustBeCompleted())
+		assert.Equal(t, "ChildSaga", def.steps[0].(subSagaStep[mockTxContext]).SagaName())
+
+		assert.Equal(t, step2Name, def.steps[1].Name())
+		assert.False(t, def.steps[1].IsCompensable())
+	})
+
+	t.Run("Build with pivot step", func(t *testing.T) {
+		def, err := builder.
+			Step(step1Name).
+			Invoke(endpoint).
+			WithCompensation(endpoint).
+			Step(step2Name).
+			AsPivot().
+			Invoke(endpoint).
+			Step(step3Name).
+			LocalInvoke(localEndpoint).
+			Retry().
+			Build()
+		assert.Nil(t, err)
+
+		assert.Equal(t, step2Name, def.Pivot().Name())
+		assert.Nil(t, def.Validate())
+
+		_, err = builder.
+			Step(step1Name).
+			AsPivot().
+			Invoke(endpoint).
+			Step(step2Name).
+			Invoke(endpoint).
+			Build()
+		assert.ErrorIs(t, err, ErrStepAfterPivotNotRetriable)
+
+		_, err = builder.
+			Step(step1Name).
+			AsPivot().
+			Invoke(endpoint).
+			Retry().
+			Step(step2Name).
+			AsPivot().
+			Invoke(endpoint).
+			Retry().
+			Build()
+		assert.ErrorIs(t, err, ErrMultiplePivotSteps)
+
+		_, err = builder.
+			Step(step1Name).
+			AsPivot().
+			Invoke(endpoint).
+			Step(step2Name).
+			Parallel(NewStepBuilder[mockTxContext]().
+				Step("branch").
+				Invoke(endpoint).
+				Retry().
+				MustBuild()).
+			Step(step3Name).
+			SubSaga("ChildSaga", nil).
+			Build()
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []ValidationProblem{
+			{Step: step2Name, Err: ErrStepAfterPivotNotRetriable},
+			{Step: step3Name, Err: ErrStepAfterPivotNotRetriable},
+		}, validationErr.Problems)
+
+		def, err = builder.
+			Step(step1Name).
+			Invoke(endpoint).
+			Build()
+		assert.Nil(t, err)
+
+		assert.Nil(t, def.Pivot())
+		assert.Nil(t, def.Validate())
+	})
+
+	t.Run("Build with problems", func(t *testing.T) {
+		noChannelEndpoint := NewEndpoint[Session, mockMessage, mockMessage, mockMessage, mockTxContext](
+			"command",
+			messageConstructor,
+			repo,
+			"",
+			messageConstructor,
+			"",
+			messageConstructor,
+		)
+
+		_, err := builder.
+			Step("").
+			Invoke(endpoint).
+			Step(step1Name).
+			Invoke(noChannelEndpoint).
+			Step(step2Name).
+			Invoke(endpoint).
+			WithCompensation(noChannelEndpoint).
+			Step(step3Name).
+			Parallel(newDefinition([]Step{
+				newRemoteStep(step1Name, endpoint, stepOptions{}),
+				newRemoteStep(step1Name, endpoint, stepOptions{}),
+			})).
+			Step("step4").
+			SubSaga("", nil).
+			If("cond", func(session Session) bool { return true }).
+			Build()
+
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, []ValidationProblem{
+			{Err: ErrUnclosedIfBlock},
+			{Step: "", Err: ErrStepNameEmpty},
+			{Step: step1Name, Err: ErrInvocationWithoutResponseChannel},
+			{Step: step2Name, Err: ErrCompensationWithoutResponseChannel},
+			{Step: step3Name, Branch: step1Name, Err: ErrDuplicateStepName},
+			{Step: step3Name, Branch: step1Name, Err: ErrBranchResponseChannelShared},
+			{Step: "step4", Err: ErrSubSagaNameEmpty},
+		}, validationErr.Problems)
+		assert.ErrorIs(t, err, ErrDuplicateStepName)
+		assert.Contains(t, err.Error(), `step "step3", branch "step1": step name is duplicated`)
+
+		def := newDefinition([]Step{
+			newRemoteStep(step1Name, endpoint, stepOptions{}),
+			newRemoteStep(step1Name, endpoint, stepOptions{}),
+		})
+		err = RegisterSagaTo(
+			NewRegistry[mockTxContext](&mockOrchestrator[mockTxContext]{}),
+			NewSaga[Session, mockTxContext]("saga", def, nil, newMockSessionRepository[Session, mockTxContext]()),
+		)
+		assert.ErrorIs(t, err, ErrDuplicateStepName)
+	})
+
+	t.Run("Build with conditions", func(t *testing.T) {
+		isTrue := func(session Session) bool { return true }
+
+		def, err := builder.
+			If("cond", isTrue).
+			Step(step1Name).
+			Invoke(endpoint).
+			Else().
+			Step(step2Name).
+			When(isTrue).
+			Invoke(endpoint).
+			EndIf().
+			Step(step3Name).
+			Invoke(endpoint).
+			Build()
+		assert.Nil(t, err)
+
+		assert.Equal(t, 3, len(def.steps))
+
+		conditions := optionsOf(def.steps[0]).conditions
+		assert.Equal(t, 1, len(conditions))
+		assert.Equal(t, "cond", conditions[0].name)
+		assert.True(t, conditions[0].expected)
+
+		conditions = optionsOf(def.steps[1]).conditions
+		assert.Equal(t, 2, len(conditions))
+		assert.Equal(t, "cond", conditions[0].name)
+		assert.False(t, conditions[0].expected)
+		assert.Equal(t, step2Name, conditions[1].name)
+		assert.True(t, conditions[1].expected)
+
+		assert.Equal(t, 0, len(optionsOf(def.steps[2]).conditions))
+
+		assert.Panics(t, func() {
+			NewStepBuilder[mockTxContext]().
+				If("cond", isTrue).
+				Step(step1Name).
+				Invoke(endpoint).
+				Else().
+				Step(step2Name).
+				Invoke(endpoint).
+				Else()
+		})
+	})
 }
@@ -1,5 +1,7 @@
 package saga
 
+import "context"
+
 type ChannelName string
 
 type AbstractChannel[Tx TxContext] interface {
@@ -9,6 +11,9 @@ type AbstractChannel[Tx TxContext] interface {
 	// Send sends a AbstractMessage to the channel
 	Send(message Message) error
 
+	// SendContext sends a AbstractMessage to the channel, respecting the cancellation and deadline of ctx
+	SendContext(ctx context.Context, message Message) error
+
 	Repository() AbstractMessageRepository[Message, Tx]
 }
 
@@ -37,8 +42,12 @@ func (c *channel[Tx]) Name() ChannelName {
 }
 
 func (c *channel[Tx]) Send(message Message) error {
+	return c.SendContext(context.Background(), message)
+}
+
+func (c *channel[Tx]) SendContext(ctx context.Context, message Message) error {
 	packet := parseMessageToPacket[Tx](c, message)
-	return c.registry.consumeMessage(packet)
+	return c.registry.consumeMessage(ctx, packet)
 }
 
 func (c *channel[Tx]) Repository() AbstractMessageRepository[Message, Tx] {
@@ -0,0 +1,119 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"sync"
+)
+
+// VersionedSession is an optional interface that can be implemented by Session for optimistic concurrency control.
+// SessionRepository.Save of a versioned session must fail with ErrSessionVersionConflict if the stored session
+// has another version than Version(), and must store the session with the next version otherwise.
+// The orchestrator then reloads the session and handles the message again.
+type VersionedSession interface {
+	// Version returns the version of the session when it was loaded. A new session has version zero.
+	Version() int64
+
+	// SetVersion sets the version of the session. It is called by the repository when the session is saved.
+	SetVersion(version int64)
+}
+
+// OrchestratorOption configures the orchestrator created by NewOrchestrator.
+// It is generic over the transaction context of the orchestrator, so that an option which depends on it,
+// such as WithInbox, cannot be given to an orchestrator with another transaction context.
+type OrchestratorOption[Tx TxContext] func(*orchestratorOptions[Tx])
+
+type orchestratorOptions[Tx TxContext] struct {
+	conflictRetries int
+	sessionLock     bool
+	inbox           InboxRepository[Tx]
+	tracerProvider  trace.TracerProvider
+	propagator      propagation.TextMapPropagator
+	logger          *slog.Logger
+}
+
+func defaultOrchestratorOptions[Tx TxContext]() orchestratorOptions[Tx] {
+	return orchestratorOptions[Tx]{
+		conflictRetries: 3,
+		logger:          slog.Default(),
+	}
+}
+
+// WithConflictRetries sets how many times a message is handled again with the reloaded session,
+// when the session was saved by another orchestration after it was loaded. The default is 3.
+func WithConflictRetries[Tx TxContext](retries int) OrchestratorOption[Tx] {
+	return func(o *orchestratorOptions[Tx]) {
+		o.conflictRetries = retries
+	}
+}
+
+// WithSessionLock makes the orchestrator handle one message of a session at a time, by an in-process lock per session.
+// It avoids version conflicts in single-node deployments, but does not protect sessions shared by several processes.
+func WithSessionLock[Tx TxContext]() OrchestratorOption[Tx] {
+	return func(o *orchestratorOptions[Tx]) {
+		o.sessionLock = true
+	}
+}
+
+// withSession runs fn while holding the lock of the session if the session lock is enabled,
+// and runs fn again if the session was saved concurrently, as many times as the conflict retries allow.
+func (o *orchestrator[Tx]) withSession(ctx context.Context, sessionID string, fn func() error) error {
+	if o.locks != nil {
+		unlock := o.locks.lock(sessionID)
+		defer unlock()
+	}
+
+	for retries := 0; ; retries++ {
+		err := fn()
+		if !errors.Is(err, ErrSessionVersionConflict) || retries >= o.options.conflictRetries || ctx.Err() != nil {
+			return err
+		}
+
+		o.logger.DebugContext(ctx, "session was saved concurrently, retrying", LogKeySessionID, sessionID)
+	}
+}
+
+// sessionLocks holds a mutex for each session which is being orchestrated.
+type sessionLocks struct {
+	mutex sync.Mutex
+	locks map[string]*sessionLock
+}
+
+type sessionLock struct {
+	mutex   sync.Mutex
+	holders int
+}
+
+func newSessionLocks() *sessionLocks {
+	return &sessionLocks{
+		locks: make(map[string]*sessionLock),
+	}
+}
+
+// lock blocks until the lock of the session is acquired, and returns the function releasing it.
+func (l *sessionLocks) lock(sessionID string) func() {
+	l.mutex.Lock()
+	lock, ok := l.locks[sessionID]
+	if !ok {
+		lock = &sessionLock{}
+		l.locks[sessionID] = lock
+	}
+	lock.holders++
+	l.mutex.Unlock()
+
+	lock.mutex.Lock()
+
+	return func() {
+		lock.mutex.Unlock()
+
+		l.mutex.Lock()
+		lock.holders--
+		if lock.holders == 0 {
+			delete(l.locks, sessionID)
+		}
+		l.mutex.Unlock()
+	}
+}
@@ -0,0 +1,72 @@
+package saga
+
+// Condition decides whether a conditional step is executed, by the data of the session.
+type Condition func(session Session) bool
+
+// ConditionSession is an optional interface that must be implemented by Session to run conditional steps.
+// Each condition is evaluated once when the saga reaches it in forward direction, and the result is recorded,
+// so that the compensation only touches the steps which were actually executed.
+type ConditionSession interface {
+	// Condition returns the recorded result of the named condition, or false if it has not been evaluated.
+	Condition(name string) (result bool, evaluated bool)
+
+	// SetCondition records the result of the named condition.
+	SetCondition(name string, result bool)
+}
+
+// stepCondition guards a step. The step is executed only if the named condition evaluates to expected.
+type stepCondition struct {
+	name      string
+	condition Condition
+	expected  bool
+}
+
+// evaluateConditions evaluates the conditions of the step which have not been evaluated yet,
+// and returns true if the step must be executed.
+func evaluateConditions(session Session, step Step) (bool, error) {
+	conditions := optionsOf(step).conditions
+	if len(conditions) == 0 {
+		return true, nil
+	}
+
+	conditionSession, ok := session.(ConditionSession)
+	if !ok {
+		return false, ErrSessionNotConditional
+	}
+
+	for _, c := range conditions {
+		result, evaluated := conditionSession.Condition(c.name)
+		if !evaluated {
+			result = c.condition(session)
+			conditionSession.SetCondition(c.name, result)
+		}
+
+		if result != c.expected {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// wasExecuted returns true if the step was executed in forward direction, by the recorded results of its conditions.
+func wasExecuted(session Session, step Step) bool {
+	conditions := optionsOf(step).conditions
+	if len(conditions) == 0 {
+		return true
+	}
+
+	conditionSession, ok := session.(ConditionSession)
+	if !ok {
+		return false
+	}
+
+	for _, c := range conditions {
+		result, evaluated := conditionSession.Condition(c.name)
+		if !evaluated || result != c.expected {
+			return false
+		}
+	}
+
+	return true
+}
@@ -2,6 +2,9 @@ package saga
 
 type Definition struct {
 	steps []Step
+
+	// sagaName is the name of the saga the definition is given to.
+	sagaName string
 }
 
 func newDefinition(steps []Step) Definition {
@@ -10,6 +13,11 @@ func newDefinition(steps []Step) Definition {
 	}
 }
 
+// Steps returns the steps of the definition in order.
+func (d Definition) Steps() []Step {
+	return append([]Step(nil), d.steps...)
+}
+
 func (d Definition) FirstStep() Step {
 	if len(d.steps) == 0 {
 		return nil
@@ -61,3 +69,29 @@ func (d Definition) PrevStep(step Step) Step {
 
 	return nil
 }
+
+// Pivot returns the pivot step of the definition, or nil if it has none.
+func (d Definition) Pivot() Step {
+	for _, step := range d.steps {
+		if optionsOf(step).pivot {
+			return step
+		}
+	}
+
+	return nil
+}
+
+// isAfterPivot returns true if the pivot step of the definition is before step and was executed by the session.
+func (d Definition) isAfterPivot(session Session, step Step) bool {
+	for _, s := range d.steps {
+		if s.Name() == step.Name() {
+			return false
+		}
+
+		if optionsOf(s).pivot {
+			return wasExecuted(session, s)
+		}
+	}
+
+	return false
+}
@@ -3,16 +3,42 @@ package saga
 import "errors"
 
 var (
-	ErrChannelAlreadyRegistered         = errors.New("channel already registered")
-	ErrUnitOfWorkImmutable              = errors.New("unit of work is immutable because it has already been committed")
-	ErrSessionCreationFailed            = errors.New("session is nil when creating a new session")
-	ErrSessionIDEmpty                   = errors.New("session ID is empty")
-	ErrSagaHasNoSteps                   = errors.New("saga has no steps")
-	ErrUnknownMessageOrigin             = errors.New("message consumed but origin channel is unknown")
-	ErrDeadSession                      = errors.New("session is already completed or failed")
-	ErrSessionStepAndDefinitionMismatch = errors.New("session step and definition mismatch")
-	ErrRetryCalledOnNonRetryingStep     = errors.New("retry called but step must be completed false")
-	ErrRegisterInvalidSaga              = errors.New("saga is invalid, but tried to register")
-	ErrSagaNotFound                     = errors.New("saga not found")
-	ErrInvalidSagaStart                 = errors.New("start saga called with invalid parameters")
+	ErrChannelAlreadyRegistered           = errors.New("channel already registered")
+	ErrChannelNotFound                    = errors.New("channel not found")
+	ErrUnitOfWorkImmutable                = errors.New("unit of work is immutable because it has already been committed")
+	ErrSessionCreationFailed              = errors.New("session is nil when creating a new session")
+	ErrSessionIDEmpty                     = errors.New("session ID is empty")
+	ErrSagaHasNoSteps                     = errors.New("saga has no steps")
+	ErrUnknownMessageOrigin               = errors.New("message consumed but origin channel is unknown")
+	ErrDeadSession                        = errors.New("session is already completed or failed")
+	ErrSessionStepAndDefinitionMismatch   = errors.New("session step and definition mismatch")
+	ErrRetryCalledOnNonRetryingStep       = errors.New("retry called but step must be completed false")
+	ErrRegisterInvalidSaga                = errors.New("saga is invalid, but tried to register")
+	ErrSagaNotFound                       = errors.New("saga not found")
+	ErrInvalidSagaStart                   = errors.New("start saga called with invalid parameters")
+	ErrStepTimeout                        = errors.New("step timed out before a response arrived")
+	ErrSessionNeedsIntervention           = errors.New("session ran out of retries and needs manual intervention")
+	ErrSessionNotParallel                 = errors.New("session must implement ParallelSession to run parallel steps")
+	ErrSessionNotConditional              = errors.New("session must implement ConditionSession to run conditional steps")
+	ErrSessionNotSubSaga                  = errors.New("session must implement SubSagaSession to run sub-saga steps")
+	ErrSubSagaFailed                      = errors.New("session of the sub-saga failed")
+	ErrStepAfterPivotNotRetriable         = errors.New("step after the pivot step must be retriable")
+	ErrMultiplePivotSteps                 = errors.New("saga can have only one pivot step")
+	ErrStepNameEmpty                      = errors.New("step name is empty")
+	ErrDuplicateStepName                  = errors.New("step name is duplicated")
+	ErrInvocationWithoutResponseChannel   = errors.New("invocation endpoint has no success or failure response channel")
+	ErrCompensationWithoutResponseChannel = errors.New("compensation endpoint has no success or failure response channel")
+	ErrSubSagaNameEmpty                   = errors.New("saga name of the sub-saga step is empty")
+	ErrBranchResponseChannelShared        = errors.New("response channel is shared with another branch of the parallel step")
+	ErrUnsupportedBranchStep              = errors.New("branch of a parallel step must be a remote or local step")
+	ErrUnclosedIfBlock                    = errors.New("if block is not closed by EndIf")
+	ErrSessionVersionConflict             = errors.New("session was saved by another orchestration after it was loaded")
+	ErrDuplicateMessage                   = errors.New("message has already been received")
+	ErrStaleResponse                      = errors.New("response does not answer the current step or attempt of the session")
+	ErrSessionPaused                      = errors.New("session is paused until an operator resumes it")
+	ErrSessionNotPausable                 = errors.New("session must implement PausableSession to be paused")
+	ErrSessionAfterPivot                  = errors.New("session cannot be canceled after its pivot step was executed")
+	ErrUnknownIntervention                = errors.New("intervention is unknown")
+	ErrSessionNotFound                    = errors.New("session not found")
+	ErrSessionQueryNotSupported           = errors.New("session repository must implement SessionQueryRepository to list sessions")
 )
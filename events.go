@@ -0,0 +1,257 @@
+package saga
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+type EventType int
+
+const (
+	EventSagaStarted EventType = iota
+	EventStepInvoked
+	EventStepSucceeded
+	// EventStepFailed means the step responded with a failure or did not respond in time.
+	EventStepFailed
+	// EventStepSkipped means the step was passed over because its conditions were not met.
+	EventStepSkipped
+	// EventStepRetried means the invocation or compensation of the step is tried again.
+	EventStepRetried
+	EventCompensationStarted
+	EventCompensationFinished
+	EventCompensationFailed
+	EventSagaCompleted
+	EventSagaFailed
+	// EventSagaStateChanged means the state of the session changed from Event.PreviousState to Event.State.
+	EventSagaStateChanged
+	// EventSagaNeedsIntervention means the session stopped until an operator acts on it.
+	EventSagaNeedsIntervention
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventSagaStarted:
+		return "SagaStarted"
+	case EventStepInvoked:
+		return "StepInvoked"
+	case EventStepSucceeded:
+		return "StepSucceeded"
+	case EventStepFailed:
+		return "StepFailed"
+	case EventStepSkipped:
+		return "StepSkipped"
+	case EventStepRetried:
+		return "StepRetried"
+	case EventCompensationStarted:
+		return "CompensationStarted"
+	case EventCompensationFinished:
+		return "CompensationFinished"
+	case EventCompensationFailed:
+		return "CompensationFailed"
+	case EventSagaCompleted:
+		return "SagaCompleted"
+	case EventSagaFailed:
+		return "SagaFailed"
+	case EventSagaStateChanged:
+		return "SagaStateChanged"
+	case EventSagaNeedsIntervention:
+		return "SagaNeedsIntervention"
+	}
+
+	return "EventType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Event is a record of what the orchestrator did to a session, delivered to the listeners of the registry.
+type Event struct {
+	Type      EventType
+	SagaName  string
+	SessionID string
+
+	// Step is the name of the step, or empty if the event is about the whole session.
+	Step string
+
+	// Branch is the name of the branch if the step is a parallel step and the event is about one of its branches.
+	Branch string
+
+	// Attempt is the attempt of the step when the event occurred, or zero if it is unknown.
+	Attempt   int
+	Timestamp time.Time
+
+	// State is the state of the session when the event occurred.
+	State State
+
+	// PreviousState is the state the session left if the event is EventSagaStateChanged, and State otherwise.
+	PreviousState State
+
+	// Elapsed is how long the invocation or compensation took for the events which end one,
+	// and how long the session ran for the events which end the session.
+	// It is zero if the session does not keep a history.
+	Elapsed time.Duration
+}
+
+// Listener receives the events within the unit of work which causes them.
+// The returned executable is committed with the session, so an error of it fails the unit of work.
+type Listener[Tx TxContext] interface {
+	OnEvent(event Event) Executable[Tx]
+}
+
+// ListenerFunc is an adapter to use a function as Listener.
+type ListenerFunc[Tx TxContext] func(event Event) Executable[Tx]
+
+func (f ListenerFunc[Tx]) OnEvent(event Event) Executable[Tx] {
+	return f(event)
+}
+
+// AsyncListener receives the events after the unit of work which caused them is committed.
+// The events of a unit of work are delivered in order, in a goroutine other than the one of the orchestrator,
+// but the events of different units of work can be delivered concurrently.
+// Nothing is delivered if the unit of work fails.
+type AsyncListener interface {
+	OnEvent(ctx context.Context, event Event)
+}
+
+// AsyncListenerFunc is an adapter to use a function as AsyncListener.
+type AsyncListenerFunc func(ctx context.Context, event Event)
+
+func (f AsyncListenerFunc) OnEvent(ctx context.Context, event Event) {
+	f(ctx, event)
+}
+
+// emit delivers the event of the session to the listeners of the registry the orchestrator is used by,
+// and logs it at debug level. step can be nil if the event is about the whole session.
+func (o *orchestrator[Tx]) emit(session Session, eventType EventType, step Step, branch string, def Definition, uow *UnitOfWork[Tx]) {
+	o.emitFrom(session, session.State(), eventType, step, branch, def, uow)
+}
+
+// setState changes the state of the session, and emits EventSagaStateChanged if it differs from the current one.
+// EventSagaNeedsIntervention follows if the session stopped until an operator acts on it.
+func (o *orchestrator[Tx]) setState(session Session, state State, def Definition, uow *UnitOfWork[Tx]) {
+	previous := session.State()
+	session.SetState(state)
+	if previous == state {
+		return
+	}
+
+	o.emitFrom(session, previous, EventSagaStateChanged, nil, "", def, uow)
+	if state == StateNeedsIntervention {
+		o.emit(session, EventSagaNeedsIntervention, nil, "", def, uow)
+	}
+}
+
+// emitFrom is like emit but reports previous as the state the session left.
+func (o *orchestrator[Tx]) emitFrom(session Session, previous State, eventType EventType, step Step, branch string, def Definition, uow *UnitOfWork[Tx]) {
+	stepName := ""
+	if step != nil {
+		stepName = step.Name()
+	}
+
+	o.logger.DebugContext(uow.Context(), "saga event",
+		LogKeyEvent, eventType.String(),
+		LogKeySaga, def.sagaName,
+		LogKeySessionID, session.ID(),
+		LogKeyStep, stepName,
+		LogKeyBranch, branch,
+		LogKeyAttempt, o.attempt(session),
+	)
+
+	if o.registry == nil {
+		return
+	}
+
+	listeners, asyncListeners := o.registry.listenersOf()
+	if len(listeners) == 0 && len(asyncListeners) == 0 {
+		return
+	}
+
+	event := Event{
+		Type:      eventType,
+		SagaName:  def.sagaName,
+		SessionID: session.ID(),
+		Step:      stepName,
+		Branch:    branch,
+		Attempt:   o.attempt(session),
+		Timestamp: time.Now(),
+
+		State:         session.State(),
+		PreviousState: previous,
+	}
+
+	if historySession, ok := session.(HistorySession); ok {
+		if since, ok := elapsedSince(historySession.History(), event); ok {
+			event.Elapsed = event.Timestamp.Sub(since)
+		}
+	}
+
+	for _, listener := range listeners {
+		if executable := listener.OnEvent(event); executable != nil {
+			uow.deferWorkUnit(executable)
+		}
+	}
+
+	if len(asyncListeners) > 0 {
+		uow.afterCommit(func(ctx context.Context) {
+			for _, listener := range asyncListeners {
+				listener.OnEvent(ctx, event)
+			}
+		})
+	}
+}
+
+// eventOf returns the type of the event about the entry of the history, or false if no event is delivered for it.
+func eventOf(direction Direction, outcome Outcome) (EventType, bool) {
+	if direction == DirectionForward {
+		switch outcome {
+		case OutcomeInvoked:
+			return EventStepInvoked, true
+		case OutcomeSucceeded:
+			return EventStepSucceeded, true
+		case OutcomeFailed, OutcomeTimedOut:
+			return EventStepFailed, true
+		case OutcomeSkipped, OutcomeForceSkipped:
+			return EventStepSkipped, true
+		}
+
+		return 0, false
+	}
+
+	switch outcome {
+	case OutcomeInvoked:
+		return EventCompensationStarted, true
+	case OutcomeSucceeded:
+		return EventCompensationFinished, true
+	case OutcomeFailed, OutcomeTimedOut:
+		return EventCompensationFailed, true
+	}
+
+	return 0, false
+}
+
+// elapsedSince returns when the invocation or compensation ended by the event was started,
+// or when the session ended by the event was started, by the history of the session.
+func elapsedSince(history []HistoryEntry, event Event) (time.Time, bool) {
+	var direction Direction
+	switch event.Type {
+	case EventSagaCompleted, EventSagaFailed:
+		if len(history) == 0 {
+			return time.Time{}, false
+		}
+
+		return history[0].Timestamp, true
+	case EventStepSucceeded, EventStepFailed:
+		direction = DirectionForward
+	case EventCompensationFinished, EventCompensationFailed:
+		direction = DirectionBackward
+	default:
+		return time.Time{}, false
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		entry := history[i]
+		if entry.Step == event.Step && entry.Branch == event.Branch && entry.Direction == direction && entry.Outcome == OutcomeInvoked {
+			return entry.Timestamp, true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -10,10 +10,15 @@ const (
 	ExampleSuccessChannelName = "ExampleSuccessChannel"
 	ExampleFailureChannelName = "ExampleFailureChannel"
 	ExampleCommandChannelName = "ExampleCommandChannel"
+
+	ExampleSecondSuccessChannelName = "ExampleSecondSuccessChannel"
+	ExampleSecondFailureChannelName = "ExampleSecondFailureChannel"
 )
 
 var ExampleSuccessChannel = saga.NewChannel[ExampleMessage, ExampleTxContext](ExampleSuccessChannelName, registry, exampleSuccessResponseRepository)
 var ExampleFailureChannel = saga.NewChannel[ExampleMessage, ExampleTxContext](ExampleFailureChannelName, registry, exampleFailureResponseRepository) // repo ?
+var ExampleSecondSuccessChannel = saga.NewChannel[ExampleMessage, ExampleTxContext](ExampleSecondSuccessChannelName, registry, exampleSecondSuccessResponseRepository)
+var ExampleSecondFailureChannel = saga.NewChannel[ExampleMessage, ExampleTxContext](ExampleSecondFailureChannelName, registry, exampleSecondFailureResponseRepository)
 var ExampleCommandChannel = messageRelayer.NewChannel[ExampleMessage, ExampleTxContext](
 	ExampleCommandChannelName,
 	registry,
@@ -37,6 +42,8 @@ func init() {
 	var err error
 	err = channelRegistry.Register(ExampleSuccessChannel)
 	err = channelRegistry.Register(ExampleFailureChannel)
+	err = channelRegistry.Register(ExampleSecondSuccessChannel)
+	err = channelRegistry.Register(ExampleSecondFailureChannel)
 	err = channelRegistry.Register(ExampleCommandChannel)
 	err = channelRegistry.Register(AlwaysFailCommandChannel)
 
@@ -37,6 +37,24 @@ var ExampleLocalEndpoint = saga.NewLocalEndpoint[
 	},
 )
 
+var ExampleSecondLocalEndpoint = saga.NewLocalEndpoint[
+	*ExampleSession,
+	ExampleMessage, ExampleMessage,
+	ExampleTxContext,
+](
+	ExampleSecondSuccessChannelName,
+	ExampleMessageConstructor,
+	exampleSecondSuccessResponseRepository,
+	ExampleSecondFailureChannelName,
+	ExampleMessageConstructor,
+	exampleSecondFailureResponseRepository,
+	func(session saga.Session) (saga.Executable[ExampleTxContext], error) {
+		return func(ctx ExampleTxContext) error {
+			return nil
+		}, nil
+	},
+)
+
 var ExampleAlwaysFailingLocalEndpoint = saga.NewLocalEndpoint[
 	*ExampleSession,
 	ExampleMessage, ExampleMessage,
@@ -54,3 +72,21 @@ var ExampleAlwaysFailingLocalEndpoint = saga.NewLocalEndpoint[
 		}, errors.New("failed because always failing endpoint called")
 	},
 )
+
+var ExampleSecondAlwaysFailingLocalEndpoint = saga.NewLocalEndpoint[
+	*ExampleSession,
+	ExampleMessage, ExampleMessage,
+	ExampleTxContext,
+](
+	ExampleSecondSuccessChannelName,
+	ExampleMessageConstructor,
+	exampleSecondSuccessResponseRepository,
+	ExampleSecondFailureChannelName,
+	ExampleMessageConstructor,
+	exampleSecondFailureResponseRepository,
+	func(session saga.Session) (saga.Executable[ExampleTxContext], error) {
+		return func(ctx ExampleTxContext) error {
+			return nil
+		}, errors.New("failed because always failing endpoint called")
+	},
+)
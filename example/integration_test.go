@@ -1,12 +1,34 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/violetpay-org/go-saga"
+	"github.com/violetpay-org/go-saga/admin"
+	"github.com/violetpay-org/go-saga/inmemory"
 	"github.com/violetpay-org/go-saga/messageRelayer"
+	"github.com/violetpay-org/go-saga/prometheusMetrics"
+	"github.com/violetpay-org/go-saga/sqlStore"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"log/slog"
+	_ "modernc.org/sqlite"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestOrchestrator(t *testing.T) {
@@ -20,11 +42,16 @@ func TestOrchestrator(t *testing.T) {
 		registry = saga.NewRegistry(orchestrator)
 		successRepository.clear()
 		failureRepository.clear()
+		exampleSecondSuccessResponseRepository.clear()
+		exampleSecondFailureResponseRepository.clear()
+		exampleInboxRepository.clear()
 		commandRepository.clear()
 		sessionRepository.clear()
 
 		ExampleSuccessChannel = saga.NewChannel[ExampleMessage, ExampleTxContext](ExampleSuccessChannelName, registry, exampleSuccessResponseRepository)
 		ExampleFailureChannel = saga.NewChannel[ExampleMessage, ExampleTxContext](ExampleFailureChannelName, registry, exampleFailureResponseRepository) // repo ?
+		ExampleSecondSuccessChannel = saga.NewChannel[ExampleMessage, ExampleTxContext](ExampleSecondSuccessChannelName, registry, exampleSecondSuccessResponseRepository)
+		ExampleSecondFailureChannel = saga.NewChannel[ExampleMessage, ExampleTxContext](ExampleSecondFailureChannelName, registry, exampleSecondFailureResponseRepository)
 		ExampleCommandChannel = messageRelayer.NewChannel[ExampleMessage, ExampleTxContext](
 			ExampleCommandChannelName,
 			registry,
@@ -45,6 +72,8 @@ func TestOrchestrator(t *testing.T) {
 		channelRegistry = messageRelayer.NewChannelRegistry[ExampleTxContext]()
 		err := channelRegistry.Register(ExampleSuccessChannel)
 		err = channelRegistry.Register(ExampleFailureChannel)
+		err = channelRegistry.Register(ExampleSecondSuccessChannel)
+		err = channelRegistry.Register(ExampleSecondFailureChannel)
 		err = channelRegistry.Register(ExampleCommandChannel)
 		err = channelRegistry.Register(AlwaysFailCommandChannel)
 		assert.Nil(t, err)
@@ -54,7 +83,11 @@ func TestOrchestrator(t *testing.T) {
 
 	builder := saga.NewStepBuilder[ExampleTxContext]()
 
-	buildSagaAndRegister := func(def saga.Definition) {
+	buildSagaAndRegister := func(def saga.Definition, err error) {
+		if err != nil {
+			panic(err)
+		}
+
 		exampleSaga = saga.NewSaga[*ExampleSession, ExampleTxContext](
 			"ExampleSaga",
 			def,
@@ -62,7 +95,7 @@ func TestOrchestrator(t *testing.T) {
 			exampleSessionRepository,
 		)
 
-		err := saga.RegisterSagaTo(registry, exampleSaga)
+		err = saga.RegisterSagaTo(registry, exampleSaga)
 		if err != nil {
 			panic(err)
 		}
@@ -462,4 +495,2434 @@ func TestOrchestrator(t *testing.T) {
 		assert.Equal(t, saga.StateFailed, sessions[0].State())
 		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
 	})
+
+	t.Run("should not start saga when context is canceled", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := registry.StartSagaContext(ctx, exampleSaga.Name(), map[string]interface{}{})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(sessions))
+
+		outbox, err := exampleSuccessResponseRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(outbox))
+	})
+
+	t.Run("should not consume response and relay messages when context is canceled", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSagaContext(context.Background(), exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory).(*messageRelayer.Relayer[ExampleTxContext])
+		err = relayer.ExecuteContext(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(sessions))
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+
+		err = ExampleSuccessChannel.SendContext(ctx, ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(
+				uuid.New().String(),
+				sessions[0].ID(),
+				"Triggered by test",
+			),
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+
+		// Relaying with a live context still advances the saga
+		err = relayer.ExecuteContext(context.Background())
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+	})
+
+	t.Run("should compensate when a step times out", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				WithTimeout(10 * time.Millisecond).
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		// Consume first step, second step is invoked but no response arrives
+		err = messageRelayer.New(1, channelRegistry, UnitOfWorkFactory).Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+		assert.False(t, sessions[0].Deadline().IsZero())
+
+		time.Sleep(20 * time.Millisecond)
+
+		err = saga.NewTimeoutSweeper(registry, 10).Execute()
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+		assert.True(t, sessions[0].Deadline().IsZero())
+	})
+
+	t.Run("should retry when a retrying step times out", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				WithTimeout(10 * time.Millisecond).
+				Invoke(ExampleEndpoint).
+				Retry().
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		time.Sleep(20 * time.Millisecond)
+
+		err = saga.NewTimeoutSweeper(registry, 10).Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsRetrying, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+		assert.True(t, sessions[0].Deadline().After(time.Now()))
+
+		outbox, err := exampleCommandRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(outbox))
+	})
+
+	t.Run("should not touch sessions before their deadline", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				WithTimeout(time.Hour).
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		err = saga.NewTimeoutSweeper(registry, 10).Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateCommon, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+	})
+
+	t.Run("should need intervention when retries of a step run out", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				RetryWith(saga.RetryPolicy{MaxAttempts: 2}).
+				Build(),
+		)
+
+		var interventions []saga.Event
+		registry.AddListener(saga.ListenerFunc[ExampleTxContext](func(event saga.Event) saga.Executable[ExampleTxContext] {
+			if event.Type == saga.EventSagaNeedsIntervention {
+				interventions = append(interventions, event)
+			}
+			return nil
+		}))
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+
+		// Consume first failure, retry
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsRetrying, sessions[0].State())
+		assert.Equal(t, 2, sessions[0].Attempt())
+
+		// Consume second failure, no more attempts
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.False(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateNeedsIntervention, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+		assert.Len(t, interventions, 1)
+		assert.Equal(t, saga.StateNeedsIntervention, interventions[0].State)
+
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(
+				uuid.New().String(),
+				sessions[0].ID(),
+				"Triggered by test",
+			),
+		})
+		assert.ErrorIs(t, err, saga.ErrSessionNeedsIntervention)
+	})
+
+	t.Run("should compensate when a failure is not retriable", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				RetryWith(saga.RetryPolicy{
+					RetryOn: func(cause error) bool {
+						return !saga.IsFailureResponse(cause, nil)
+					},
+				}).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+	})
+
+	t.Run("should delay retry by backoff", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				RetryWith(saga.RetryPolicy{Backoff: saga.FixedBackoff(10 * time.Millisecond)}).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		err = messageRelayer.New(1, channelRegistry, UnitOfWorkFactory).Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.False(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsRetrying, sessions[0].State())
+		assert.False(t, sessions[0].Deadline().IsZero())
+
+		outbox, err := exampleFailureResponseRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(outbox))
+
+		time.Sleep(20 * time.Millisecond)
+
+		err = saga.NewTimeoutSweeper(registry, 10).Execute()
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsRetrying, sessions[0].State())
+		assert.Equal(t, 2, sessions[0].Attempt())
+
+		outbox, err = exampleFailureResponseRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(outbox))
+	})
+
+	t.Run("should need intervention when retries of a compensation run out", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				RetryCompensationWith(saga.RetryPolicy{MaxAttempts: 1}).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+
+		err = ExampleFailureChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(
+				uuid.New().String(),
+				sessions[0].ID(),
+				"Triggered by test",
+			),
+		})
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.False(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateNeedsIntervention, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+	})
+
+	t.Run("should step forward when all parallel branches succeed", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				Parallel(
+					saga.NewStepBuilder[ExampleTxContext]().
+						Step("ExampleBranch1").
+						LocalInvoke(ExampleLocalEndpoint).
+						Step("ExampleBranch2").
+						LocalInvoke(ExampleSecondLocalEndpoint).
+						MustBuild(),
+				).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		outbox, err := exampleSuccessResponseRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(outbox))
+		outbox, err = exampleSecondSuccessResponseRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(outbox))
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+
+		// Consume first branch, wait for the other
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+
+		// Consume second branch
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateCommon, sessions[0].State())
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+
+		state, ok := sessions[0].BranchState("ExampleStep1", "ExampleBranch1")
+		assert.True(t, ok)
+		assert.Equal(t, saga.BranchStateSucceeded, state)
+	})
+
+	t.Run("should compensate only succeeded branches when a parallel branch fails", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				Parallel(
+					saga.NewStepBuilder[ExampleTxContext]().
+						Step("ExampleBranch1").
+						LocalInvoke(ExampleLocalEndpoint).
+						WithLocalCompensation(ExampleLocalEndpoint).
+						Step("ExampleBranch2").
+						LocalInvoke(ExampleSecondAlwaysFailingLocalEndpoint).
+						WithLocalCompensation(ExampleSecondLocalEndpoint).
+						MustBuild(),
+				).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+
+		// Consume first step, and both branches
+		for i := 0; i < 3; i++ {
+			err = relayer.Execute()
+			assert.Nil(t, err)
+		}
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+
+		state, _ := sessions[0].BranchState("ExampleStep2", "ExampleBranch1")
+		assert.Equal(t, saga.BranchStateCompensating, state)
+		state, _ = sessions[0].BranchState("ExampleStep2", "ExampleBranch2")
+		assert.Equal(t, saga.BranchStateFailed, state)
+
+		// Consume compensation of first branch, then compensate first step
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(
+				uuid.New().String(),
+				sessions[0].ID(),
+				"Triggered by test",
+			),
+		})
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+
+		state, _ = sessions[0].BranchState("ExampleStep2", "ExampleBranch1")
+		assert.Equal(t, saga.BranchStateCompensated, state)
+	})
+
+	t.Run("should retry the compensation of a parallel branch until its retries run out", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				Parallel(
+					saga.NewStepBuilder[ExampleTxContext]().
+						Step("ExampleBranch1").
+						LocalInvoke(ExampleLocalEndpoint).
+						WithLocalCompensation(ExampleLocalEndpoint).
+						RetryCompensationWith(saga.RetryPolicy{MaxAttempts: 2}).
+						Step("ExampleBranch2").
+						LocalInvoke(ExampleSecondAlwaysFailingLocalEndpoint).
+						MustBuild(),
+				).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		send := func(channel saga.Channel[ExampleTxContext]) {
+			err := channel.Send(ExampleMessage{
+				AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+			})
+			assert.Nil(t, err)
+		}
+
+		// The first branch succeeds and the second one fails, so the first branch is compensated
+		send(ExampleSuccessChannel)
+		send(ExampleSecondFailureChannel)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, 1, sessions[0].Attempt())
+
+		send(ExampleFailureChannel)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, 2, sessions[0].Attempt())
+
+		state, _ := sessions[0].BranchState("ExampleStep1", "ExampleBranch1")
+		assert.Equal(t, saga.BranchStateCompensating, state)
+
+		send(ExampleFailureChannel)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.False(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateNeedsIntervention, sessions[0].State())
+		assert.Equal(t, 2, sessions[0].Attempt())
+	})
+
+	t.Run("should compensate the parallel branches which timed out", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				WithTimeout(10 * time.Millisecond).
+				Parallel(
+					saga.NewStepBuilder[ExampleTxContext]().
+						Step("ExampleBranch1").
+						Invoke(ExampleEndpoint).
+						WithCompensation(ExampleEndpoint).
+						Step("ExampleBranch2").
+						LocalInvoke(ExampleSecondLocalEndpoint).
+						MustBuild(),
+				).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		// The second branch responds, but the command of the first branch is never answered
+		err = ExampleSecondSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		time.Sleep(20 * time.Millisecond)
+
+		err = saga.NewTimeoutSweeper(registry, 10).Execute()
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+
+		state, _ := sessions[0].BranchState("ExampleStep1", "ExampleBranch1")
+		assert.Equal(t, saga.BranchStateCompensating, state)
+
+		// The command of the first branch and its compensation
+		outbox, err := exampleCommandRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(outbox))
+	})
+
+	t.Run("should skip a step when its condition is false", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				When(func(session saga.Session) bool {
+					return session.(*ExampleSession).exampleField != "test"
+				}).
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+
+		err = messageRelayer.New(1, channelRegistry, UnitOfWorkFactory).Execute()
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateCompleted, sessions[0].State())
+	})
+
+	t.Run("should compensate only the steps of the branch taken", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				If("IsTest", func(session saga.Session) bool {
+					return session.(*ExampleSession).exampleField == "test"
+				}).
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Else().
+				Step("ExampleStep2").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				EndIf().
+				Step("ExampleStep3").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+
+		// Consume first step, second step is skipped
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleStep3", sessions[0].CurrentStep().Name())
+
+		// Consume third step but failed, compensate first step
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+	})
+
+	t.Run("should record history and compensate the steps it records as succeeded", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		// Consume first step, then second step but failed
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		// Consume first compensation step
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(
+				uuid.New().String(),
+				sessions[0].ID(),
+				"Triggered by test",
+			),
+		})
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateFailed, sessions[0].State())
+
+		type record struct {
+			step      string
+			direction saga.Direction
+			outcome   saga.Outcome
+		}
+
+		var records []record
+		for _, entry := range sessions[0].History() {
+			assert.False(t, entry.Timestamp.IsZero())
+			records = append(records, record{entry.Step, entry.Direction, entry.Outcome})
+		}
+
+		assert.Equal(t, []record{
+			{"ExampleStep1", saga.DirectionForward, saga.OutcomeInvoked},
+			{"ExampleStep1", saga.DirectionForward, saga.OutcomeSucceeded},
+			{"ExampleStep2", saga.DirectionForward, saga.OutcomeInvoked},
+			{"ExampleStep2", saga.DirectionForward, saga.OutcomeFailed},
+			{"ExampleStep1", saga.DirectionBackward, saga.OutcomeInvoked},
+			{"ExampleStep1", saga.DirectionBackward, saga.OutcomeSucceeded},
+		}, records)
+
+		history := sessions[0].History()
+		assert.NotEmpty(t, history[1].MessageID)
+		assert.Empty(t, history[0].MessageID)
+	})
+
+	buildChildSagaAndRegister := func(def saga.Definition, err error) {
+		if err != nil {
+			panic(err)
+		}
+
+		childSaga := saga.NewSaga[*ExampleSession, ExampleTxContext](
+			"ExampleChildSaga",
+			def,
+			exampleSessionFactory,
+			exampleSessionRepository,
+		)
+
+		err = saga.RegisterSagaTo(registry, childSaga)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	findSessions := func(t *testing.T) (parent *ExampleSession, child *ExampleSession) {
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(sessions))
+
+		for _, sess := range sessions {
+			if _, parentID := sess.ParentSession(); parentID == "" {
+				parent = sess
+			} else {
+				child = sess
+			}
+		}
+
+		return parent, child
+	}
+
+	t.Run("should resume parent saga when sub-saga is completed", func(t *testing.T) {
+		CleanUp(t)
+
+		buildChildSagaAndRegister(
+			builder.
+				Step("ExampleChildStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				SubSaga("ExampleChildSaga", nil).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		parent, child := findSessions(t)
+		assert.True(t, parent.IsPending())
+		assert.Equal(t, "ExampleStep1", parent.CurrentStep().Name())
+		parentSagaName, parentID := child.ParentSession()
+		assert.Equal(t, exampleSaga.Name(), parentSagaName)
+		assert.Equal(t, parent.ID(), parentID)
+		childID, ok := parent.ChildSessionID("ExampleStep1")
+		assert.True(t, ok)
+		assert.Equal(t, child.ID(), childID)
+		assert.Equal(t, "ExampleChildStep1", child.CurrentStep().Name())
+
+		// Consume child step, parent steps forward
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		parent, child = findSessions(t)
+		assert.Equal(t, saga.StateCompleted, child.State())
+		assert.True(t, parent.IsPending())
+		assert.Equal(t, "ExampleStep2", parent.CurrentStep().Name())
+
+		// Consume second step of parent
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		parent, _ = findSessions(t)
+		assert.Equal(t, saga.StateCompleted, parent.State())
+	})
+
+	t.Run("should compensate parent saga when sub-saga is failed", func(t *testing.T) {
+		CleanUp(t)
+
+		buildChildSagaAndRegister(
+			builder.
+				Step("ExampleChildStep1").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				Build(),
+		)
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				SubSaga("ExampleChildSaga", nil).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		// Consume first step, then the failed child step
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		parent, child := findSessions(t)
+		assert.Equal(t, saga.StateFailed, child.State())
+		assert.True(t, parent.IsPending())
+		assert.Equal(t, saga.StateIsCompensating, parent.State())
+		assert.Equal(t, "ExampleStep1", parent.CurrentStep().Name())
+	})
+
+	t.Run("should compensate completed sub-saga when parent saga fails", func(t *testing.T) {
+		CleanUp(t)
+
+		buildChildSagaAndRegister(
+			builder.
+				Step("ExampleChildStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Build(),
+		)
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				SubSaga("ExampleChildSaga", func(parent saga.Session) map[string]interface{} {
+					return map[string]interface{}{"id": "ExampleChildSaga-" + parent.ID()}
+				}).
+				WithSubSagaCompensation().
+				Step("ExampleStep2").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		parent, child := findSessions(t)
+		assert.Equal(t, "ExampleChildSaga-"+parent.ID(), child.ID())
+
+		// Consume child step, then the failed second step of parent
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		parent, child = findSessions(t)
+		assert.True(t, parent.IsPending())
+		assert.Equal(t, saga.StateIsCompensating, parent.State())
+		assert.Equal(t, "ExampleStep1", parent.CurrentStep().Name())
+		assert.True(t, child.IsPending())
+		assert.Equal(t, saga.StateIsCompensating, child.State())
+		assert.Equal(t, "ExampleChildStep1", child.CurrentStep().Name())
+
+		// Consume compensation of child step, parent fails with the child
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(
+				uuid.New().String(),
+				child.ID(),
+				"Triggered by test",
+			),
+		})
+		assert.Nil(t, err)
+
+		parent, child = findSessions(t)
+		assert.Equal(t, saga.StateFailed, child.State())
+		assert.False(t, parent.IsPending())
+		assert.Equal(t, saga.StateFailed, parent.State())
+	})
+
+	t.Run("should cancel a running sub-saga when the parent is canceled", func(t *testing.T) {
+		CleanUp(t)
+
+		buildChildSagaAndRegister(
+			builder.
+				Step("ExampleChildStep1").
+				Invoke(ExampleEndpoint).
+				WithCompensation(ExampleEndpoint).
+				Build(),
+		)
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				SubSaga("ExampleChildSaga", nil).
+				WithSubSagaCompensation().
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		parent, child := findSessions(t)
+		assert.True(t, child.IsPending())
+		assert.Equal(t, saga.StateCommon, child.State())
+
+		err = registry.CancelSession(context.Background(), exampleSaga.Name(), parent.ID())
+		assert.Nil(t, err)
+
+		parent, child = findSessions(t)
+		assert.True(t, parent.IsPending())
+		assert.Equal(t, saga.StateIsCompensating, parent.State())
+		assert.Equal(t, "ExampleStep1", parent.CurrentStep().Name())
+		assert.True(t, child.IsPending())
+		assert.Equal(t, saga.StateIsCompensating, child.State())
+		assert.Equal(t, "ExampleChildStep1", child.CurrentStep().Name())
+
+		// Consume compensation of child step, parent fails with the child
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), child.ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		parent, child = findSessions(t)
+		assert.Equal(t, saga.StateFailed, child.State())
+		assert.False(t, parent.IsPending())
+		assert.Equal(t, saga.StateFailed, parent.State())
+	})
+
+	t.Run("should cancel a running sub-saga of a step without compensation when the parent is canceled", func(t *testing.T) {
+		CleanUp(t)
+
+		buildChildSagaAndRegister(
+			builder.
+				Step("ExampleChildStep1").
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				SubSaga("ExampleChildSaga", nil).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		parent, child := findSessions(t)
+		assert.True(t, child.IsPending())
+		assert.Equal(t, saga.StateCommon, child.State())
+
+		err = registry.CancelSession(context.Background(), exampleSaga.Name(), parent.ID())
+		assert.Nil(t, err)
+
+		// Neither step is compensable, so both sessions fail at once
+		parent, child = findSessions(t)
+		assert.False(t, parent.IsPending())
+		assert.Equal(t, saga.StateFailed, parent.State())
+		assert.False(t, child.IsPending())
+		assert.Equal(t, saga.StateFailed, child.State())
+		assert.Equal(t, saga.OutcomeCanceled, historyEntryOf(child, "ExampleChildStep1", saga.OutcomeCanceled).Outcome)
+	})
+
+	t.Run("should compensate a sub-saga which completes while the parent is compensating", func(t *testing.T) {
+		CleanUp(t)
+
+		buildChildSagaAndRegister(
+			builder.
+				Step("ExampleChildStep1").
+				AsPivot().
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleChildStep2").
+				Invoke(ExampleEndpoint).
+				Retry().
+				Build(),
+		)
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				SubSaga("ExampleChildSaga", nil).
+				WithSubSagaCompensation().
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		parent, child := findSessions(t)
+
+		// Consume first child step, which is the pivot step of the child
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), child.ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		// The child cannot be canceled after its pivot step, so the parent waits for it
+		err = registry.CancelSession(context.Background(), exampleSaga.Name(), parent.ID())
+		assert.Nil(t, err)
+
+		parent, child = findSessions(t)
+		assert.True(t, parent.IsPending())
+		assert.Equal(t, saga.StateIsCompensating, parent.State())
+		assert.Equal(t, saga.StateCommon, child.State())
+		assert.Equal(t, "ExampleChildStep2", child.CurrentStep().Name())
+
+		// Consume second child step, then the completed child is compensated
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), child.ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		parent, child = findSessions(t)
+		assert.True(t, parent.IsPending())
+		assert.Equal(t, saga.StateIsCompensating, parent.State())
+		assert.True(t, child.IsPending())
+		assert.Equal(t, saga.StateIsCompensating, child.State())
+		assert.Equal(t, "ExampleChildStep1", child.CurrentStep().Name())
+	})
+
+	t.Run("should cancel a running sub-saga when its step times out", func(t *testing.T) {
+		CleanUp(t)
+
+		buildChildSagaAndRegister(
+			builder.
+				Step("ExampleChildStep1").
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				WithTimeout(10*time.Millisecond).
+				SubSaga("ExampleChildSaga", nil).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		time.Sleep(20 * time.Millisecond)
+
+		err = saga.NewTimeoutSweeper(registry, 10).Execute()
+		assert.Nil(t, err)
+
+		parent, child := findSessions(t)
+		assert.Equal(t, saga.StateFailed, parent.State())
+		assert.Equal(t, saga.StateFailed, child.State())
+		assert.Equal(t, saga.OutcomeCanceled, historyEntryOf(child, "ExampleChildStep1", saga.OutcomeCanceled).Outcome)
+	})
+
+	t.Run("should reject definition with every problem listed", func(t *testing.T) {
+		CleanUp(t)
+
+		_, err := builder.
+			Step("ExampleStep1").
+			AsPivot().
+			LocalInvoke(ExampleLocalEndpoint).
+			Step("ExampleStep1").
+			LocalInvoke(ExampleLocalEndpoint).
+			Build()
+
+		assert.ErrorIs(t, err, saga.ErrDuplicateStepName)
+		assert.ErrorIs(t, err, saga.ErrStepAfterPivotNotRetriable)
+
+		var validationErr *saga.ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []saga.ValidationProblem{
+			{Step: "ExampleStep1", Err: saga.ErrDuplicateStepName},
+			{Step: "ExampleStep1", Err: saga.ErrStepAfterPivotNotRetriable},
+		}, validationErr.Problems)
+	})
+
+	t.Run("should not compensate over the pivot step when a failure is not retriable", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				AsPivot().
+				LocalInvoke(ExampleLocalEndpoint).
+				Step("ExampleStep3").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				RetryWith(saga.RetryPolicy{
+					RetryOn: func(cause error) bool {
+						return !saga.IsFailureResponse(cause, nil)
+					},
+				}).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		for i := 0; i < 3; i++ {
+			err = relayer.Execute()
+			assert.Nil(t, err)
+		}
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateNeedsIntervention, sessions[0].State())
+		assert.Equal(t, "ExampleStep3", sessions[0].CurrentStep().Name())
+	})
+
+	t.Run("should compensate when the pivot step fails", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				AsPivot().
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				Step("ExampleStep3").
+				LocalInvoke(ExampleLocalEndpoint).
+				Retry().
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+	})
+
+	t.Run("should route message by saga name when session ID is supplied by caller", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				Invoke(ExampleEndpoint).
+				Step("ExampleStep2").
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{"id": "order-1"})
+		assert.Nil(t, err)
+
+		// Commands constructed from the session carry its saga name
+		commands, err := exampleCommandRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(commands))
+		assert.Equal(t, exampleSaga.Name(), commands[0].SagaName())
+
+		// Without saga name, the message is not routed to any saga
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), "order-1", "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessageForSaga(uuid.New().String(), "order-1", exampleSaga.Name(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessageForSaga(uuid.New().String(), "order-1", "UnknownSaga", "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		// A message of a saga which is not registered is ignored
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+	})
+
+	t.Run("should route message to the saga with the longest name prefixing session ID", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+
+		def, err := builder.
+			Step("ExampleSecondStep1").
+			Invoke(ExampleEndpoint).
+			Step("ExampleSecondStep2").
+			Invoke(ExampleEndpoint).
+			Build()
+		assert.Nil(t, err)
+
+		secondSaga := saga.NewSaga[*ExampleSession, ExampleTxContext](
+			"ExampleSaga-Second",
+			def,
+			exampleSessionFactory,
+			exampleSessionRepository,
+		)
+		err = saga.RegisterSagaTo(registry, secondSaga)
+		assert.Nil(t, err)
+
+		err = registry.StartSaga(secondSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleSecondStep2", sessions[0].CurrentStep().Name())
+	})
+
+	// runConcurrentResponses sends the responses of both branches of a parallel step at the same time.
+	// If interleave is true, both orchestrations load the session before either of them saves it.
+	runConcurrentResponses := func(t *testing.T, interleave bool) {
+		for i := 0; i < 20; i++ {
+			CleanUp(t)
+
+			buildSagaAndRegister(
+				builder.
+					Step("ExampleStep1").
+					Parallel(
+						saga.NewStepBuilder[ExampleTxContext]().
+							Step("ExampleBranch1").
+							LocalInvoke(ExampleLocalEndpoint).
+							Step("ExampleBranch2").
+							LocalInvoke(ExampleSecondAlwaysFailingLocalEndpoint).
+							MustBuild(),
+					).
+					Build(),
+			)
+
+			err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+			assert.Nil(t, err)
+
+			sessions, err := sessionRepository.loadAll()
+			assert.Nil(t, err)
+			sessionID := sessions[0].ID()
+
+			if interleave {
+				var loads int32
+				loaded := make(chan struct{})
+				sessionRepository.onLoad = func() {
+					n := atomic.AddInt32(&loads, 1)
+					if n == 2 {
+						close(loaded)
+					}
+					if n <= 2 {
+						<-loaded
+					}
+				}
+			}
+
+			// Both branches respond at the same time
+			var wg sync.WaitGroup
+			for _, channel := range []saga.Channel[ExampleTxContext]{ExampleSuccessChannel, ExampleSecondSuccessChannel} {
+				wg.Add(1)
+				go func(channel saga.Channel[ExampleTxContext]) {
+					defer wg.Done()
+					err := channel.Send(ExampleMessage{
+						AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessionID, "Triggered by test"),
+					})
+					assert.Nil(t, err)
+				}(channel)
+			}
+			wg.Wait()
+			sessionRepository.onLoad = nil
+
+			sessions, err = sessionRepository.loadAll()
+			assert.Nil(t, err)
+			assert.Equal(t, saga.StateCompleted, sessions[0].State())
+		}
+	}
+
+	t.Run("should not lose a response when responses of a session arrive concurrently", func(t *testing.T) {
+		runConcurrentResponses(t, true)
+	})
+
+	t.Run("should handle responses of a session one at a time with session lock", func(t *testing.T) {
+		defaultOrchestrator := orchestrator
+		orchestrator = saga.NewOrchestrator[ExampleTxContext](UnitOfWorkFactory, saga.WithSessionLock[ExampleTxContext](), saga.WithConflictRetries[ExampleTxContext](0))
+		defer func() {
+			orchestrator = defaultOrchestrator
+		}()
+
+		runConcurrentResponses(t, false)
+	})
+
+	t.Run("should reject a redelivered response with inbox", func(t *testing.T) {
+		defaultOrchestrator := orchestrator
+		orchestrator = saga.NewOrchestrator[ExampleTxContext](UnitOfWorkFactory, saga.WithInbox[ExampleTxContext](exampleInboxRepository))
+		defer func() {
+			orchestrator = defaultOrchestrator
+		}()
+
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleLocalEndpoint).
+				Step("ExampleStep3").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		response := ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		}
+
+		err = ExampleSuccessChannel.Send(response)
+		assert.Nil(t, err)
+
+		// The broker delivers the same response again
+		err = ExampleSuccessChannel.Send(response)
+		assert.ErrorIs(t, err, saga.ErrDuplicateMessage)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+		assert.Equal(t, saga.StateCommon, sessions[0].State())
+	})
+
+	t.Run("should reject a response which does not answer the current step and attempt", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		outbox, err := successRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, outbox, 1)
+		assert.Equal(t, "ExampleStep1", outbox[0].StepName())
+		assert.Equal(t, 1, outbox[0].Attempt())
+
+		err = ExampleSuccessChannel.Send(outbox[0])
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+
+		// A late copy of the response of the first step
+		late := ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessageForStep(uuid.New().String(), sessions[0].ID(), "ExampleStep1", 1, "Triggered by test"),
+		}
+		err = ExampleSuccessChannel.Send(late)
+		assert.ErrorIs(t, err, saga.ErrStaleResponse)
+
+		// A response of another attempt of the second step
+		otherAttempt := ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessageForStep(uuid.New().String(), sessions[0].ID(), "ExampleStep2", 2, "Triggered by test"),
+		}
+		err = ExampleSuccessChannel.Send(otherAttempt)
+		assert.ErrorIs(t, err, saga.ErrStaleResponse)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+		assert.Equal(t, saga.StateCommon, sessions[0].State())
+
+		current := ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessageForStep(uuid.New().String(), sessions[0].ID(), "ExampleStep2", 1, "Triggered by test"),
+		}
+		err = ExampleSuccessChannel.Send(current)
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateCompleted, sessions[0].State())
+	})
+
+	t.Run("should deliver events to listeners in and after the unit of work", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				Build(),
+		)
+
+		var events []saga.Event
+		registry.AddListener(saga.ListenerFunc[ExampleTxContext](func(event saga.Event) saga.Executable[ExampleTxContext] {
+			return func(ctx ExampleTxContext) error {
+				events = append(events, event)
+				return nil
+			}
+		}))
+
+		asyncEvents := make(chan saga.Event, 100)
+		registry.AddAsyncListener(saga.AsyncListenerFunc(func(ctx context.Context, event saga.Event) {
+			asyncEvents <- event
+		}))
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		// Consume first compensation step
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		expected := []struct {
+			eventType     saga.EventType
+			step          string
+			previousState saga.State
+			state         saga.State
+		}{
+			{saga.EventSagaStarted, "", saga.StateCommon, saga.StateCommon},
+			{saga.EventStepInvoked, "ExampleStep1", saga.StateCommon, saga.StateCommon},
+			{saga.EventStepSucceeded, "ExampleStep1", saga.StateCommon, saga.StateCommon},
+			{saga.EventStepInvoked, "ExampleStep2", saga.StateCommon, saga.StateCommon},
+			{saga.EventStepFailed, "ExampleStep2", saga.StateCommon, saga.StateCommon},
+			{saga.EventSagaStateChanged, "", saga.StateCommon, saga.StateIsCompensating},
+			{saga.EventCompensationStarted, "ExampleStep1", saga.StateIsCompensating, saga.StateIsCompensating},
+			{saga.EventCompensationFinished, "ExampleStep1", saga.StateIsCompensating, saga.StateIsCompensating},
+			{saga.EventSagaStateChanged, "", saga.StateIsCompensating, saga.StateFailed},
+			{saga.EventSagaFailed, "", saga.StateFailed, saga.StateFailed},
+		}
+
+		assert.Len(t, events, len(expected))
+		for i, event := range events {
+			assert.Equal(t, expected[i].eventType, event.Type)
+			assert.Equal(t, expected[i].step, event.Step)
+			assert.Equal(t, expected[i].previousState, event.PreviousState)
+			assert.Equal(t, expected[i].state, event.State)
+			assert.Equal(t, "ExampleSaga", event.SagaName)
+			assert.Equal(t, sessions[0].ID(), event.SessionID)
+		}
+
+		// The events of different units of work are delivered concurrently
+		var received []saga.Event
+		for range expected {
+			select {
+			case event := <-asyncEvents:
+				received = append(received, event)
+			case <-time.After(time.Second):
+				t.Fatal("async listener did not receive the event")
+			}
+		}
+		assert.ElementsMatch(t, events, received)
+	})
+
+	t.Run("should trace a saga across the relayer and the response", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		propagator := propagation.TraceContext{}
+
+		defaultOrchestrator := orchestrator
+		orchestrator = saga.NewOrchestrator[ExampleTxContext](UnitOfWorkFactory, saga.WithTracerProvider[ExampleTxContext](provider), saga.WithPropagator[ExampleTxContext](propagator))
+		defer func() {
+			orchestrator = defaultOrchestrator
+		}()
+
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				Invoke(ExampleEndpoint).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		outbox, err := commandRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, outbox, 1)
+		assert.Contains(t, outbox[0].Headers(), "traceparent")
+
+		// Publish only the command, which is answered on the success channel at once
+		commandChannels := messageRelayer.NewChannelRegistry[ExampleTxContext]()
+		err = commandChannels.Register(ExampleCommandChannel)
+		assert.Nil(t, err)
+
+		relayer := messageRelayer.New(10, commandChannels, UnitOfWorkFactory, messageRelayer.WithTracerProvider(provider), messageRelayer.WithPropagator(propagator))
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		spans := make(map[string]tracetest.SpanStub)
+		for _, span := range exporter.GetSpans() {
+			spans[span.Name] = span
+		}
+
+		start := spans["start ExampleSaga"]
+		invoke1 := spans["invoke ExampleStep1"]
+		publish := spans["publish ExampleCommandChannel"]
+		consume := spans["consume ExampleSuccessChannel"]
+		invoke2 := spans["invoke ExampleStep2"]
+
+		traceID := start.SpanContext.TraceID()
+		assert.True(t, traceID.IsValid())
+		for _, span := range []tracetest.SpanStub{invoke1, publish, consume, invoke2} {
+			assert.Equal(t, traceID, span.SpanContext.TraceID())
+		}
+
+		assert.Equal(t, start.SpanContext.SpanID(), invoke1.Parent.SpanID())
+		assert.Equal(t, invoke1.SpanContext.SpanID(), publish.Parent.SpanID())
+		assert.Equal(t, invoke1.SpanContext.SpanID(), consume.Parent.SpanID())
+		assert.Equal(t, consume.SpanContext.SpanID(), invoke2.Parent.SpanID())
+	})
+
+	t.Run("should measure sagas and the relayer with the metrics collector", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				Build(),
+		)
+
+		collector := prometheusMetrics.New("example")
+		metrics := prometheus.NewRegistry()
+		err := metrics.Register(collector)
+		assert.Nil(t, err)
+
+		registry.AddAsyncListener(saga.NewMetricsListener(collector))
+
+		err = registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory, messageRelayer.WithMetrics(collector))
+		err = relayer.Execute()
+		assert.Nil(t, err)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		// Consume first compensation step
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		// The listener is called after the units of work are committed
+		assert.Eventually(t, func() bool {
+			return counterValue(t, metrics, "example_saga_sessions_finished_total", map[string]string{"saga": "ExampleSaga", "state": "failed"}) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		assert.Equal(t, float64(1), counterValue(t, metrics, "example_saga_sessions_started_total", map[string]string{"saga": "ExampleSaga"}))
+		assert.Equal(t, float64(0), counterValue(t, metrics, "example_saga_sessions_finished_total", map[string]string{"saga": "ExampleSaga", "state": "completed"}))
+		assert.Equal(t, float64(1), counterValue(t, metrics, "example_saga_steps_finished_total", map[string]string{"saga": "ExampleSaga", "step": "ExampleStep1", "direction": "forward", "result": "succeeded"}))
+		assert.Equal(t, float64(1), counterValue(t, metrics, "example_saga_steps_finished_total", map[string]string{"saga": "ExampleSaga", "step": "ExampleStep2", "direction": "forward", "result": "failed"}))
+		assert.Equal(t, float64(1), counterValue(t, metrics, "example_saga_compensations_started_total", map[string]string{"saga": "ExampleSaga", "step": "ExampleStep1"}))
+		assert.Equal(t, float64(1), counterValue(t, metrics, "example_saga_steps_finished_total", map[string]string{"saga": "ExampleSaga", "step": "ExampleStep1", "direction": "backward", "result": "succeeded"}))
+		assert.Equal(t, float64(1), counterValue(t, metrics, "example_saga_session_state_transitions_total", map[string]string{"saga": "ExampleSaga", "from": "common", "to": "compensating"}))
+		assert.Equal(t, float64(1), counterValue(t, metrics, "example_saga_session_state_transitions_total", map[string]string{"saga": "ExampleSaga", "from": "compensating", "to": "failed"}))
+		assert.Equal(t, float64(0), counterValue(t, metrics, "example_saga_sessions_needing_intervention_total", map[string]string{"saga": "ExampleSaga"}))
+
+		published := counterValue(t, metrics, "example_relayer_messages_published_total", map[string]string{"channel": ExampleSuccessChannelName, "source": "outbox"}) +
+			counterValue(t, metrics, "example_relayer_messages_published_total", map[string]string{"channel": ExampleFailureChannelName, "source": "outbox"})
+		assert.Equal(t, float64(2), published)
+
+		count, err := testutil.GatherAndCount(metrics, "example_relayer_batch_duration_seconds")
+		assert.Nil(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("should log events and rejected responses with the fields of the session", func(t *testing.T) {
+		var buffer bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+		defaultOrchestrator := orchestrator
+		orchestrator = saga.NewOrchestrator[ExampleTxContext](UnitOfWorkFactory, saga.WithLogger[ExampleTxContext](logger))
+		defer func() {
+			orchestrator = defaultOrchestrator
+		}()
+
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		// The session is completed, so the response is rejected
+		response := ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		}
+		err = ExampleSuccessChannel.Send(response)
+		assert.ErrorIs(t, err, saga.ErrDeadSession)
+
+		var records []map[string]interface{}
+		decoder := json.NewDecoder(&buffer)
+		for decoder.More() {
+			var record map[string]interface{}
+			assert.Nil(t, decoder.Decode(&record))
+			records = append(records, record)
+		}
+
+		var invoked map[string]interface{}
+		for _, record := range records {
+			if record[saga.LogKeyEvent] == "StepInvoked" {
+				invoked = record
+			}
+		}
+
+		assert.Equal(t, "DEBUG", invoked["level"])
+		assert.Equal(t, "ExampleSaga", invoked[saga.LogKeySaga])
+		assert.Equal(t, sessions[0].ID(), invoked[saga.LogKeySessionID])
+		assert.Equal(t, "ExampleStep1", invoked[saga.LogKeyStep])
+
+		rejected := records[len(records)-1]
+		assert.Equal(t, "WARN", rejected["level"])
+		assert.Equal(t, "ExampleSaga", rejected[saga.LogKeySaga])
+		assert.Equal(t, sessions[0].ID(), rejected[saga.LogKeySessionID])
+		assert.Equal(t, response.ID(), rejected[saga.LogKeyMessageID])
+		assert.Equal(t, ExampleSuccessChannelName, rejected[saga.LogKeyChannel])
+		assert.Equal(t, saga.ErrDeadSession.Error(), rejected[saga.LogKeyError])
+	})
+
+	t.Run("should reject responses of a paused session until it is resumed", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				LocalInvoke(ExampleLocalEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		err = registry.PauseSession(context.Background(), exampleSaga.Name(), sessions[0].ID())
+		assert.Nil(t, err)
+
+		response := ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		}
+		err = ExampleSuccessChannel.Send(response)
+		assert.ErrorIs(t, err, saga.ErrSessionPaused)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].Paused())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+
+		err = registry.ResumeSession(context.Background(), exampleSaga.Name(), sessions[0].ID())
+		assert.Nil(t, err)
+
+		// The broker delivers the rejected response again
+		err = ExampleSuccessChannel.Send(response)
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.False(t, sessions[0].Paused())
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+
+		var outcomes []saga.Outcome
+		for _, entry := range sessions[0].History() {
+			outcomes = append(outcomes, entry.Outcome)
+		}
+		assert.Equal(t, []saga.Outcome{
+			saga.OutcomeInvoked,
+			saga.OutcomePaused,
+			saga.OutcomeResumed,
+			saga.OutcomeSucceeded,
+			saga.OutcomeInvoked,
+		}, outcomes)
+	})
+
+	t.Run("should compensate from the current step when a session is canceled", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleLocalEndpoint).
+				WithLocalCompensation(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		err = registry.CancelSession(context.Background(), exampleSaga.Name(), sessions[0].ID())
+		assert.Nil(t, err)
+
+		// ExampleStep2 is not compensable, so ExampleStep1 is compensated
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+		assert.Contains(t, sessions[0].History(), saga.HistoryEntry{
+			Step:      "ExampleStep2",
+			Direction: saga.DirectionForward,
+			Outcome:   saga.OutcomeCanceled,
+			Timestamp: historyEntryOf(sessions[0], "ExampleStep2", saga.OutcomeCanceled).Timestamp,
+		})
+
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateFailed, sessions[0].State())
+
+		err = registry.CancelSession(context.Background(), exampleSaga.Name(), sessions[0].ID())
+		assert.ErrorIs(t, err, saga.ErrDeadSession)
+	})
+
+	t.Run("should compensate the branches which have not responded when a parallel step is canceled", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				Parallel(
+					saga.NewStepBuilder[ExampleTxContext]().
+						Step("ExampleBranch1").
+						LocalInvoke(ExampleLocalEndpoint).
+						WithLocalCompensation(ExampleLocalEndpoint).
+						Step("ExampleBranch2").
+						LocalInvoke(ExampleSecondLocalEndpoint).
+						WithLocalCompensation(ExampleSecondLocalEndpoint).
+						MustBuild(),
+				).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		// Consume one branch, the other has not responded yet
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		err = registry.CancelSession(context.Background(), exampleSaga.Name(), sessions[0].ID())
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateIsCompensating, sessions[0].State())
+		assert.Equal(t, "ExampleStep1", sessions[0].CurrentStep().Name())
+
+		state, _ := sessions[0].BranchState("ExampleStep1", "ExampleBranch1")
+		assert.Equal(t, saga.BranchStateCompensating, state)
+		state, _ = sessions[0].BranchState("ExampleStep1", "ExampleBranch2")
+		assert.Equal(t, saga.BranchStateCompensating, state)
+
+		timedOut := 0
+		for _, entry := range sessions[0].History() {
+			if entry.Outcome == saga.OutcomeTimedOut {
+				timedOut++
+			}
+		}
+		assert.Equal(t, 1, timedOut)
+	})
+
+	t.Run("should not cancel a session after its pivot step", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				AsPivot().
+				LocalInvoke(ExampleLocalEndpoint).
+				Step("ExampleStep2").
+				Invoke(ExampleEndpoint).
+				RetryWith(saga.RetryPolicy{}).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+
+		err = ExampleSuccessChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), sessions[0].ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		err = registry.CancelSession(context.Background(), exampleSaga.Name(), sessions[0].ID())
+		assert.ErrorIs(t, err, saga.ErrSessionAfterPivot)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateCommon, sessions[0].State())
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+	})
+
+	t.Run("should skip a step which needs intervention", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				RetryWith(saga.RetryPolicy{MaxAttempts: 1}).
+				Step("ExampleStep2").
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
+		err = relayer.Execute()
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateNeedsIntervention, sessions[0].State())
+
+		err = registry.SkipStep(context.Background(), exampleSaga.Name(), sessions[0].ID())
+		assert.Nil(t, err)
+
+		sessions, err = sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.True(t, sessions[0].IsPending())
+		assert.Equal(t, saga.StateCommon, sessions[0].State())
+		assert.Equal(t, "ExampleStep2", sessions[0].CurrentStep().Name())
+		assert.Equal(t, saga.DirectionForward, historyEntryOf(sessions[0], "ExampleStep1", saga.OutcomeForceSkipped).Direction)
+	})
+
+	t.Run("should complete or fail a session by an operator", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+		err = registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		sessions, err := sessionRepository.loadAll()
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 2)
+
+		err = registry.CompleteSession(context.Background(), exampleSaga.Name(), sessions[0].ID())
+		assert.Nil(t, err)
+		err = registry.FailSession(context.Background(), exampleSaga.Name(), sessions[1].ID())
+		assert.Nil(t, err)
+
+		completed, err := sessionRepository.Load(sessions[0].ID())
+		assert.Nil(t, err)
+		assert.False(t, completed.IsPending())
+		assert.Equal(t, saga.StateCompleted, completed.State())
+		assert.Equal(t, saga.DirectionForward, historyEntryOf(completed, "ExampleStep1", saga.OutcomeForceCompleted).Direction)
+
+		failed, err := sessionRepository.Load(sessions[1].ID())
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateFailed, failed.State())
+		assert.Equal(t, saga.DirectionForward, historyEntryOf(failed, "ExampleStep1", saga.OutcomeForceFailed).Direction)
+
+		err = registry.FailSession(context.Background(), exampleSaga.Name(), sessions[0].ID())
+		assert.ErrorIs(t, err, saga.ErrDeadSession)
+	})
+
+	t.Run("should inspect and operate sessions through the admin handler", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				LocalInvoke(ExampleAlwaysFailingLocalEndpoint).
+				RetryWith(saga.RetryPolicy{MaxAttempts: 1}).
+				Step("ExampleStep2").
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+
+		err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+		err = registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+		assert.Nil(t, err)
+
+		// Only the first session runs out of retries
+		sessions, err := sessionRepository.QuerySessions(context.Background(), saga.SessionQuery{})
+		assert.Nil(t, err)
+		stuck := sessions[0]
+		err = ExampleFailureChannel.Send(ExampleMessage{
+			AbstractMessage: saga.NewAbstractMessage(uuid.New().String(), stuck.ID(), "Triggered by test"),
+		})
+		assert.Nil(t, err)
+
+		server := httptest.NewServer(http.StripPrefix("/admin", admin.NewHandler(registry, channelRegistry)))
+		defer server.Close()
+
+		request := func(method string, path string, body interface{}) int {
+			req, err := http.NewRequest(method, server.URL+"/admin"+path, nil)
+			assert.Nil(t, err)
+
+			res, err := server.Client().Do(req)
+			assert.Nil(t, err)
+			defer res.Body.Close()
+
+			if body != nil {
+				assert.Nil(t, json.NewDecoder(res.Body).Decode(body))
+			}
+
+			return res.StatusCode
+		}
+
+		var sagas []admin.SagaView
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/sagas", &sagas))
+		assert.Equal(t, []admin.SagaView{{
+			Name: "ExampleSaga",
+			Steps: []admin.StepView{
+				{Name: "ExampleStep1", Invocable: true, Retriable: true},
+				{Name: "ExampleStep2", Invocable: true},
+			},
+		}}, sagas)
+
+		var listed []admin.SessionView
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/sagas/ExampleSaga/sessions?state=NeedsIntervention", &listed))
+		assert.Len(t, listed, 1)
+		assert.Equal(t, stuck.ID(), listed[0].ID)
+		assert.Equal(t, "ExampleStep1", listed[0].CurrentStep)
+		assert.Nil(t, listed[0].History)
+
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/sagas/ExampleSaga/sessions?state=Common&state=NeedsIntervention&limit=1", &listed))
+		assert.Len(t, listed, 1)
+
+		var session admin.SessionView
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/sagas/ExampleSaga/sessions/"+stuck.ID(), &session))
+		assert.Equal(t, "NeedsIntervention", session.State)
+		assert.Equal(t, "Failed", session.History[len(session.History)-1].Outcome)
+
+		assert.Equal(t, http.StatusOK, request(http.MethodPost, "/sagas/ExampleSaga/sessions/"+stuck.ID()+"/retry", &session))
+		assert.Equal(t, "IsRetrying", session.State)
+		assert.Equal(t, 2, session.Attempt)
+		assert.True(t, session.Pending)
+		assert.Equal(t, "ForceRetried", session.History[len(session.History)-2].Outcome)
+
+		// The failure responses of both sessions and of the retried invocation wait in the outbox
+		var messages []admin.MessageView
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/channels/ExampleFailureChannel/outbox", &messages))
+		assert.Len(t, messages, 3)
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/channels/ExampleFailureChannel/dead-letters", &messages))
+		assert.Len(t, messages, 0)
+
+		var channels admin.ChannelsView
+		assert.Equal(t, http.StatusOK, request(http.MethodGet, "/channels", &channels))
+		assert.Contains(t, channels.Channels, ExampleFailureChannelName)
+
+		var failure admin.ErrorView
+		assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/sagas/ExampleSaga/sessions/"+stuck.ID()+"/restart", &failure))
+		assert.Equal(t, admin.ErrUnknownAction.Error(), failure.Error)
+		assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/sagas/ExampleSaga/sessions?state=Unknown", nil))
+		assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/sagas/UnknownSaga", nil))
+		assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/sagas/ExampleSaga/sessions/unknown", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, request(http.MethodGet, "/sagas/ExampleSaga/sessions/"+stuck.ID()+"/retry", nil))
+		assert.Equal(t, http.StatusOK, request(http.MethodPost, "/sagas/ExampleSaga/sessions/"+sessions[1].ID()+"/complete", nil))
+		assert.Equal(t, http.StatusConflict, request(http.MethodPost, "/sagas/ExampleSaga/sessions/"+sessions[1].ID()+"/fail", nil))
+	})
+
+	t.Run("should query sessions by their fields page by page", func(t *testing.T) {
+		CleanUp(t)
+
+		buildSagaAndRegister(
+			builder.
+				Step("ExampleStep1").
+				Invoke(ExampleEndpoint).
+				Step("ExampleStep2").
+				Invoke(ExampleEndpoint).
+				Build(),
+		)
+
+		for i := 0; i < 3; i++ {
+			err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{})
+			assert.Nil(t, err)
+		}
+
+		sessions, err := registry.QuerySessions(context.Background(), exampleSaga.Name(), saga.SessionQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 3)
+
+		time.Sleep(time.Millisecond)
+		startedBefore := time.Now()
+
+		err = registry.CompleteSession(context.Background(), exampleSaga.Name(), sessions[0].ID())
+		assert.Nil(t, err)
+		err = registry.SkipStep(context.Background(), exampleSaga.Name(), sessions[1].ID())
+		assert.Nil(t, err)
+
+		pending := true
+		listed, err := registry.QuerySessions(context.Background(), exampleSaga.Name(), saga.SessionQuery{Pending: &pending})
+		assert.Nil(t, err)
+		assert.Len(t, listed, 2)
+
+		listed, err = registry.QuerySessions(context.Background(), exampleSaga.Name(), saga.SessionQuery{CurrentStep: "ExampleStep2"})
+		assert.Nil(t, err)
+		assert.Len(t, listed, 1)
+		assert.Equal(t, sessions[1].ID(), listed[0].ID())
+
+		listed, err = registry.QuerySessions(context.Background(), exampleSaga.Name(), saga.SessionQuery{UpdatedBefore: startedBefore})
+		assert.Nil(t, err)
+		assert.Len(t, listed, 1)
+		assert.Equal(t, sessions[2].ID(), listed[0].ID())
+
+		var paged []string
+		after := ""
+		for {
+			page, err := registry.QuerySessions(context.Background(), exampleSaga.Name(), saga.SessionQuery{After: after, Limit: 2})
+			assert.Nil(t, err)
+			if len(page) == 0 {
+				break
+			}
+
+			for _, session := range page {
+				paged = append(paged, session.ID())
+			}
+			after = page[len(page)-1].ID()
+		}
+		assert.Equal(t, []string{sessions[0].ID(), sessions[1].ID(), sessions[2].ID()}, paged)
+
+		_, err = registry.QuerySessions(context.Background(), "UnknownSaga", saga.SessionQuery{})
+		assert.ErrorIs(t, err, saga.ErrSagaNotFound)
+
+		server := httptest.NewServer(admin.NewHandler(registry, channelRegistry))
+		defer server.Close()
+
+		var views []admin.SessionView
+		res, err := server.Client().Get(server.URL + "/sagas/ExampleSaga/sessions?pending=false")
+		assert.Nil(t, err)
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&views))
+		res.Body.Close()
+		assert.Len(t, views, 1)
+		assert.Equal(t, sessions[0].ID(), views[0].ID)
+
+		res, err = server.Client().Get(server.URL + "/sagas/ExampleSaga/sessions?step=ExampleStep1&after=" + sessions[0].ID())
+		assert.Nil(t, err)
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&views))
+		res.Body.Close()
+		assert.Len(t, views, 1)
+		assert.Equal(t, sessions[2].ID(), views[0].ID)
+
+		res, err = server.Client().Get(server.URL + "/sagas/ExampleSaga/sessions?pending=maybe")
+		assert.Nil(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestSQLStore(t *testing.T) {
+	exampleMessageCodec := sqlStore.NewCodec(
+		func(message ExampleMessage) ([]byte, error) {
+			return []byte(message.exampleField), nil
+		},
+		func(metadata saga.AbstractMessage, payload []byte) (ExampleMessage, error) {
+			return ExampleMessage{AbstractMessage: metadata, exampleField: string(payload)}, nil
+		},
+	)
+
+	exampleEndpoint := func(successRepository, failureRepository *sqlStore.MessageRepository[ExampleMessage]) saga.LocalEndpoint[*sql.Tx] {
+		return saga.NewLocalEndpoint[*ExampleSession, ExampleMessage, ExampleMessage, *sql.Tx](
+			"SQLSuccessChannel",
+			ExampleMessageConstructor,
+			successRepository,
+			"SQLFailureChannel",
+			ExampleMessageConstructor,
+			failureRepository,
+			func(session saga.Session) (saga.Executable[*sql.Tx], error) {
+				return func(tx *sql.Tx) error {
+					return nil
+				}, nil
+			},
+		)
+	}
+
+	type examplePayload struct {
+		Session      *saga.BaseSession
+		ExampleField string
+	}
+
+	exampleSessionCodec := sqlStore.NewSessionCodec(
+		func(session *ExampleSession) ([]byte, error) {
+			return json.Marshal(examplePayload{Session: &session.BaseSession, ExampleField: session.exampleField})
+		},
+		func(session *ExampleSession, payload []byte) (*ExampleSession, error) {
+			decoded := examplePayload{Session: &session.BaseSession}
+			if err := json.Unmarshal(payload, &decoded); err != nil {
+				return nil, err
+			}
+
+			session.exampleField = decoded.ExampleField
+			return session, nil
+		},
+	)
+
+	t.Run("should relay messages from the SQL outbox and move the failed ones to the dead letters", func(t *testing.T) {
+		db := openSQLite(t,
+			fmt.Sprintf(sqlStore.MessageTableSchema, "outbox"),
+			fmt.Sprintf(sqlStore.MessageTableSchema, "dead_letter"),
+		)
+		factory := sqlStore.NewUnitOfWorkFactory(db)
+		repository := sqlStore.NewMessageRepository[ExampleMessage](db, "SQLChannel", exampleMessageCodec,
+			sqlStore.WithOutboxTable("outbox"),
+			sqlStore.WithDeadLetterTable("dead_letter"),
+			sqlStore.WithClaimLease(0),
+		)
+
+		var mutex sync.Mutex
+		var sent []ExampleMessage
+		sqlRegistry := saga.NewRegistry(saga.NewOrchestrator[*sql.Tx](factory))
+		channel := messageRelayer.NewChannel[ExampleMessage, *sql.Tx]("SQLChannel", sqlRegistry, repository, func(message saga.Message) error {
+			if message.(ExampleMessage).exampleField == "unpublishable" {
+				return errors.New("SQLChannel failed")
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			sent = append(sent, message.(ExampleMessage))
+			return nil
+		})
+		channels := messageRelayer.NewChannelRegistry[*sql.Tx]()
+		assert.Nil(t, channels.Register(channel))
+
+		uow, err := factory(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, uow.AddWorkUnit(repository.SaveMessages([]ExampleMessage{
+			{AbstractMessage: saga.NewAbstractMessage("1", "ExampleSaga-1", "Triggered by test"), exampleField: "published"},
+			{AbstractMessage: saga.NewAbstractMessage("2", "ExampleSaga-2", "Triggered by test"), exampleField: "unpublishable"},
+		})))
+		assert.Nil(t, uow.Commit())
+
+		relayer := messageRelayer.New(10, channels, factory)
+		assert.Nil(t, relayer.Execute())
+
+		assert.Len(t, sent, 1)
+		assert.Equal(t, "published", sent[0].exampleField)
+		assert.Equal(t, 0, countRows(t, db, "outbox"))
+		assert.Equal(t, 1, countRows(t, db, "dead_letter"))
+	})
+
+	t.Run("should run a saga whose sessions and messages are kept in SQL", func(t *testing.T) {
+		db := openSQLite(t,
+			fmt.Sprintf(sqlStore.SessionTableSchema, sqlStore.DefaultSessionTable),
+			fmt.Sprintf(sqlStore.MessageTableSchema, sqlStore.DefaultOutboxTable),
+			fmt.Sprintf(sqlStore.MessageTableSchema, sqlStore.DefaultDeadLetterTable),
+		)
+		factory := sqlStore.NewUnitOfWorkFactory(db)
+		sqlRegistry := saga.NewRegistry(saga.NewOrchestrator[*sql.Tx](factory))
+
+		successRepository := sqlStore.NewMessageRepository[ExampleMessage](db, "SQLSuccessChannel", exampleMessageCodec, sqlStore.WithClaimLease(0))
+		failureRepository := sqlStore.NewMessageRepository[ExampleMessage](db, "SQLFailureChannel", exampleMessageCodec, sqlStore.WithClaimLease(0))
+		channels := messageRelayer.NewChannelRegistry[*sql.Tx]()
+		assert.Nil(t, channels.Register(saga.NewChannel[ExampleMessage, *sql.Tx]("SQLSuccessChannel", sqlRegistry, successRepository)))
+		assert.Nil(t, channels.Register(saga.NewChannel[ExampleMessage, *sql.Tx]("SQLFailureChannel", sqlRegistry, failureRepository)))
+
+		endpoint := exampleEndpoint(successRepository, failureRepository)
+
+		def, err := saga.NewStepBuilder[*sql.Tx]().
+			Step("Reserve").
+			LocalInvoke(endpoint).
+			Step("Pay").
+			LocalInvoke(endpoint).
+			Build()
+		assert.Nil(t, err)
+
+		repository := sqlStore.NewSessionRepository[*ExampleSession](db, "SQLSaga", def, exampleSessionFactory, exampleSessionCodec)
+		assert.Nil(t, saga.RegisterSagaTo(sqlRegistry, saga.NewSaga[*ExampleSession, *sql.Tx]("SQLSaga", def, exampleSessionFactory, repository)))
+
+		assert.Nil(t, sqlRegistry.StartSaga("SQLSaga", map[string]interface{}{"id": "SQLSaga-1"}))
+
+		relayer := messageRelayer.New(10, channels, factory)
+		for i := 0; i < 5 && countRows(t, db, sqlStore.DefaultOutboxTable) > 0; i++ {
+			assert.Nil(t, relayer.Execute())
+		}
+
+		session, err := repository.Load("SQLSaga-1")
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateCompleted, session.State())
+		assert.Equal(t, "Pay", session.CurrentStep().Name())
+		assert.Equal(t, saga.DirectionForward, historyEntryOf(session, "Pay", saga.OutcomeSucceeded).Direction)
+		assert.Equal(t, 0, countRows(t, db, sqlStore.DefaultDeadLetterTable))
+	})
+}
+
+func TestInMemory(t *testing.T) {
+	cloneSession := func(session *ExampleSession) *ExampleSession {
+		cloned := session.clone()
+		return &cloned
+	}
+
+	t.Run("should run a saga whose sessions and messages are kept in memory", func(t *testing.T) {
+		store := inmemory.NewStore()
+		factory := saga.NewUnitOfWorkFactory[*inmemory.Tx](store)
+		inbox := inmemory.NewInboxRepository(store)
+		memoryRegistry := saga.NewRegistry(saga.NewOrchestrator[*inmemory.Tx](factory, saga.WithInbox[*inmemory.Tx](inbox)))
+
+		commandRepository := inmemory.NewMessageRepository[ExampleMessage](store)
+		successRepository := inmemory.NewMessageRepository[ExampleMessage](store)
+		failureRepository := inmemory.NewMessageRepository[ExampleMessage](store)
+		successChannel := saga.NewChannel[ExampleMessage, *inmemory.Tx](ExampleSuccessChannelName, memoryRegistry, successRepository)
+		channels := messageRelayer.NewChannelRegistry[*inmemory.Tx]()
+		assert.Nil(t, channels.Register(successChannel))
+		assert.Nil(t, channels.Register(saga.NewChannel[ExampleMessage, *inmemory.Tx](ExampleFailureChannelName, memoryRegistry, failureRepository)))
+		assert.Nil(t, channels.Register(messageRelayer.NewChannel[ExampleMessage, *inmemory.Tx](
+			ExampleCommandChannelName,
+			memoryRegistry,
+			commandRepository,
+			func(message saga.Message) error {
+				return successChannel.Send(message)
+			},
+		)))
+
+		endpoint := saga.NewEndpoint[*ExampleSession, ExampleMessage, ExampleMessage, ExampleMessage, *inmemory.Tx](
+			ExampleCommandChannelName,
+			ExampleMessageConstructor,
+			commandRepository,
+			ExampleSuccessChannelName,
+			ExampleMessageConstructor,
+			ExampleFailureChannelName,
+			ExampleMessageConstructor,
+		)
+
+		def, err := saga.NewStepBuilder[*inmemory.Tx]().
+			Step("ExampleStep1").
+			Invoke(endpoint).
+			Step("ExampleStep2").
+			Invoke(endpoint).
+			Build()
+		assert.Nil(t, err)
+
+		sessions := inmemory.NewSessionRepository[*ExampleSession](store, cloneSession)
+		assert.Nil(t, saga.RegisterSagaTo(memoryRegistry, saga.NewSaga[*ExampleSession, *inmemory.Tx]("ExampleSaga", def, exampleSessionFactory, sessions)))
+		assert.Nil(t, memoryRegistry.StartSaga("ExampleSaga", map[string]interface{}{"id": "ExampleSaga-1"}))
+
+		relayer := messageRelayer.New(10, channels, factory)
+		for i := 0; i < 2; i++ {
+			assert.Nil(t, relayer.Execute())
+		}
+
+		session, err := sessions.Load("ExampleSaga-1")
+		assert.Nil(t, err)
+		assert.Equal(t, saga.StateCompleted, session.State())
+		assert.Equal(t, "ExampleStep2", session.CurrentStep().Name())
+
+		listed, err := memoryRegistry.QuerySessions(context.Background(), "ExampleSaga", saga.SessionQuery{States: []saga.State{saga.StateCompleted}})
+		assert.Nil(t, err)
+		assert.Len(t, listed, 1)
+	})
+}
+
+// countRows returns the number of rows of the table.
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	var count int
+	assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&count))
+	return count
+}
+
+// openSQLite opens a database in a temporary file, and creates its tables by the statements.
+// The database is closed when the test finishes.
+func openSQLite(t *testing.T, statements ...string) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "saga.db"))
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	// A transaction holds the only connection, so that SQLite never reports the database as locked.
+	db.SetMaxOpenConns(1)
+
+	for _, statement := range statements {
+		_, err := db.Exec(statement)
+		assert.Nil(t, err)
+	}
+
+	return db
+}
+
+// counterValue returns the value of the counter with the name and the labels gathered from the gatherer,
+// or zero if it is not gathered.
+func counterValue(t *testing.T, gatherer prometheus.Gatherer, name string, labels map[string]string) float64 {
+	families, err := gatherer.Gather()
+	assert.Nil(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] != label.GetValue() {
+					continue metrics
+				}
+			}
+
+			return metric.GetCounter().GetValue()
+		}
+	}
+
+	return 0
+}
+
+// historyEntryOf returns the last entry of the history of the session with the step and the outcome.
+func historyEntryOf(session *ExampleSession, step string, outcome saga.Outcome) saga.HistoryEntry {
+	history := session.History()
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Step == step && history[i].Outcome == outcome {
+			return history[i]
+		}
+	}
+
+	return saga.HistoryEntry{}
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/violetpay-org/go-saga"
 	"github.com/violetpay-org/go-saga/messageRelayer"
 	"log"
+	"log/slog"
 	"time"
 )
 
@@ -11,27 +14,25 @@ func main() {
 	relayer := messageRelayer.New(1, channelRegistry, UnitOfWorkFactory)
 	go messageRelayer.StartBatchRun(1*time.Second, relayer)
 
-	saga := NewExampleSaga()
-	saga.ApplySchemaTo(registry)
+	registry.AddAsyncListener(saga.AsyncListenerFunc(func(ctx context.Context, event saga.Event) {
+		slog.InfoContext(ctx, "saga event",
+			saga.LogKeyEvent, event.Type.String(),
+			saga.LogKeySessionID, event.SessionID,
+			saga.LogKeyStep, event.Step,
+			saga.LogKeyAttempt, event.Attempt,
+		)
+	}))
 
-	err := registry.StartSaga(saga.Name(), map[string]interface{}{
+	exampleSaga := NewExampleSaga()
+	exampleSaga.ApplySchemaTo(registry)
+
+	err := registry.StartSaga(exampleSaga.Name(), map[string]interface{}{
 		"id": "ExampleSaga-9cda1798-c3ad-463a-8a25-70c2416fed13",
 	})
 	if err != nil {
 		log.Print(err)
 	}
 
-	go func() {
-		for {
-			load, err := exampleSessionRepository.Load("ExampleSaga-9cda1798-c3ad-463a-8a25-70c2416fed13")
-			if err != nil {
-				return
-			}
-			log.Print("", load.currentStep.Name()+" ", load.State(), load.IsPending())
-			time.Sleep(1 * time.Second)
-		}
-	}()
-
 	fmt.Println("Hello, World!")
 
 	<-time.After(2 * 1000 * time.Millisecond)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"github.com/google/uuid"
 	"github.com/violetpay-org/go-saga"
 	"sync"
@@ -9,12 +10,15 @@ import (
 var exampleCommandRepository = NewExampleMessageRepository()
 var exampleSuccessResponseRepository = NewExampleMessageRepository()
 var exampleFailureResponseRepository = NewExampleMessageRepository()
+var exampleSecondSuccessResponseRepository = NewExampleMessageRepository()
+var exampleSecondFailureResponseRepository = NewExampleMessageRepository()
+var exampleInboxRepository = NewExampleInboxRepository()
 
 func ExampleMessageConstructor(session *ExampleSession) ExampleMessage {
 	return ExampleMessage{
-		AbstractMessage: saga.NewAbstractMessage(
+		AbstractMessage: saga.NewAbstractMessageFromSession(
 			uuid.New().String(),
-			session.ID(),
+			session,
 			"Triggered by test",
 		),
 		exampleField: session.exampleField,
@@ -133,3 +137,30 @@ func (e *ExampleMessageRepository) clear() {
 	e.outbox = sync.Map{}
 	e.deadLetter = sync.Map{}
 }
+
+func NewExampleInboxRepository() *ExampleInboxRepository {
+	return &ExampleInboxRepository{}
+}
+
+type ExampleInboxRepository struct {
+	received sync.Map
+}
+
+func (e *ExampleInboxRepository) HasReceived(ctx context.Context, messageID string) (bool, error) {
+	_, ok := e.received.Load(messageID)
+	return ok, nil
+}
+
+func (e *ExampleInboxRepository) SaveReceived(message saga.Message) saga.Executable[ExampleTxContext] {
+	return func(ctx ExampleTxContext) error {
+		if _, loaded := e.received.LoadOrStore(message.ID(), struct{}{}); loaded {
+			return saga.ErrDuplicateMessage
+		}
+
+		return nil
+	}
+}
+
+func (e *ExampleInboxRepository) clear() {
+	e.received = sync.Map{}
+}
@@ -12,13 +12,16 @@ type ExampleSaga struct {
 	saga.Saga[*ExampleSession, ExampleTxContext]
 }
 
-func (e *ExampleSaga) buildSaga() {
-	def := saga.NewStepBuilder[ExampleTxContext]().
+func (e *ExampleSaga) buildSaga() error {
+	def, err := saga.NewStepBuilder[ExampleTxContext]().
 		Step("ExampleStep1").
 		LocalInvoke(ExampleLocalEndpoint).
 		Step("ExampleStep2").
 		LocalInvoke(ExampleLocalEndpoint).
 		Build()
+	if err != nil {
+		return err
+	}
 
 	e.Saga = saga.NewSaga[*ExampleSession, ExampleTxContext](
 		"ExampleSaga",
@@ -26,9 +29,14 @@ func (e *ExampleSaga) buildSaga() {
 		exampleSessionFactory,
 		exampleSessionRepository,
 	)
+
+	return nil
 }
 
 func (e *ExampleSaga) ApplySchemaTo(registry *saga.Registry[ExampleTxContext]) error {
-	e.buildSaga()
+	if err := e.buildSaga(); err != nil {
+		return err
+	}
+
 	return saga.RegisterSagaTo(registry, e.Saga)
 }
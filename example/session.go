@@ -1,54 +1,30 @@
 package main
 
 import (
-	"errors"
+	"context"
 	"github.com/violetpay-org/go-saga"
 	"sync"
+	"time"
 )
 
 var exampleSessionRepository = NewExampleSessionRepository()
 var exampleSessionFactory saga.SessionFactory[*ExampleSession] = func(data map[string]interface{}) *ExampleSession {
 	return &ExampleSession{
-		id:           data["id"].(string),
+		BaseSession:  saga.NewBaseSession(data),
 		exampleField: "test",
 	}
 }
 
 type ExampleSession struct {
-	id           string
-	currentStep  saga.Step
-	pending      bool
-	state        saga.State
+	saga.BaseSession
 	exampleField string
 }
 
-func (e *ExampleSession) ID() string {
-	return e.id
-}
-
-func (e *ExampleSession) CurrentStep() saga.Step {
-	return e.currentStep
-}
-
-func (e *ExampleSession) UpdateCurrentStep(step saga.Step) error {
-	e.currentStep = step
-	return nil
-}
-
-func (e *ExampleSession) IsPending() bool {
-	return e.pending
-}
-
-func (e *ExampleSession) SetPending(pending bool) {
-	e.pending = pending
-}
-
-func (e *ExampleSession) State() saga.State {
-	return e.state
-}
-
-func (e *ExampleSession) SetState(state saga.State) {
-	e.state = state
+// clone returns a copy of the session which shares no map or slice with it.
+func (e *ExampleSession) clone() ExampleSession {
+	cloned := *e
+	cloned.BaseSession = e.BaseSession.Clone()
+	return cloned
 }
 
 func NewExampleSessionRepository() *ExampleSessionRepository {
@@ -57,22 +33,43 @@ func NewExampleSessionRepository() *ExampleSessionRepository {
 
 type ExampleSessionRepository struct {
 	sessions sync.Map
+	mutex    sync.Mutex
+
+	// onLoad is called whenever a session has been loaded, to interleave concurrent orchestrations in tests.
+	onLoad func()
 }
 
 func (e *ExampleSessionRepository) Load(id string) (*ExampleSession, error) {
 	sess, ok := e.sessions.Load(id)
 	if !ok {
-		return nil, errors.New("session not found")
+		return nil, saga.ErrSessionNotFound
 	}
 
-	session := sess.(ExampleSession)
+	stored := sess.(ExampleSession)
+	session := stored.clone()
+
+	if e.onLoad != nil {
+		e.onLoad()
+	}
 
 	return &session, nil
 }
 
 func (e *ExampleSessionRepository) Save(sess *ExampleSession) saga.Executable[ExampleTxContext] {
 	return func(ctx ExampleTxContext) error {
-		e.sessions.Store(sess.ID(), *sess)
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+
+		if prev, ok := e.sessions.Load(sess.ID()); ok {
+			stored := prev.(ExampleSession)
+			if stored.Version() != sess.Version() {
+				return saga.ErrSessionVersionConflict
+			}
+		}
+
+		sess.SetVersion(sess.Version() + 1)
+		sess.SetUpdatedAt(time.Now())
+		e.sessions.Store(sess.ID(), sess.clone())
 		return nil
 	}
 }
@@ -84,6 +81,30 @@ func (e *ExampleSessionRepository) Delete(sess *ExampleSession) saga.Executable[
 	}
 }
 
+func (e *ExampleSessionRepository) LoadExpired(ctx context.Context, now time.Time, limit int) ([]*ExampleSession, error) {
+	var sessions []*ExampleSession
+	e.sessions.Range(func(key, value interface{}) bool {
+		val := value.(ExampleSession)
+		if !val.Deadline().IsZero() && val.Deadline().Before(now) {
+			sessions = append(sessions, &val)
+		}
+		return len(sessions) < limit
+	})
+
+	return sessions, nil
+}
+
+func (e *ExampleSessionRepository) QuerySessions(ctx context.Context, query saga.SessionQuery) ([]*ExampleSession, error) {
+	var sessions []*ExampleSession
+	e.sessions.Range(func(key, value interface{}) bool {
+		val := value.(ExampleSession)
+		sessions = append(sessions, &val)
+		return true
+	})
+
+	return saga.FilterSessions(sessions, query), nil
+}
+
 func (e *ExampleSessionRepository) loadAll() ([]*ExampleSession, error) {
 	var sessions []*ExampleSession
 	e.sessions.Range(func(key, value interface{}) bool {
@@ -0,0 +1,169 @@
+package saga
+
+import (
+	"strconv"
+	"time"
+)
+
+type Direction int
+
+const (
+	// DirectionForward means the entry is about the invocation of a step.
+	DirectionForward Direction = iota
+	// DirectionBackward means the entry is about the compensation of a step.
+	DirectionBackward
+)
+
+func (d Direction) String() string {
+	if d == DirectionBackward {
+		return "Backward"
+	}
+
+	return "Forward"
+}
+
+type Outcome int
+
+const (
+	// OutcomeInvoked means the invocation or compensation command was sent.
+	OutcomeInvoked Outcome = iota
+	OutcomeSucceeded
+	OutcomeFailed
+	OutcomeTimedOut
+	// OutcomeSkipped means the step was passed over without being executed.
+	OutcomeSkipped
+	// OutcomePaused means an operator paused the session at the step.
+	OutcomePaused
+	// OutcomeResumed means an operator resumed the session paused at the step.
+	OutcomeResumed
+	// OutcomeCanceled means an operator canceled the session at the step, so the session compensates from the step.
+	OutcomeCanceled
+	// OutcomeForceSkipped means an operator passed over the step without waiting for its response.
+	OutcomeForceSkipped
+	// OutcomeForceCompleted means an operator completed the session at the step.
+	OutcomeForceCompleted
+	// OutcomeForceFailed means an operator failed the session at the step.
+	OutcomeForceFailed
+	// OutcomeForceRetried means an operator made the step be invoked or compensated again.
+	OutcomeForceRetried
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeInvoked:
+		return "Invoked"
+	case OutcomeSucceeded:
+		return "Succeeded"
+	case OutcomeFailed:
+		return "Failed"
+	case OutcomeTimedOut:
+		return "TimedOut"
+	case OutcomeSkipped:
+		return "Skipped"
+	case OutcomePaused:
+		return "Paused"
+	case OutcomeResumed:
+		return "Resumed"
+	case OutcomeCanceled:
+		return "Canceled"
+	case OutcomeForceSkipped:
+		return "ForceSkipped"
+	case OutcomeForceCompleted:
+		return "ForceCompleted"
+	case OutcomeForceFailed:
+		return "ForceFailed"
+	case OutcomeForceRetried:
+		return "ForceRetried"
+	}
+
+	return "Outcome(" + strconv.Itoa(int(o)) + ")"
+}
+
+// HistoryEntry is a record of what the orchestrator did to a step of a session.
+type HistoryEntry struct {
+	// Step is the name of the step.
+	Step string
+
+	// Branch is the name of the branch if the step is a parallel step and the entry is about one of its branches.
+	Branch string
+
+	Direction Direction
+	Outcome   Outcome
+	Timestamp time.Time
+
+	// MessageID is the ID of the response which caused the entry, or empty if the entry was not caused by a response.
+	MessageID string
+}
+
+// HistorySession is an optional interface that can be implemented by Session to keep an ordered execution log.
+// If it is implemented, the orchestrator compensates only the steps that the log records as succeeded,
+// instead of walking back every step of the definition.
+type HistorySession interface {
+	// History returns the entries of the session in the order they were appended.
+	History() []HistoryEntry
+
+	// AppendHistory appends an entry to the history of the session.
+	AppendHistory(entry HistoryEntry)
+}
+
+// record appends an entry about the step to the history of the session, if the session keeps a history,
+// and delivers the event about the entry to the listeners. msg is the response which caused the entry, and can be nil.
+func (o *orchestrator[Tx]) record(session Session, step Step, branch string, direction Direction, outcome Outcome, msg Message, def Definition, uow *UnitOfWork[Tx]) {
+	if eventType, ok := eventOf(direction, outcome); ok {
+		o.emit(session, eventType, step, branch, def, uow)
+	}
+
+	historySession, ok := session.(HistorySession)
+	if !ok {
+		return
+	}
+
+	entry := HistoryEntry{
+		Step:      step.Name(),
+		Branch:    branch,
+		Direction: direction,
+		Outcome:   outcome,
+		Timestamp: time.Now(),
+	}
+
+	if msg != nil {
+		entry.MessageID = msg.ID()
+	}
+
+	historySession.AppendHistory(entry)
+}
+
+// stepToCompensate returns the last step which succeeded in forward direction and has not been compensated yet,
+// by the history of the session. It returns nil if there is no such step.
+func stepToCompensate(session HistorySession, def Definition) Step {
+	history := session.History()
+
+	compensated := make(map[string]bool)
+	for _, entry := range history {
+		if entry.Branch != "" || entry.Direction != DirectionBackward {
+			continue
+		}
+
+		if entry.Outcome == OutcomeSucceeded || entry.Outcome == OutcomeSkipped || entry.Outcome == OutcomeForceSkipped {
+			compensated[entry.Step] = true
+		}
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		entry := history[i]
+		if entry.Branch != "" || entry.Direction != DirectionForward || entry.Outcome != OutcomeSucceeded {
+			continue
+		}
+
+		if compensated[entry.Step] {
+			continue
+		}
+
+		step := def.FindStep(entry.Step)
+		if step != nil && step.IsCompensable() {
+			return step
+		}
+	}
+
+	return nil
+}
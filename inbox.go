@@ -0,0 +1,39 @@
+package saga
+
+import "context"
+
+// InboxRepository records the IDs of the responses handled by the orchestrator, so that a redelivered response
+// does not move its session twice. The ID of a response is saved in the unit of work which saves its session.
+type InboxRepository[Tx TxContext] interface {
+	// HasReceived returns true if the response with the given ID has already been handled.
+	HasReceived(ctx context.Context, messageID string) (bool, error)
+
+	// SaveReceived saves the ID of the response. The executable must fail with ErrDuplicateMessage
+	// if the ID is already saved, so that the unit of work handling a redelivered response is not committed.
+	SaveReceived(message Message) Executable[Tx]
+}
+
+// WithInbox makes the orchestrator reject the responses whose IDs are already in the inbox with ErrDuplicateMessage.
+func WithInbox[Tx TxContext](inbox InboxRepository[Tx]) OrchestratorOption[Tx] {
+	return func(o *orchestratorOptions[Tx]) {
+		o.inbox = inbox
+	}
+}
+
+// receive rejects the response if it has already been handled, and adds the saving of its ID to the unit of work.
+func (o *orchestrator[Tx]) receive(msg Message, uow *UnitOfWork[Tx]) error {
+	if o.inbox == nil {
+		return nil
+	}
+
+	received, err := o.inbox.HasReceived(uow.Context(), msg.ID())
+	if err != nil {
+		return err
+	}
+
+	if received {
+		return ErrDuplicateMessage
+	}
+
+	return uow.AddWorkUnit(o.inbox.SaveReceived(msg))
+}
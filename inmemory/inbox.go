@@ -0,0 +1,58 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/violetpay-org/go-saga"
+)
+
+// InboxRepository keeps the IDs of the responses handled by the orchestrator in a Store.
+// It implements saga.InboxRepository.
+type InboxRepository struct {
+	store    *Store
+	received map[string]struct{}
+}
+
+func NewInboxRepository(store *Store) *InboxRepository {
+	return &InboxRepository{
+		store:    store,
+		received: make(map[string]struct{}),
+	}
+}
+
+func (r *InboxRepository) HasReceived(ctx context.Context, messageID string) (bool, error) {
+	var received bool
+	r.store.read(func() {
+		_, received = r.received[messageID]
+	})
+
+	return received, nil
+}
+
+// SaveReceived saves the ID of the response. It fails with saga.ErrDuplicateMessage if the ID is already saved,
+// and the transaction fails with it if another transaction saved the ID in the meantime.
+func (r *InboxRepository) SaveReceived(message saga.Message) saga.Executable[*Tx] {
+	return func(tx *Tx) error {
+		id := message.ID()
+		check := func() error {
+			if _, ok := r.received[id]; ok {
+				return saga.ErrDuplicateMessage
+			}
+
+			return nil
+		}
+
+		var err error
+		r.store.read(func() {
+			err = check()
+		})
+		if err != nil {
+			return err
+		}
+
+		tx.stage(check, func() {
+			r.received[id] = struct{}{}
+		})
+
+		return nil
+	}
+}
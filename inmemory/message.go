@@ -0,0 +1,151 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/violetpay-org/go-saga"
+)
+
+// MessageRepository keeps the outbox and the dead letters of a channel in a Store.
+// It implements saga.AbstractMessageRepository and saga.AbstractMessageLoadContextRepository,
+// and loads the messages in the order they were saved.
+type MessageRepository[M saga.Message] struct {
+	store      *Store
+	outbox     *messageQueue[M]
+	deadLetter *messageQueue[M]
+}
+
+func NewMessageRepository[M saga.Message](store *Store) *MessageRepository[M] {
+	return &MessageRepository[M]{
+		store:      store,
+		outbox:     newMessageQueue[M](),
+		deadLetter: newMessageQueue[M](),
+	}
+}
+
+func (r *MessageRepository[M]) GetMessagesFromOutbox(batchSize int) ([]M, error) {
+	return r.GetMessagesFromOutboxContext(context.Background(), batchSize)
+}
+
+func (r *MessageRepository[M]) GetMessagesFromDeadLetter(batchSize int) ([]M, error) {
+	return r.GetMessagesFromDeadLetterContext(context.Background(), batchSize)
+}
+
+func (r *MessageRepository[M]) GetMessagesFromOutboxContext(ctx context.Context, batchSize int) ([]M, error) {
+	var messages []M
+	r.store.read(func() {
+		messages = r.outbox.first(batchSize)
+	})
+
+	return messages, nil
+}
+
+func (r *MessageRepository[M]) GetMessagesFromDeadLetterContext(ctx context.Context, batchSize int) ([]M, error) {
+	var messages []M
+	r.store.read(func() {
+		messages = r.deadLetter.first(batchSize)
+	})
+
+	return messages, nil
+}
+
+func (r *MessageRepository[M]) SaveMessage(message M) saga.Executable[*Tx] {
+	return r.push(r.outbox, []M{message})
+}
+
+func (r *MessageRepository[M]) SaveMessages(messages []M) saga.Executable[*Tx] {
+	return r.push(r.outbox, messages)
+}
+
+func (r *MessageRepository[M]) SaveDeadLetter(message M) saga.Executable[*Tx] {
+	return r.push(r.deadLetter, []M{message})
+}
+
+func (r *MessageRepository[M]) SaveDeadLetters(messages []M) saga.Executable[*Tx] {
+	return r.push(r.deadLetter, messages)
+}
+
+func (r *MessageRepository[M]) DeleteMessage(message M) saga.Executable[*Tx] {
+	return r.remove(r.outbox, []M{message})
+}
+
+func (r *MessageRepository[M]) DeleteMessages(messages []M) saga.Executable[*Tx] {
+	return r.remove(r.outbox, messages)
+}
+
+func (r *MessageRepository[M]) DeleteDeadLetter(message M) saga.Executable[*Tx] {
+	return r.remove(r.deadLetter, []M{message})
+}
+
+func (r *MessageRepository[M]) DeleteDeadLetters(messages []M) saga.Executable[*Tx] {
+	return r.remove(r.deadLetter, messages)
+}
+
+func (r *MessageRepository[M]) push(queue *messageQueue[M], messages []M) saga.Executable[*Tx] {
+	return func(tx *Tx) error {
+		tx.stage(nil, func() {
+			for _, message := range messages {
+				queue.push(message)
+			}
+		})
+
+		return nil
+	}
+}
+
+func (r *MessageRepository[M]) remove(queue *messageQueue[M], messages []M) saga.Executable[*Tx] {
+	return func(tx *Tx) error {
+		tx.stage(nil, func() {
+			for _, message := range messages {
+				queue.remove(message.ID())
+			}
+		})
+
+		return nil
+	}
+}
+
+// messageQueue holds messages in the order they were pushed. A message pushed again with the ID of a message
+// in the queue replaces it and keeps its place.
+type messageQueue[M saga.Message] struct {
+	ids      []string
+	messages map[string]M
+}
+
+func newMessageQueue[M saga.Message]() *messageQueue[M] {
+	return &messageQueue[M]{messages: make(map[string]M)}
+}
+
+func (q *messageQueue[M]) push(message M) {
+	if _, ok := q.messages[message.ID()]; !ok {
+		q.ids = append(q.ids, message.ID())
+	}
+
+	q.messages[message.ID()] = message
+}
+
+func (q *messageQueue[M]) remove(id string) {
+	if _, ok := q.messages[id]; !ok {
+		return
+	}
+
+	delete(q.messages, id)
+	for i, queued := range q.ids {
+		if queued == id {
+			q.ids = append(q.ids[:i:i], q.ids[i+1:]...)
+			return
+		}
+	}
+}
+
+func (q *messageQueue[M]) first(n int) []M {
+	var messages []M
+	for _, id := range q.ids {
+		if len(messages) >= n {
+			break
+		}
+
+		messages = append(messages, q.messages[id])
+	}
+
+	return messages
+}
@@ -0,0 +1,90 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMessageRepository(t *testing.T) {
+	ids := func(messages []testMessage) []string {
+		var ids []string
+		for _, message := range messages {
+			ids = append(ids, message.ID())
+		}
+		return ids
+	}
+
+	t.Run("should load the messages in the order they were saved", func(t *testing.T) {
+		store := NewStore()
+		messages := NewMessageRepository[testMessage](store)
+
+		tx, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		for _, id := range []string{"5", "3", "1"} {
+			assert.Nil(t, messages.SaveMessage(newTestMessage(id))(tx))
+		}
+		assert.Nil(t, messages.SaveMessages([]testMessage{newTestMessage("4"), newTestMessage("2")})(tx))
+		assert.Nil(t, store.Commit(tx))
+
+		outbox, err := messages.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"5", "3", "1", "4", "2"}, ids(outbox))
+
+		tx, err = store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, messages.DeleteMessages([]testMessage{newTestMessage("3"), newTestMessage("4")})(tx))
+		assert.Nil(t, messages.DeleteMessage(newTestMessage("unknown"))(tx))
+		assert.Nil(t, store.Commit(tx))
+
+		outbox, err = messages.GetMessagesFromOutboxContext(context.Background(), 2)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"5", "1"}, ids(outbox))
+	})
+
+	t.Run("should replace a message saved again with its ID and keep its place", func(t *testing.T) {
+		store := NewStore()
+		messages := NewMessageRepository[testMessage](store)
+
+		replaced := newTestMessage("1")
+		replaced.Field = "replaced"
+
+		tx, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, messages.SaveMessages([]testMessage{newTestMessage("1"), newTestMessage("2"), replaced})(tx))
+		assert.Nil(t, store.Commit(tx))
+
+		outbox, err := messages.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1", "2"}, ids(outbox))
+		assert.Equal(t, "replaced", outbox[0].Field)
+	})
+
+	t.Run("should keep the dead letters apart from the outbox", func(t *testing.T) {
+		store := NewStore()
+		messages := NewMessageRepository[testMessage](store)
+
+		tx, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, messages.SaveMessage(newTestMessage("1"))(tx))
+		assert.Nil(t, messages.SaveDeadLetters([]testMessage{newTestMessage("2"), newTestMessage("3")})(tx))
+		assert.Nil(t, store.Commit(tx))
+
+		deadLetters, err := messages.GetMessagesFromDeadLetterContext(context.Background(), 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"2", "3"}, ids(deadLetters))
+
+		tx, err = store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, messages.DeleteDeadLetter(newTestMessage("2"))(tx))
+		assert.Nil(t, messages.DeleteDeadLetters([]testMessage{newTestMessage("1")})(tx))
+		assert.Nil(t, store.Commit(tx))
+
+		deadLetters, err = messages.GetMessagesFromDeadLetter(10)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"3"}, ids(deadLetters))
+		outbox, err := messages.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1"}, ids(outbox))
+	})
+}
@@ -0,0 +1,123 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/violetpay-org/go-saga"
+	"time"
+)
+
+// SessionRepository keeps the sessions of a saga in a Store. It implements saga.SessionRepository,
+// saga.SessionContextRepository and saga.SessionQueryRepository, so the sessions past their deadline
+// are found by TimeoutSweeper too.
+//
+// The repository keeps copies of the sessions made by the clone function, so that a session changed by
+// an orchestration which is not committed stays as it was saved last. Saving a saga.VersionedSession fails
+// with saga.ErrSessionVersionConflict if the stored session has another version, when the session is saved
+// and again when the transaction is committed.
+type SessionRepository[S saga.Session] struct {
+	store    *Store
+	clone    func(S) S
+	sessions map[string]S
+}
+
+// NewSessionRepository returns the repository of sessions in the store. clone must return a copy of the session
+// which shares no map or slice with it.
+func NewSessionRepository[S saga.Session](store *Store, clone func(S) S) *SessionRepository[S] {
+	return &SessionRepository[S]{
+		store:    store,
+		clone:    clone,
+		sessions: make(map[string]S),
+	}
+}
+
+func (r *SessionRepository[S]) Load(id string) (S, error) {
+	return r.LoadContext(context.Background(), id)
+}
+
+func (r *SessionRepository[S]) LoadContext(ctx context.Context, id string) (S, error) {
+	var session S
+	var ok bool
+	r.store.read(func() {
+		session, ok = r.sessions[id]
+	})
+
+	if !ok {
+		return session, saga.ErrSessionNotFound
+	}
+
+	return r.clone(session), nil
+}
+
+func (r *SessionRepository[S]) QuerySessions(ctx context.Context, query saga.SessionQuery) ([]S, error) {
+	var sessions []S
+	r.store.read(func() {
+		for _, session := range r.sessions {
+			sessions = append(sessions, session)
+		}
+	})
+
+	filtered := saga.FilterSessions(sessions, query)
+	for i, session := range filtered {
+		filtered[i] = r.clone(session)
+	}
+
+	return filtered, nil
+}
+
+func (r *SessionRepository[S]) Save(sess S) saga.Executable[*Tx] {
+	return func(tx *Tx) error {
+		versionedSession, versioned := saga.Session(sess).(saga.VersionedSession)
+
+		var version int64
+		if versioned {
+			version = versionedSession.Version()
+		}
+
+		check := func() error {
+			if !versioned {
+				return nil
+			}
+
+			stored, ok := r.sessions[sess.ID()]
+			if ok && saga.Session(stored).(saga.VersionedSession).Version() != version {
+				return saga.ErrSessionVersionConflict
+			}
+
+			return nil
+		}
+
+		var err error
+		r.store.read(func() {
+			err = check()
+		})
+		if err != nil {
+			return err
+		}
+
+		if versioned {
+			versionedSession.SetVersion(version + 1)
+		}
+
+		if timestampedSession, ok := saga.Session(sess).(saga.TimestampedSession); ok {
+			timestampedSession.SetUpdatedAt(time.Now())
+		}
+
+		saved := r.clone(sess)
+		tx.stage(check, func() {
+			r.sessions[saved.ID()] = saved
+		})
+
+		return nil
+	}
+}
+
+func (r *SessionRepository[S]) Delete(sess S) saga.Executable[*Tx] {
+	return func(tx *Tx) error {
+		id := sess.ID()
+		tx.stage(nil, func() {
+			delete(r.sessions, id)
+		})
+
+		return nil
+	}
+}
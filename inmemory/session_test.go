@@ -0,0 +1,123 @@
+package inmemory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/violetpay-org/go-saga"
+	"testing"
+)
+
+func TestSessionRepository(t *testing.T) {
+	save := func(t *testing.T, store *Store, executables ...saga.Executable[*Tx]) error {
+		tx, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		for _, executable := range executables {
+			if err := executable(tx); err != nil {
+				assert.Nil(t, store.Rollback(tx))
+				return err
+			}
+		}
+
+		return store.Commit(tx)
+	}
+
+	t.Run("should fail to save a session whose stored version is newer", func(t *testing.T) {
+		store := NewStore()
+		sessions := NewSessionRepository[*testSession](store, cloneTestSession)
+		assert.Nil(t, save(t, store, sessions.Save(newTestSession("TestSaga-1"))))
+
+		loaded, err := sessions.Load("TestSaga-1")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), loaded.Version())
+		stale, err := sessions.Load("TestSaga-1")
+		assert.Nil(t, err)
+
+		assert.Nil(t, save(t, store, sessions.Save(loaded)))
+		assert.Equal(t, int64(2), loaded.Version())
+		assert.ErrorIs(t, save(t, store, sessions.Save(stale)), saga.ErrSessionVersionConflict)
+
+		stored, err := sessions.Load("TestSaga-1")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), stored.Version())
+	})
+
+	t.Run("should fail the commit of a session saved by another transaction in the meantime", func(t *testing.T) {
+		store := NewStore()
+		sessions := NewSessionRepository[*testSession](store, cloneTestSession)
+		assert.Nil(t, save(t, store, sessions.Save(newTestSession("TestSaga-1"))))
+
+		first, err := sessions.Load("TestSaga-1")
+		assert.Nil(t, err)
+		second, err := sessions.Load("TestSaga-1")
+		assert.Nil(t, err)
+		first.Items = []string{"first"}
+		second.Items = []string{"second"}
+
+		firstTx, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		secondTx, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+
+		// Both saves pass, since neither transaction is committed yet
+		assert.Nil(t, sessions.Save(first)(firstTx))
+		assert.Nil(t, sessions.Save(second)(secondTx))
+
+		assert.Nil(t, store.Commit(firstTx))
+		assert.ErrorIs(t, store.Commit(secondTx), saga.ErrSessionVersionConflict)
+
+		stored, err := sessions.Load("TestSaga-1")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"first"}, stored.Items)
+		assert.Equal(t, int64(2), stored.Version())
+	})
+
+	t.Run("should keep the sessions apart from the copies which are loaded and saved", func(t *testing.T) {
+		store := NewStore()
+		sessions := NewSessionRepository[*testSession](store, cloneTestSession)
+
+		session := newTestSession("TestSaga-1")
+		session.Items = []string{"saved"}
+		assert.Nil(t, save(t, store, sessions.Save(session)))
+		assert.False(t, session.UpdatedAt().IsZero())
+
+		// Changes to the saved session and to a loaded copy stay out of the repository until they are saved
+		session.Items[0] = "changed after save"
+		loaded, err := sessions.Load("TestSaga-1")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"saved"}, loaded.Items)
+
+		loaded.Items[0] = "changed after load"
+		loaded.SetState(saga.StateFailed)
+		reloaded, err := sessions.Load("TestSaga-1")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"saved"}, reloaded.Items)
+		assert.Equal(t, saga.StateCommon, reloaded.State())
+
+		listed, err := sessions.QuerySessions(context.Background(), saga.SessionQuery{})
+		assert.Nil(t, err)
+		assert.Len(t, listed, 1)
+		listed[0].Items[0] = "changed after query"
+		reloaded, err = sessions.Load("TestSaga-1")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"saved"}, reloaded.Items)
+	})
+
+	t.Run("should query and delete sessions", func(t *testing.T) {
+		store := NewStore()
+		sessions := NewSessionRepository[*testSession](store, cloneTestSession)
+
+		first := newTestSession("TestSaga-1")
+		second := newTestSession("TestSaga-2")
+		second.SetState(saga.StateFailed)
+		assert.Nil(t, save(t, store, sessions.Save(first), sessions.Save(second)))
+
+		listed, err := sessions.QuerySessions(context.Background(), saga.SessionQuery{States: []saga.State{saga.StateFailed}})
+		assert.Nil(t, err)
+		assert.Len(t, listed, 1)
+		assert.Equal(t, "TestSaga-2", listed[0].ID())
+
+		assert.Nil(t, save(t, store, sessions.Delete(second)))
+		_, err = sessions.LoadContext(context.Background(), "TestSaga-2")
+		assert.ErrorIs(t, err, saga.ErrSessionNotFound)
+	})
+}
@@ -0,0 +1,97 @@
+// Package inmemory keeps sessions, messages and received message IDs in memory, for tests and prototypes.
+// The repositories write in transactions like the ones of a database: their writes are staged in the transaction
+// and applied together when it is committed, or discarded when it is rolled back.
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// Store is the memory which the repositories created with it share. It implements saga.TxHandler,
+// so that saga.NewUnitOfWorkFactory runs the units of work of the orchestrator and the relayer on it.
+// A transaction is applied at once, so reads never see a part of it.
+type Store struct {
+	mutex sync.RWMutex
+}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Tx is a transaction on a Store, which holds the writes of the repositories until it is committed.
+type Tx struct {
+	store  *Store
+	writes []write
+	done   bool
+}
+
+// write is a write staged in a transaction. check is called with the store locked when the transaction is committed,
+// and the transaction fails with its error without applying any write. apply then applies the write.
+type write struct {
+	check func() error
+	apply func()
+}
+
+// stage adds a write to the transaction. check can be nil.
+func (tx *Tx) stage(check func() error, apply func()) {
+	tx.writes = append(tx.writes, write{check: check, apply: apply})
+}
+
+func (s *Store) BeginTx(ctx context.Context) (*Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Tx{store: s}, nil
+}
+
+// Commit applies the writes of the transaction, unless one of them fails its check.
+func (s *Store) Commit(tx *Tx) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, w := range tx.writes {
+		if w.check == nil {
+			continue
+		}
+
+		if err := w.check(); err != nil {
+			return err
+		}
+	}
+
+	for _, w := range tx.writes {
+		w.apply()
+	}
+
+	return nil
+}
+
+// Rollback discards the writes of the transaction. It does nothing if the transaction is already done,
+// since the unit of work rolls back after every commit too.
+func (s *Store) Rollback(tx *Tx) error {
+	if tx == nil || tx.done {
+		return nil
+	}
+
+	tx.done = true
+	tx.writes = nil
+	return nil
+}
+
+// read runs fn with the store locked for reading.
+func (s *Store) read(fn func()) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	fn()
+}
@@ -0,0 +1,150 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/violetpay-org/go-saga"
+	"testing"
+)
+
+type testSession struct {
+	saga.BaseSession
+	Items []string
+}
+
+func newTestSession(id string) *testSession {
+	return &testSession{BaseSession: saga.NewBaseSession(map[string]interface{}{"id": id, "sagaName": "TestSaga"})}
+}
+
+func cloneTestSession(session *testSession) *testSession {
+	return &testSession{BaseSession: session.BaseSession.Clone(), Items: append([]string(nil), session.Items...)}
+}
+
+type testMessage struct {
+	saga.AbstractMessage
+	Field string
+}
+
+func newTestMessage(id string) testMessage {
+	return testMessage{AbstractMessage: saga.NewAbstractMessage(id, "TestSaga-"+id, "Triggered by test")}
+}
+
+func TestStore(t *testing.T) {
+	t.Run("should apply the writes of a transaction only when it is committed", func(t *testing.T) {
+		store := NewStore()
+		sessions := NewSessionRepository[*testSession](store, cloneTestSession)
+		messages := NewMessageRepository[testMessage](store)
+
+		tx, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, sessions.Save(newTestSession("TestSaga-1"))(tx))
+		assert.Nil(t, messages.SaveMessage(newTestMessage("1"))(tx))
+
+		// The staged writes are not read before the commit
+		_, err = sessions.Load("TestSaga-1")
+		assert.ErrorIs(t, err, saga.ErrSessionNotFound)
+		outbox, err := messages.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, outbox, 0)
+
+		assert.Nil(t, store.Commit(tx))
+
+		_, err = sessions.Load("TestSaga-1")
+		assert.Nil(t, err)
+		outbox, err = messages.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, outbox, 1)
+	})
+
+	t.Run("should discard the writes of a transaction which is rolled back", func(t *testing.T) {
+		store := NewStore()
+		sessions := NewSessionRepository[*testSession](store, cloneTestSession)
+		messages := NewMessageRepository[testMessage](store)
+		inbox := NewInboxRepository(store)
+
+		tx, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, sessions.Save(newTestSession("TestSaga-1"))(tx))
+		assert.Nil(t, messages.SaveMessage(newTestMessage("1"))(tx))
+		assert.Nil(t, messages.SaveDeadLetter(newTestMessage("2"))(tx))
+		assert.Nil(t, inbox.SaveReceived(newTestMessage("3"))(tx))
+		assert.Nil(t, store.Rollback(tx))
+
+		// A rolled back transaction can be neither committed nor rolled back again
+		assert.ErrorIs(t, store.Commit(tx), ErrTxDone)
+		assert.Nil(t, store.Rollback(tx))
+
+		_, err = sessions.Load("TestSaga-1")
+		assert.ErrorIs(t, err, saga.ErrSessionNotFound)
+		outbox, err := messages.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, outbox, 0)
+		deadLetters, err := messages.GetMessagesFromDeadLetter(10)
+		assert.Nil(t, err)
+		assert.Len(t, deadLetters, 0)
+		received, err := inbox.HasReceived(context.Background(), "3")
+		assert.Nil(t, err)
+		assert.False(t, received)
+	})
+
+	t.Run("should discard the writes of a unit of work which fails", func(t *testing.T) {
+		store := NewStore()
+		factory := saga.NewUnitOfWorkFactory[*Tx](store)
+		sessions := NewSessionRepository[*testSession](store, cloneTestSession)
+		messages := NewMessageRepository[testMessage](store)
+
+		uow, err := factory(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, uow.AddWorkUnit(sessions.Save(newTestSession("TestSaga-1"))))
+		assert.Nil(t, uow.AddWorkUnit(messages.SaveMessage(newTestMessage("1"))))
+		assert.Nil(t, uow.AddWorkUnit(func(tx *Tx) error {
+			return errors.New("failed after the writes")
+		}))
+		assert.NotNil(t, uow.Commit())
+
+		_, err = sessions.Load("TestSaga-1")
+		assert.ErrorIs(t, err, saga.ErrSessionNotFound)
+		outbox, err := messages.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, outbox, 0)
+	})
+
+	t.Run("should apply none of the writes of a transaction which fails a check", func(t *testing.T) {
+		store := NewStore()
+		messages := NewMessageRepository[testMessage](store)
+		inbox := NewInboxRepository(store)
+
+		first, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		second, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+
+		response := newTestMessage("response")
+		assert.Nil(t, inbox.SaveReceived(response)(first))
+		assert.Nil(t, messages.SaveMessage(newTestMessage("1"))(second))
+		assert.Nil(t, inbox.SaveReceived(response)(second))
+
+		assert.Nil(t, store.Commit(first))
+		assert.ErrorIs(t, store.Commit(second), saga.ErrDuplicateMessage)
+		assert.ErrorIs(t, store.Commit(second), ErrTxDone)
+
+		outbox, err := messages.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, outbox, 0)
+
+		// The ID saved by a committed transaction is rejected when it is saved again
+		tx, err := store.BeginTx(context.Background())
+		assert.Nil(t, err)
+		assert.ErrorIs(t, inbox.SaveReceived(response)(tx), saga.ErrDuplicateMessage)
+		assert.Nil(t, store.Rollback(tx))
+	})
+
+	t.Run("should not begin a transaction when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := NewStore().BeginTx(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
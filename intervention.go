@@ -0,0 +1,292 @@
+package saga
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"strconv"
+)
+
+// Intervention is an action of an operator on a session which is stuck or must be stopped.
+type Intervention int
+
+const (
+	// InterventionPause makes the orchestrator reject the responses of the session and leave it at its deadline
+	// until the session is resumed. The session must implement PausableSession.
+	InterventionPause Intervention = iota
+	// InterventionResume makes the orchestrator handle the responses and the deadline of the paused session again.
+	InterventionResume
+	// InterventionCancel compensates the session from its current step, as if the step had failed.
+	InterventionCancel
+	// InterventionSkip passes over the invocation or compensation of the current step without waiting for its response.
+	InterventionSkip
+	// InterventionComplete completes the session at its current step.
+	InterventionComplete
+	// InterventionFail fails the session at its current step without compensating the steps before it.
+	InterventionFail
+	// InterventionRetry invokes or compensates the current step again, even if its retries ran out.
+	InterventionRetry
+)
+
+func (i Intervention) String() string {
+	switch i {
+	case InterventionPause:
+		return "Pause"
+	case InterventionResume:
+		return "Resume"
+	case InterventionCancel:
+		return "Cancel"
+	case InterventionSkip:
+		return "Skip"
+	case InterventionComplete:
+		return "Complete"
+	case InterventionFail:
+		return "Fail"
+	case InterventionRetry:
+		return "Retry"
+	}
+
+	return "Intervention(" + strconv.Itoa(int(i)) + ")"
+}
+
+// PausableSession is an optional interface that can be implemented by Session to be paused by an operator.
+type PausableSession interface {
+	// Paused returns true if the session is paused.
+	Paused() bool
+
+	// SetPaused sets whether the session is paused.
+	SetPaused(paused bool)
+}
+
+func isPaused(session Session) bool {
+	pausable, ok := session.(PausableSession)
+	return ok && pausable.Paused()
+}
+
+func (o *orchestrator[Tx]) Intervene(ctx context.Context, saga Saga[Session, Tx], sessionID string, intervention Intervention) error {
+	ctx, span := o.tracer.Start(ctx, "intervene "+saga.Name(), trace.WithAttributes(
+		AttributeSagaName.String(saga.Name()),
+		AttributeSessionID.String(sessionID),
+		AttributeIntervention.String(intervention.String()),
+	))
+
+	err := o.withSession(ctx, sessionID, func() error {
+		return o.intervene(ctx, saga, sessionID, intervention)
+	})
+	EndSpan(span, err)
+
+	args := []any{LogKeySaga, saga.Name(), LogKeySessionID, sessionID, LogKeyIntervention, intervention.String()}
+	if err != nil {
+		o.logFailure(ctx, "failed to intervene in session", err, args...)
+	} else {
+		o.logger.InfoContext(ctx, "intervened in session", args...)
+	}
+
+	return err
+}
+
+func (o *orchestrator[Tx]) intervene(ctx context.Context, saga Saga[Session, Tx], sessionID string, intervention Intervention) error {
+	var uow *UnitOfWork[Tx]
+	var err error
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	sagaSession, err := loadSession(ctx, saga.Repository(), sessionID)
+	if err != nil {
+		return err
+	}
+
+	if sagaSession.State() == StateCompleted || sagaSession.State() == StateFailed {
+		return ErrDeadSession
+	}
+
+	currentStep := sagaSession.CurrentStep()
+	if saga.Definition().Exists(currentStep) == false {
+		return ErrSessionStepAndDefinitionMismatch
+	}
+
+	uow, err = o.newUnitOfWork(ctx, saga.Name(), sessionID)
+	if err != nil {
+		return err
+	}
+
+	switch intervention {
+	case InterventionPause:
+		err = o.pauseSession(sagaSession, currentStep, saga.Definition(), uow)
+	case InterventionResume:
+		err = o.resumeSession(sagaSession, currentStep, saga.Definition(), uow)
+	case InterventionCancel:
+		err = o.cancelSession(sagaSession, currentStep, saga.Definition(), uow)
+	case InterventionSkip:
+		err = o.skipStep(sagaSession, currentStep, saga.Definition(), uow)
+	case InterventionComplete:
+		err = o.finishSession(sagaSession, currentStep, StateCompleted, saga.Definition(), uow)
+	case InterventionFail:
+		err = o.finishSession(sagaSession, currentStep, StateFailed, saga.Definition(), uow)
+	case InterventionRetry:
+		err = o.retryStep(sagaSession, currentStep, saga.Definition(), uow)
+	default:
+		return ErrUnknownIntervention
+	}
+
+	if err != nil {
+		return err
+	}
+
+	saver := saga.Repository().Save(sagaSession)
+	err = uow.AddWorkUnit(saver)
+	if err != nil {
+		return err
+	}
+
+	release, err := o.resumeParent(sagaSession, uow)
+	defer release()
+	if err != nil {
+		return err
+	}
+
+	return uow.Commit()
+}
+
+func (o *orchestrator[Tx]) pauseSession(session Session, step Step, def Definition, uow *UnitOfWork[Tx]) error {
+	pausable, ok := session.(PausableSession)
+	if !ok {
+		return ErrSessionNotPausable
+	}
+
+	if pausable.Paused() {
+		return nil
+	}
+
+	pausable.SetPaused(true)
+	o.record(session, step, "", directionOf(session), OutcomePaused, nil, def, uow)
+	return nil
+}
+
+func (o *orchestrator[Tx]) resumeSession(session Session, step Step, def Definition, uow *UnitOfWork[Tx]) error {
+	if !isPaused(session) {
+		return nil
+	}
+
+	session.(PausableSession).SetPaused(false)
+	o.record(session, step, "", directionOf(session), OutcomeResumed, nil, def, uow)
+	return nil
+}
+
+// cancelSession compensates the session from the step. The step is compensated too, since its invocation
+// may have been executed although its response has not arrived yet. A compensating session is left as it is.
+func (o *orchestrator[Tx]) cancelSession(session Session, step Step, def Definition, uow *UnitOfWork[Tx]) error {
+	if directionOf(session) == DirectionBackward {
+		return nil
+	}
+
+	if def.isAfterPivot(session, step) {
+		return ErrSessionAfterPivot
+	}
+
+	o.unpause(session)
+	o.clearPending(session)
+	o.record(session, step, "", DirectionForward, OutcomeCanceled, nil, def, uow)
+
+	switch step := step.(type) {
+	case subSagaStep[Tx]:
+		// The compensation of a compensable step cancels the running session of the sub-saga itself.
+		if !step.IsCompensable() {
+			err := o.cancelSubSaga(session, step, uow)
+			if err != nil {
+				return err
+			}
+		}
+	case parallelStep[Tx]:
+		// The branches which have not responded yet are compensated like timed out ones.
+		err := o.timeOutPendingBranches(session, step, def, uow)
+		if err != nil {
+			return err
+		}
+	}
+
+	return o.compensateFrom(session, step, def, uow)
+}
+
+// skipStep moves the session past the invocation or compensation of the step, as if it had succeeded,
+// except that a skipped invocation is not compensated later.
+func (o *orchestrator[Tx]) skipStep(session Session, step Step, def Definition, uow *UnitOfWork[Tx]) error {
+	direction := directionOf(session)
+
+	o.unpause(session)
+	o.clearPending(session)
+	o.record(session, step, "", direction, OutcomeForceSkipped, nil, def, uow)
+
+	if direction == DirectionBackward {
+		return o.stepBackwardAndCompensate(session, step, def, uow)
+	}
+
+	return o.stepForwardAndInvoke(session, step, def, uow)
+}
+
+// retryStep invokes or compensates the step again as the next attempt, so that the responses
+// of the previous attempts are rejected as stale.
+func (o *orchestrator[Tx]) retryStep(session Session, step Step, def Definition, uow *UnitOfWork[Tx]) error {
+	direction := directionOf(session)
+
+	o.unpause(session)
+	o.clearPending(session)
+	o.record(session, step, "", direction, OutcomeForceRetried, nil, def, uow)
+	o.setAttempt(session, o.attempt(session)+1)
+	o.emit(session, EventStepRetried, step, "", def, uow)
+
+	if direction == DirectionBackward {
+		o.setState(session, StateIsCompensating, def, uow)
+		return o.compensateStep(session, step, def, uow)
+	}
+
+	o.setState(session, StateIsRetrying, def, uow)
+	return o.invokeStep(session, step, def, uow)
+}
+
+// finishSession completes or fails the session at the step without invoking or compensating any other step.
+func (o *orchestrator[Tx]) finishSession(session Session, step Step, state State, def Definition, uow *UnitOfWork[Tx]) error {
+	outcome, eventType := OutcomeForceCompleted, EventSagaCompleted
+	if state == StateFailed {
+		outcome, eventType = OutcomeForceFailed, EventSagaFailed
+	}
+
+	o.unpause(session)
+	o.clearPending(session)
+	o.record(session, step, "", directionOf(session), outcome, nil, def, uow)
+
+	o.setState(session, state, def, uow)
+	o.emit(session, eventType, nil, "", def, uow)
+	return nil
+}
+
+func (o *orchestrator[Tx]) unpause(session Session) {
+	if pausable, ok := session.(PausableSession); ok {
+		pausable.SetPaused(false)
+	}
+}
+
+// directionOf returns the direction the session is moving in. A session which needs intervention
+// is moving backward if the last entry of its history about the current step is about the compensation of the step.
+// Without a history, such a session is considered to be moving forward.
+func directionOf(session Session) Direction {
+	switch session.State() {
+	case StateIsCompensating:
+		return DirectionBackward
+	case StateNeedsIntervention:
+		historySession, ok := session.(HistorySession)
+		if !ok {
+			return DirectionForward
+		}
+
+		history := historySession.History()
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Step == session.CurrentStep().Name() && history[i].Branch == "" {
+				return history[i].Direction
+			}
+		}
+	}
+
+	return DirectionForward
+}
@@ -0,0 +1,61 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Keys of the fields logged by the orchestrator, the registry and the relayer.
+const (
+	LogKeySaga         = "saga"
+	LogKeySessionID    = "session_id"
+	LogKeyStep         = "step"
+	LogKeyBranch       = "branch"
+	LogKeyAttempt      = "attempt"
+	LogKeyEvent        = "event"
+	LogKeyMessageID    = "message_id"
+	LogKeyChannel      = "channel"
+	LogKeyDeadLetter   = "dead_letter"
+	LogKeyIntervention = "intervention"
+	LogKeyError        = "err"
+)
+
+// WithLogger sets the logger of the orchestrator. The registry using the orchestrator and the units of work
+// created by the orchestrator log with it too. The default is slog.Default().
+func WithLogger[Tx TxContext](logger *slog.Logger) OrchestratorOption[Tx] {
+	return func(o *orchestratorOptions[Tx]) {
+		o.logger = logger
+	}
+}
+
+// loggerHolder is implemented by the orchestrators which pass their logger to the registry they are used by.
+type loggerHolder interface {
+	loggerOf() *slog.Logger
+}
+
+func (o *orchestrator[Tx]) loggerOf() *slog.Logger {
+	return o.logger
+}
+
+// newUnitOfWork creates a unit of work which logs with the saga and the session it is created for.
+func (o *orchestrator[Tx]) newUnitOfWork(ctx context.Context, sagaName string, sessionID string) (*UnitOfWork[Tx], error) {
+	uow, err := o.uowFactory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uow.SetLogger(o.logger.With(LogKeySaga, sagaName, LogKeySessionID, sessionID))
+	return uow, nil
+}
+
+// logFailure logs the error the orchestrator failed to handle a session with. The responses rejected on purpose
+// are logged as warnings, since they are expected when responses are redelivered or the session is paused.
+func (o *orchestrator[Tx]) logFailure(ctx context.Context, msg string, err error, args ...any) {
+	level := slog.LevelError
+	if errors.Is(err, ErrDuplicateMessage) || errors.Is(err, ErrStaleResponse) || errors.Is(err, ErrDeadSession) || errors.Is(err, ErrSessionPaused) {
+		level = slog.LevelWarn
+	}
+
+	o.logger.Log(ctx, level, msg, append(args, LogKeyError, err)...)
+}
@@ -1,6 +1,7 @@
 package saga
 
 import (
+	"context"
 	"time"
 )
 
@@ -20,12 +21,48 @@ type Message interface {
 	CreatedAt() time.Time
 }
 
+// RoutedMessage is an optional interface that can be implemented by Message to carry routing metadata.
+// A message with a saga name is routed to the saga registered with the name, so the ID of its session can have any format.
+// Otherwise, the message is routed to the saga whose name prefixes the ID of its session.
+type RoutedMessage interface {
+	Message
+
+	// SagaName returns the name of the saga which the session of the message belongs to, or empty if it is unknown.
+	SagaName() string
+}
+
+// HeaderMessage is an optional interface that can be implemented by Message to carry metadata, like trace context.
+// The orchestrator injects the trace context of a step into the headers of the messages constructed for the step,
+// and extracts it from the headers of the responses.
+type HeaderMessage interface {
+	Message
+
+	// Headers returns the headers of the message. The map is shared by the copies of the message,
+	// so headers set on it are kept when the message is saved.
+	Headers() map[string]string
+}
+
+// StepMessage is an optional interface that can be implemented by Message to carry the step and the attempt it answers.
+// The orchestrator rejects a response with ErrStaleResponse if its step is not the current step of the session,
+// or its attempt is not the current attempt of the session. A response with an empty step name or a zero attempt
+// is not checked against the session.
+type StepMessage interface {
+	Message
+
+	// StepName returns the name of the step which the message was sent for, or empty if it is unknown.
+	StepName() string
+
+	// Attempt returns the attempt of the step which the message was sent for, or zero if it is unknown.
+	Attempt() int
+}
+
 func NewAbstractMessage(id, sessionID, trigger string) AbstractMessage {
 	return AbstractMessage{
 		id:        id,
 		sessionID: sessionID,
 		trigger:   trigger,
 		createdAt: time.Now(),
+		headers:   make(map[string]string),
 	}
 }
 
@@ -35,6 +72,74 @@ func NewAbstractMessageWithTime(id, sessionID, trigger string, createdAt time.Ti
 		sessionID: sessionID,
 		trigger:   trigger,
 		createdAt: createdAt,
+		headers:   make(map[string]string),
+	}
+}
+
+// NewAbstractMessageForSaga is like NewAbstractMessage but the message is routed to the saga named sagaName.
+func NewAbstractMessageForSaga(id, sessionID, sagaName, trigger string) AbstractMessage {
+	return AbstractMessage{
+		id:        id,
+		sessionID: sessionID,
+		sagaName:  sagaName,
+		trigger:   trigger,
+		createdAt: time.Now(),
+		headers:   make(map[string]string),
+	}
+}
+
+// NewAbstractMessageForStep is like NewAbstractMessage but the message answers the given attempt of the named step.
+// Services responding to a command should copy the step name and the attempt of the command into the response.
+func NewAbstractMessageForStep(id, sessionID, stepName string, attempt int, trigger string) AbstractMessage {
+	return AbstractMessage{
+		id:        id,
+		sessionID: sessionID,
+		stepName:  stepName,
+		attempt:   attempt,
+		trigger:   trigger,
+		createdAt: time.Now(),
+		headers:   make(map[string]string),
+	}
+}
+
+// NewAbstractMessageFromSession is like NewAbstractMessage but the message carries the current step and attempt of session,
+// and the saga name of session if it implements SagaSession.
+// It is meant to be used by MessageConstructor, which is called when the current step is invoked or compensated.
+func NewAbstractMessageFromSession(id string, session Session, trigger string) AbstractMessage {
+	var stepName string
+	if step := session.CurrentStep(); step != nil {
+		stepName = step.Name()
+	}
+
+	var attempt int
+	if attemptSession, ok := session.(AttemptSession); ok {
+		attempt = attemptSession.Attempt()
+	}
+
+	msg := NewAbstractMessageForStep(id, session.ID(), stepName, attempt, trigger)
+	if sagaSession, ok := session.(SagaSession); ok {
+		msg.sagaName = sagaSession.SagaName()
+	}
+
+	return msg
+}
+
+// RestoreAbstractMessage returns the message with every field given, like a repository loading a saved message needs.
+// Empty headers are replaced by an empty map, so that headers can be set on the restored message.
+func RestoreAbstractMessage(id, sessionID, sagaName, stepName string, attempt int, trigger string, createdAt time.Time, headers map[string]string) AbstractMessage {
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+
+	return AbstractMessage{
+		id:        id,
+		sessionID: sessionID,
+		sagaName:  sagaName,
+		stepName:  stepName,
+		attempt:   attempt,
+		trigger:   trigger,
+		createdAt: createdAt,
+		headers:   headers,
 	}
 }
 
@@ -44,8 +149,12 @@ func NewAbstractMessageWithTime(id, sessionID, trigger string, createdAt time.Ti
 type AbstractMessage struct {
 	id        string
 	sessionID string
+	sagaName  string
+	stepName  string
+	attempt   int
 	trigger   string
 	createdAt time.Time
+	headers   map[string]string
 }
 
 func (m AbstractMessage) ID() string {
@@ -56,6 +165,22 @@ func (m AbstractMessage) SessionID() string {
 	return m.sessionID
 }
 
+func (m AbstractMessage) SagaName() string {
+	return m.sagaName
+}
+
+func (m AbstractMessage) StepName() string {
+	return m.stepName
+}
+
+func (m AbstractMessage) Attempt() int {
+	return m.attempt
+}
+
+func (m AbstractMessage) Headers() map[string]string {
+	return m.headers
+}
+
 func (m AbstractMessage) Trigger() string {
 	return m.trigger
 }
@@ -65,8 +190,20 @@ func (m AbstractMessage) CreatedAt() time.Time {
 }
 
 func ConvertMessageRepository[M Message, Tx TxContext](repository AbstractMessageRepository[M, Tx]) AbstractMessageRepository[Message, Tx] {
-	getMessagesFromOutbox := func(batchSize int) ([]Message, error) {
-		ms, err := repository.GetMessagesFromOutbox(batchSize)
+	contextRepository, hasContext := repository.(AbstractMessageLoadContextRepository[M])
+
+	getMessagesFromOutbox := func(ctx context.Context, batchSize int) ([]Message, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var ms []M
+		var err error
+		if hasContext {
+			ms, err = contextRepository.GetMessagesFromOutboxContext(ctx, batchSize)
+		} else {
+			ms, err = repository.GetMessagesFromOutbox(batchSize)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -77,8 +214,18 @@ func ConvertMessageRepository[M Message, Tx TxContext](repository AbstractMessag
 		return messages, nil
 	}
 
-	getMessagesFromDeadLetter := func(batchSize int) ([]Message, error) {
-		ms, err := repository.GetMessagesFromDeadLetter(batchSize)
+	getMessagesFromDeadLetter := func(ctx context.Context, batchSize int) ([]Message, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var ms []M
+		var err error
+		if hasContext {
+			ms, err = contextRepository.GetMessagesFromDeadLetterContext(ctx, batchSize)
+		} else {
+			ms, err = repository.GetMessagesFromDeadLetter(batchSize)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -121,7 +268,7 @@ func ConvertMessageRepository[M Message, Tx TxContext](repository AbstractMessag
 		return repository.DeleteDeadLetters(ms)
 	}
 
-	return messageRepository[Tx]{
+	converted := messageRepository[Tx]{
 		saveMessage:               func(m Message) Executable[Tx] { return repository.SaveMessage(m.(M)) },
 		saveMessages:              saveMessages,
 		saveDeadLetter:            func(m Message) Executable[Tx] { return repository.SaveDeadLetter(m.(M)) },
@@ -133,6 +280,31 @@ func ConvertMessageRepository[M Message, Tx TxContext](repository AbstractMessag
 		getMessagesFromOutbox:     getMessagesFromOutbox,
 		getMessagesFromDeadLetter: getMessagesFromDeadLetter,
 	}
+
+	peekRepository, canPeek := repository.(AbstractMessagePeekRepository[M])
+	if !canPeek {
+		return converted
+	}
+
+	peek := func(ctx context.Context, limit int, deadLetter bool) ([]Message, error) {
+		var ms []M
+		var err error
+		if deadLetter {
+			ms, err = peekRepository.PeekMessagesFromDeadLetter(ctx, limit)
+		} else {
+			ms, err = peekRepository.PeekMessagesFromOutbox(ctx, limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		var messages []Message
+		for _, m := range ms {
+			messages = append(messages, m)
+		}
+		return messages, nil
+	}
+
+	return peekMessageRepository[Tx]{messageRepository: converted, peek: peek}
 }
 
 type AbstractMessageRepository[M Message, Tx TxContext] interface {
@@ -152,6 +324,22 @@ type AbstractMessageLoadRepository[M Message] interface {
 	GetMessagesFromDeadLetter(batchSize int) ([]M, error)
 }
 
+// AbstractMessageLoadContextRepository is an optional interface that can be implemented by AbstractMessageRepository
+// to receive the context of the caller when loading messages.
+// If the repository does not implement this interface, the methods of AbstractMessageLoadRepository are used instead.
+type AbstractMessageLoadContextRepository[M Message] interface {
+	GetMessagesFromOutboxContext(ctx context.Context, batchSize int) ([]M, error)
+	GetMessagesFromDeadLetterContext(ctx context.Context, batchSize int) ([]M, error)
+}
+
+// AbstractMessagePeekRepository is an optional interface that can be implemented by AbstractMessageRepository
+// whose loads claim the messages, to list the messages without hiding them from the relayer.
+// If the repository does not implement this interface, the listings load the messages instead.
+type AbstractMessagePeekRepository[M Message] interface {
+	PeekMessagesFromOutbox(ctx context.Context, limit int) ([]M, error)
+	PeekMessagesFromDeadLetter(ctx context.Context, limit int) ([]M, error)
+}
+
 type messageRepository[Tx TxContext] struct {
 	saveMessage               func(Message) Executable[Tx]
 	saveMessages              func([]Message) Executable[Tx]
@@ -161,8 +349,8 @@ type messageRepository[Tx TxContext] struct {
 	deleteMessages            func([]Message) Executable[Tx]
 	deleteDeadLetter          func(Message) Executable[Tx]
 	deleteDeadLetters         func([]Message) Executable[Tx]
-	getMessagesFromOutbox     func(int) ([]Message, error)
-	getMessagesFromDeadLetter func(int) ([]Message, error)
+	getMessagesFromOutbox     func(context.Context, int) ([]Message, error)
+	getMessagesFromDeadLetter func(context.Context, int) ([]Message, error)
 }
 
 func (r messageRepository[Tx]) SaveMessage(message Message) Executable[Tx] {
@@ -198,11 +386,33 @@ func (r messageRepository[Tx]) DeleteDeadLetters(messages []Message) Executable[
 }
 
 func (r messageRepository[Tx]) GetMessagesFromOutbox(batchSize int) ([]Message, error) {
-	return r.getMessagesFromOutbox(batchSize)
+	return r.getMessagesFromOutbox(context.Background(), batchSize)
 }
 
 func (r messageRepository[Tx]) GetMessagesFromDeadLetter(batchSize int) ([]Message, error) {
-	return r.getMessagesFromDeadLetter(batchSize)
+	return r.getMessagesFromDeadLetter(context.Background(), batchSize)
+}
+
+func (r messageRepository[Tx]) GetMessagesFromOutboxContext(ctx context.Context, batchSize int) ([]Message, error) {
+	return r.getMessagesFromOutbox(ctx, batchSize)
+}
+
+func (r messageRepository[Tx]) GetMessagesFromDeadLetterContext(ctx context.Context, batchSize int) ([]Message, error) {
+	return r.getMessagesFromDeadLetter(ctx, batchSize)
+}
+
+// peekMessageRepository is the messageRepository of a repository implementing AbstractMessagePeekRepository.
+type peekMessageRepository[Tx TxContext] struct {
+	messageRepository[Tx]
+	peek func(ctx context.Context, limit int, deadLetter bool) ([]Message, error)
+}
+
+func (r peekMessageRepository[Tx]) PeekMessagesFromOutbox(ctx context.Context, limit int) ([]Message, error) {
+	return r.peek(ctx, limit, false)
+}
+
+func (r peekMessageRepository[Tx]) PeekMessagesFromDeadLetter(ctx context.Context, limit int) ([]Message, error) {
+	return r.peek(ctx, limit, true)
 }
 
 // messagePacket is a value object that represents a AbstractMessage packet.
@@ -1,9 +1,10 @@
 package messageRelayer
 
 import (
+	"context"
 	"fmt"
 	"github.com/thanos-io/thanos/pkg/runutil"
-	"log"
+	"log/slog"
 	"time"
 )
 
@@ -11,18 +12,65 @@ type BatchJob interface {
 	Execute() error
 }
 
+// Logger is a key-value logger named by the string, which logs to slog.Default().
 type Logger string
 
 func (l Logger) Log(keyvals ...interface{}) error {
-	fmt.Println(fmt.Sprintf("[%s] ", l), keyvals)
+	return slogLogger{logger: slog.Default().With("logger", string(l))}.Log(keyvals...)
+}
+
+// slogLogger adapts a slog logger to the key-value logger which the batch run reports the errors of the job to.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Log(keyvals ...interface{}) error {
+	level := slog.LevelInfo
+	msg := ""
+	var args []any
+
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		switch key {
+		case "msg":
+			msg = fmt.Sprint(keyvals[i+1])
+		case "level":
+			_ = level.UnmarshalText([]byte(fmt.Sprint(keyvals[i+1])))
+		default:
+			args = append(args, key, keyvals[i+1])
+		}
+	}
+
+	l.logger.Log(context.Background(), level, msg, args...)
 	return nil
 }
 
-func StartBatchRun(interval time.Duration, job BatchJob) chan struct{} {
-	log.Print("Starting batch run")
+// BatchRunOption configures the batch run started by StartBatchRun.
+type BatchRunOption func(*batchRunOptions)
+
+type batchRunOptions struct {
+	logger *slog.Logger
+}
+
+// WithBatchRunLogger sets the logger which the batch run reports the errors of the job to.
+// The default is slog.Default().
+func WithBatchRunLogger(logger *slog.Logger) BatchRunOption {
+	return func(o *batchRunOptions) {
+		o.logger = logger
+	}
+}
+
+// StartBatchRun executes the job every interval.
+func StartBatchRun(interval time.Duration, job BatchJob, opts ...BatchRunOption) chan struct{} {
+	o := batchRunOptions{logger: slog.Default()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	o.logger.Info("Starting batch run")
 	haultChan := make(chan struct{})
 	runutil.RepeatInfinitely(
-		Logger("Batch"),
+		slogLogger{logger: o.logger},
 		interval,
 		haultChan,
 		job.Execute,
@@ -1,6 +1,7 @@
 package messageRelayer
 
 import (
+	"context"
 	"github.com/violetpay-org/go-saga"
 	"sync"
 )
@@ -25,6 +26,16 @@ type Channel[Tx saga.TxContext] interface {
 }
 
 func NewChannel[M saga.Message, Tx saga.TxContext](name saga.ChannelName, registry *saga.Registry[Tx], repository saga.AbstractMessageRepository[M, Tx], send func(message saga.Message) error) Channel[Tx] {
+	sendContext := func(ctx context.Context, message saga.Message) error {
+		return send(message)
+	}
+
+	return NewChannelWithContext(name, registry, repository, sendContext)
+}
+
+// NewChannelWithContext is like NewChannel, but send receives the context passed to SendContext,
+// so that publishing can be canceled together with the relayer.
+func NewChannelWithContext[M saga.Message, Tx saga.TxContext](name saga.ChannelName, registry *saga.Registry[Tx], repository saga.AbstractMessageRepository[M, Tx], send func(ctx context.Context, message saga.Message) error) Channel[Tx] {
 	return &channel[Tx]{name: name, registry: registry, repository: saga.ConvertMessageRepository(repository), send: send}
 }
 
@@ -32,7 +43,7 @@ type channel[Tx saga.TxContext] struct {
 	name       saga.ChannelName
 	registry   *saga.Registry[Tx]
 	repository saga.AbstractMessageRepository[saga.Message, Tx]
-	send       func(message saga.Message) error
+	send       func(ctx context.Context, message saga.Message) error
 }
 
 func (c *channel[Tx]) Name() saga.ChannelName {
@@ -40,7 +51,15 @@ func (c *channel[Tx]) Name() saga.ChannelName {
 }
 
 func (c *channel[Tx]) Send(message saga.Message) error {
-	return c.send(message)
+	return c.SendContext(context.Background(), message)
+}
+
+func (c *channel[Tx]) SendContext(ctx context.Context, message saga.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return c.send(ctx, message)
 }
 
 func (c *channel[Tx]) Repository() saga.AbstractMessageRepository[saga.Message, Tx] {
@@ -0,0 +1,35 @@
+package messageRelayer
+
+import (
+	"github.com/violetpay-org/go-saga"
+	"time"
+)
+
+// MetricsCollector receives the measurements of the relayer.
+type MetricsCollector interface {
+	// MessagePublished counts a message published to the channel.
+	// deadLetter is true if the message was relayed from the dead letters instead of the outbox.
+	MessagePublished(channel saga.ChannelName, deadLetter bool)
+
+	// MessageFailed counts a message which failed to be published to the channel.
+	// A failed message of the outbox is moved to the dead letters, and a failed dead letter stays there.
+	MessageFailed(channel saga.ChannelName, deadLetter bool)
+
+	// BatchRelayed observes how long a batch took to be relayed. err is the error which the batch failed with, or nil.
+	BatchRelayed(duration time.Duration, err error)
+}
+
+// WithMetrics sets the collector which the relayer reports its measurements to.
+func WithMetrics(collector MetricsCollector) Option {
+	return func(o *options) {
+		o.metrics = collector
+	}
+}
+
+type noopMetricsCollector struct{}
+
+func (noopMetricsCollector) MessagePublished(saga.ChannelName, bool) {}
+
+func (noopMetricsCollector) MessageFailed(saga.ChannelName, bool) {}
+
+func (noopMetricsCollector) BatchRelayed(time.Duration, error) {}
@@ -4,8 +4,13 @@ import (
 	"context"
 	"errors"
 	"github.com/violetpay-org/go-saga"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Relayer[Tx saga.TxContext] struct {
@@ -15,30 +20,108 @@ type Relayer[Tx saga.TxContext] struct {
 
 	unitOfWork        *saga.UnitOfWork[Tx]
 	unitOfWorkFactory saga.UnitOfWorkFactory[Tx]
+
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	metrics    MetricsCollector
+	logger     *slog.Logger
+}
+
+// Option configures the relayer created by New.
+type Option func(*options)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+	metrics        MetricsCollector
+	logger         *slog.Logger
+}
+
+// WithTracerProvider sets the provider of the tracer which the relayer starts a span for each published message by.
+// The default is the global provider of OpenTelemetry.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = provider
+	}
+}
+
+// WithPropagator sets the propagator which the relayer extracts the trace context of the messages by.
+// The default is the global propagator of OpenTelemetry.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		o.propagator = propagator
+	}
+}
+
+// WithLogger sets the logger of the relayer, which the units of work of the relayer log with too.
+// The default is slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
 }
 
 func New[Tx saga.TxContext](
 	batchSize int,
 	registry ChannelRegistry[Tx],
 	factory saga.UnitOfWorkFactory[Tx],
+	opts ...Option,
 ) BatchJob {
+	o := options{
+		tracerProvider: otel.GetTracerProvider(),
+		propagator:     otel.GetTextMapPropagator(),
+		metrics:        noopMetricsCollector{},
+		logger:         slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return &Relayer[Tx]{
 		batchSize:         batchSize,
 		registry:          registry,
 		unitOfWorkFactory: factory,
 		mutex:             sync.Mutex{},
+		tracer:            o.tracerProvider.Tracer(saga.TracerName),
+		propagator:        o.propagator,
+		metrics:           o.metrics,
+		logger:            o.logger,
 	}
 }
 
 func (r *Relayer[Tx]) Execute() error {
+	return r.ExecuteContext(context.Background())
+}
+
+// ExecuteContext relays a batch of messages like Execute, but stops publishing when ctx is done.
+// If ctx is canceled, the unit of work is not committed, so the messages of the batch stay in their repository
+// and are relayed again by the next run.
+func (r *Relayer[Tx]) ExecuteContext(ctx context.Context) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	if err := r.createUnitOfWork(context.Background()); err != nil {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	started := time.Now()
+	err := r.relay(ctx)
+	r.metrics.BatchRelayed(time.Since(started), err)
+
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to relay messages", saga.LogKeyError, err)
+	}
+
+	return err
+}
+
+// relay publishes a batch of messages, and commits the unit of work which removes them from their repositories.
+func (r *Relayer[Tx]) relay(ctx context.Context) error {
+	if err := r.createUnitOfWork(ctx); err != nil {
 		return err
 	}
 
-	err := r.relayAndSave()
+	err := r.relayAndSave(ctx)
 	if err != nil {
 		return err
 	}
@@ -61,6 +144,7 @@ func (r *Relayer[Tx]) createUnitOfWork(ctx context.Context) error {
 		return err
 	}
 
+	uow.SetLogger(r.logger)
 	r.unitOfWork = uow
 	return nil
 }
@@ -76,11 +160,11 @@ func (r *Relayer[Tx]) commitUnitOfWork() error {
 	return err
 }
 
-func (r *Relayer[Tx]) relayAndSave() error {
+func (r *Relayer[Tx]) relayAndSave(ctx context.Context) error {
 	remaining := &atomic.Int64{}
 	remaining.Store(int64(r.batchSize))
 
-	published, failed := r.publishFromOutbox(remaining)
+	published, failed := r.publishFromOutbox(ctx, remaining)
 	defer published.close()
 	defer failed.close()
 
@@ -133,7 +217,7 @@ func (r *Relayer[Tx]) relayAndSave() error {
 	published = nil
 	failed = nil
 
-	published, failed = r.publishFromDeadLetters(remaining)
+	published, failed = r.publishFromDeadLetters(ctx, remaining)
 	defer published.close()
 	defer failed.close()
 
@@ -167,7 +251,7 @@ func (r *Relayer[Tx]) relayAndSave() error {
 func (r *Relayer[Tx]) saveDeadLetters(name saga.ChannelName, messages <-chan saga.Message) error {
 	channel := r.registry.Find(name)
 	if channel == nil {
-		return errors.New("channel not found")
+		return saga.ErrChannelNotFound
 	}
 
 	repo := channel.Repository()
@@ -199,7 +283,7 @@ saveDeadLetters:
 func (r *Relayer[Tx]) deleteMessagesFromOutbox(name saga.ChannelName, messages <-chan saga.Message) error {
 	channel := r.registry.Find(name)
 	if channel == nil {
-		return errors.New("channel not found")
+		return saga.ErrChannelNotFound
 	}
 
 	repo := channel.Repository()
@@ -224,7 +308,7 @@ deleteMessages:
 func (r *Relayer[Tx]) deleteMessagesFromDeadLetters(name saga.ChannelName, messages <-chan saga.Message) error {
 	channel := r.registry.Find(name)
 	if channel == nil {
-		return errors.New("channel not found")
+		return saga.ErrChannelNotFound
 	}
 
 	repo := channel.Repository()
@@ -246,29 +330,41 @@ deleteDeadLetters:
 	return nil
 }
 
-func (r *Relayer[Tx]) publishFromOutbox(remaining *atomic.Int64) (published *messagesByChannel, failed *messagesByChannel) {
+func (r *Relayer[Tx]) publishFromOutbox(ctx context.Context, remaining *atomic.Int64) (published *messagesByChannel, failed *messagesByChannel) {
 	messageFunc := func(repo saga.AbstractMessageLoadRepository[saga.Message], batchSize int) ([]saga.Message, error) {
+		if repo, ok := repo.(saga.AbstractMessageLoadContextRepository[saga.Message]); ok {
+			return repo.GetMessagesFromOutboxContext(ctx, batchSize)
+		}
+
 		return repo.GetMessagesFromOutbox(batchSize)
 	}
 
-	published, failed = r.publish(remaining, messageFunc)
+	published, failed = r.publish(ctx, remaining, false, messageFunc)
 	return
 }
 
-func (r *Relayer[Tx]) publishFromDeadLetters(remaining *atomic.Int64) (published *messagesByChannel, failed *messagesByChannel) {
+func (r *Relayer[Tx]) publishFromDeadLetters(ctx context.Context, remaining *atomic.Int64) (published *messagesByChannel, failed *messagesByChannel) {
 	messageFunc := func(repo saga.AbstractMessageLoadRepository[saga.Message], batchSize int) ([]saga.Message, error) {
+		if repo, ok := repo.(saga.AbstractMessageLoadContextRepository[saga.Message]); ok {
+			return repo.GetMessagesFromDeadLetterContext(ctx, batchSize)
+		}
+
 		return repo.GetMessagesFromDeadLetter(batchSize)
 	}
 
-	published, failed = r.publish(remaining, messageFunc)
+	published, failed = r.publish(ctx, remaining, true, messageFunc)
 	return
 }
 
-func (r *Relayer[Tx]) publish(remaining *atomic.Int64, messageFunc func(repo saga.AbstractMessageLoadRepository[saga.Message], batchSize int) ([]saga.Message, error)) (published *messagesByChannel, failed *messagesByChannel) {
+func (r *Relayer[Tx]) publish(ctx context.Context, remaining *atomic.Int64, deadLetter bool, messageFunc func(repo saga.AbstractMessageLoadRepository[saga.Message], batchSize int) ([]saga.Message, error)) (published *messagesByChannel, failed *messagesByChannel) {
 	published = newMessagesByChannel(r.batchSize)
 	failed = newMessagesByChannel(r.batchSize)
 
 	r.registry.Range(func(name saga.ChannelName, channel Channel[Tx]) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+
 		batchSize := int(remaining.Load())
 		if batchSize <= 0 {
 			return false
@@ -277,6 +373,11 @@ func (r *Relayer[Tx]) publish(remaining *atomic.Int64, messageFunc func(repo sag
 		repo := channel.Repository()
 		messages, err := messageFunc(repo, batchSize)
 		if err != nil {
+			r.logger.ErrorContext(ctx, "failed to load messages",
+				saga.LogKeyChannel, string(name),
+				saga.LogKeyDeadLetter, deadLetter,
+				saga.LogKeyError, err,
+			)
 			return false
 		}
 
@@ -285,11 +386,30 @@ func (r *Relayer[Tx]) publish(remaining *atomic.Int64, messageFunc func(repo sag
 			wg.Add(1)
 			go func(message saga.Message) {
 				defer wg.Done()
-				err := channel.Send(message)
+				err := r.send(ctx, name, channel, message)
 				if err != nil {
+					if ctx.Err() != nil {
+						// Canceled before the message is published, so leave it in the outbox.
+						return
+					}
+					r.logger.WarnContext(ctx, "failed to publish message",
+						saga.LogKeyChannel, string(name),
+						saga.LogKeyMessageID, message.ID(),
+						saga.LogKeySessionID, message.SessionID(),
+						saga.LogKeyDeadLetter, deadLetter,
+						saga.LogKeyError, err,
+					)
 					failed.pushMessage(name, message)
+					r.metrics.MessageFailed(name, deadLetter)
 				} else {
+					r.logger.DebugContext(ctx, "message published",
+						saga.LogKeyChannel, string(name),
+						saga.LogKeyMessageID, message.ID(),
+						saga.LogKeySessionID, message.SessionID(),
+						saga.LogKeyDeadLetter, deadLetter,
+					)
 					published.pushMessage(name, message)
+					r.metrics.MessagePublished(name, deadLetter)
 				}
 
 				remaining.Add(-1)
@@ -304,6 +424,25 @@ func (r *Relayer[Tx]) publish(remaining *atomic.Int64, messageFunc func(repo sag
 	return
 }
 
+// send publishes the message in a span which continues the trace of the step the message was constructed for.
+// The channel receives the context of the span, so that it can propagate the span to the consumer of the message.
+func (r *Relayer[Tx]) send(ctx context.Context, name saga.ChannelName, channel Channel[Tx], message saga.Message) error {
+	ctx = saga.ExtractTraceContext(ctx, r.propagator, message)
+	ctx, span := r.tracer.Start(ctx, "publish "+string(name),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			saga.AttributeSessionID.String(message.SessionID()),
+			saga.AttributeMessageID.String(message.ID()),
+			saga.AttributeChannel.String(string(name)),
+		),
+	)
+
+	err := channel.SendContext(ctx, message)
+	saga.EndSpan(span, err)
+
+	return err
+}
+
 type messagesByChannel struct {
 	once              sync.Once
 	baseBatchSize     int
@@ -0,0 +1,69 @@
+package saga
+
+import (
+	"context"
+	"time"
+)
+
+// MetricsCollector receives the measurements of the sessions orchestrated by the registry it is listening to.
+// The number of running sessions of a saga is the number of started sessions minus the number of finished sessions.
+type MetricsCollector interface {
+	// SagaStarted counts a session of the saga which was started.
+	SagaStarted(sagaName string)
+
+	// SagaFinished counts a session of the saga which was completed or failed, and observes how long it ran.
+	// duration is zero if it is unknown.
+	SagaFinished(sagaName string, state State, duration time.Duration)
+
+	// StepFinished counts an invocation or compensation of the step which succeeded or failed,
+	// and observes how long it took. latency is zero if it is unknown.
+	StepFinished(sagaName string, step string, direction Direction, succeeded bool, latency time.Duration)
+
+	// StepRetried counts a retry of the invocation or compensation of the step.
+	StepRetried(sagaName string, step string)
+
+	// CompensationStarted counts a compensation of the step which was started.
+	CompensationStarted(sagaName string, step string)
+
+	// SagaNeedsIntervention counts a session of the saga which stopped until an operator acts on it.
+	SagaNeedsIntervention(sagaName string)
+
+	// SagaStateChanged counts a session of the saga whose state changed from one state to another.
+	SagaStateChanged(sagaName string, from State, to State)
+}
+
+// NewMetricsListener returns the listener which reports the events of the sessions to the collector.
+// Register it to the registry by AddAsyncListener, so that only committed units of work are measured.
+func NewMetricsListener(collector MetricsCollector) AsyncListener {
+	return AsyncListenerFunc(func(ctx context.Context, event Event) {
+		if event.Branch != "" {
+			// The branches of a parallel step are measured with the step.
+			return
+		}
+
+		switch event.Type {
+		case EventSagaStarted:
+			collector.SagaStarted(event.SagaName)
+		case EventSagaCompleted:
+			collector.SagaFinished(event.SagaName, StateCompleted, event.Elapsed)
+		case EventSagaFailed:
+			collector.SagaFinished(event.SagaName, StateFailed, event.Elapsed)
+		case EventStepSucceeded:
+			collector.StepFinished(event.SagaName, event.Step, DirectionForward, true, event.Elapsed)
+		case EventStepFailed:
+			collector.StepFinished(event.SagaName, event.Step, DirectionForward, false, event.Elapsed)
+		case EventCompensationFinished:
+			collector.StepFinished(event.SagaName, event.Step, DirectionBackward, true, event.Elapsed)
+		case EventCompensationFailed:
+			collector.StepFinished(event.SagaName, event.Step, DirectionBackward, false, event.Elapsed)
+		case EventStepRetried:
+			collector.StepRetried(event.SagaName, event.Step)
+		case EventCompensationStarted:
+			collector.CompensationStarted(event.SagaName, event.Step)
+		case EventSagaNeedsIntervention:
+			collector.SagaNeedsIntervention(event.SagaName)
+		case EventSagaStateChanged:
+			collector.SagaStateChanged(event.SagaName, event.PreviousState, event.State)
+		}
+	})
+}
@@ -2,27 +2,90 @@ package saga
 
 import (
 	"context"
+	"errors"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"time"
 )
 
 type Orchestrator[Tx TxContext] interface {
 	Orchestrate(saga Saga[Session, Tx], packet messagePacket) error
+	OrchestrateContext(ctx context.Context, saga Saga[Session, Tx], packet messagePacket) error
 	StartSaga(saga Saga[Session, Tx], sessionArgs map[string]interface{}) error
+	StartSagaContext(ctx context.Context, saga Saga[Session, Tx], sessionArgs map[string]interface{}) error
+
+	// ExpireSession handles the session as if a failure response had arrived,
+	// if the session is still pending and its deadline is before now.
+	ExpireSession(ctx context.Context, saga Saga[Session, Tx], sessionID string, now time.Time) error
+
+	// Intervene applies the action of an operator to the session, and records it in the history of the session.
+	Intervene(ctx context.Context, saga Saga[Session, Tx], sessionID string, intervention Intervention) error
 }
 
-func NewOrchestrator[Tx TxContext](uowFactory UnitOfWorkFactory[Tx]) Orchestrator[Tx] {
-	return &orchestrator[Tx]{
+func NewOrchestrator[Tx TxContext](uowFactory UnitOfWorkFactory[Tx], opts ...OrchestratorOption[Tx]) Orchestrator[Tx] {
+	options := defaultOrchestratorOptions[Tx]()
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	tracer, propagator := newTracing(options)
+
+	o := &orchestrator[Tx]{
 		uowFactory: uowFactory,
+		options:    options,
+		tracer:     tracer,
+		propagator: propagator,
+		logger:     options.logger,
+		inbox:      options.inbox,
 	}
+
+	if options.sessionLock {
+		o.locks = newSessionLocks()
+	}
+
+	return o
 }
 
 type orchestrator[Tx TxContext] struct {
 	uowFactory UnitOfWorkFactory[Tx]
+	registry   *Registry[Tx]
+	options    orchestratorOptions[Tx]
+	locks      *sessionLocks
+	inbox      InboxRepository[Tx]
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	logger     *slog.Logger
+}
+
+func (o *orchestrator[Tx]) bindRegistry(registry *Registry[Tx]) {
+	o.registry = registry
 }
 
 func (o *orchestrator[Tx]) StartSaga(saga Saga[Session, Tx], sessionArgs map[string]interface{}) error {
+	return o.StartSagaContext(context.Background(), saga, sessionArgs)
+}
+
+func (o *orchestrator[Tx]) StartSagaContext(ctx context.Context, saga Saga[Session, Tx], sessionArgs map[string]interface{}) error {
+	ctx, span := o.tracer.Start(ctx, "start "+saga.Name(), trace.WithAttributes(AttributeSagaName.String(saga.Name())))
+	err := o.startSaga(ctx, saga, sessionArgs)
+	EndSpan(span, err)
+
+	if err != nil {
+		o.logFailure(ctx, "failed to start saga", err, LogKeySaga, saga.Name())
+	}
+
+	return err
+}
+
+func (o *orchestrator[Tx]) startSaga(ctx context.Context, saga Saga[Session, Tx], sessionArgs map[string]interface{}) error {
 	var uow *UnitOfWork[Tx]
 	var err error
 
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
 	sagaSession := saga.createSession(sessionArgs)
 	if sagaSession == nil {
 		return ErrSessionCreationFailed
@@ -32,6 +95,8 @@ func (o *orchestrator[Tx]) StartSaga(saga Saga[Session, Tx], sessionArgs map[str
 		return ErrSessionIDEmpty
 	}
 
+	trace.SpanFromContext(ctx).SetAttributes(AttributeSessionID.String(sagaSession.ID()))
+
 	sagaDef := saga.Definition()
 
 	firstStep := sagaDef.FirstStep()
@@ -39,12 +104,7 @@ func (o *orchestrator[Tx]) StartSaga(saga Saga[Session, Tx], sessionArgs map[str
 		return ErrSagaHasNoSteps
 	}
 
-	err = sagaSession.UpdateCurrentStep(firstStep)
-	if err != nil {
-		return err
-	}
-
-	uow, err = o.uowFactory(context.Background())
+	uow, err = o.newUnitOfWork(ctx, saga.Name(), sagaSession.ID())
 	if err != nil {
 		return err
 	}
@@ -55,16 +115,11 @@ func (o *orchestrator[Tx]) StartSaga(saga Saga[Session, Tx], sessionArgs map[str
 		return err
 	}
 
-	if firstStep.IsInvocable() {
-		err = o.invokeStep(sagaSession, firstStep, uow)
-		if err != nil {
-			return err
-		}
-	} else {
-		err = o.stepForwardAndInvoke(sagaSession, firstStep, sagaDef, uow)
-		if err != nil {
-			return err
-		}
+	o.emit(sagaSession, EventSagaStarted, nil, "", sagaDef, uow)
+
+	err = o.enterStep(sagaSession, firstStep, sagaDef, uow)
+	if err != nil {
+		return err
 	}
 
 	err = uow.Commit()
@@ -73,15 +128,65 @@ func (o *orchestrator[Tx]) StartSaga(saga Saga[Session, Tx], sessionArgs map[str
 }
 
 func (o *orchestrator[Tx]) Orchestrate(saga Saga[Session, Tx], packet messagePacket) error {
+	return o.OrchestrateContext(context.Background(), saga, packet)
+}
+
+func (o *orchestrator[Tx]) OrchestrateContext(ctx context.Context, saga Saga[Session, Tx], packet messagePacket) error {
+	msg := packet.Payload()
+
+	// The span of the response continues the trace of the step which the response answers.
+	ctx = ExtractTraceContext(ctx, o.propagator, msg)
+	ctx, span := o.tracer.Start(ctx, "consume "+string(packet.Origin()),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			AttributeSagaName.String(saga.Name()),
+			AttributeSessionID.String(msg.SessionID()),
+			AttributeMessageID.String(msg.ID()),
+			AttributeChannel.String(string(packet.Origin())),
+		),
+	)
+
+	err := o.withSession(ctx, msg.SessionID(), func() error {
+		return o.orchestrate(ctx, saga, packet)
+	})
+	EndSpan(span, err)
+
+	if err != nil {
+		o.logFailure(ctx, "failed to orchestrate response", err,
+			LogKeySaga, saga.Name(),
+			LogKeySessionID, msg.SessionID(),
+			LogKeyMessageID, msg.ID(),
+			LogKeyChannel, string(packet.Origin()),
+		)
+	}
+
+	return err
+}
+
+func (o *orchestrator[Tx]) orchestrate(ctx context.Context, saga Saga[Session, Tx], packet messagePacket) error {
 	var uow *UnitOfWork[Tx]
 	var err error
 
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
 	origin := packet.Origin()
 	if origin == "" {
 		return ErrUnknownMessageOrigin
 	}
 
-	sagaSession, err := saga.Repository().Load(packet.Payload().SessionID())
+	uow, err = o.newUnitOfWork(ctx, saga.Name(), packet.Payload().SessionID())
+	if err != nil {
+		return err
+	}
+
+	err = o.receive(packet.Payload(), uow)
+	if err != nil {
+		return err
+	}
+
+	sagaSession, err := loadSession(ctx, saga.Repository(), packet.Payload().SessionID())
 	if err != nil {
 		return err
 	}
@@ -90,14 +195,21 @@ func (o *orchestrator[Tx]) Orchestrate(saga Saga[Session, Tx], packet messagePac
 		return ErrDeadSession
 	}
 
+	if sagaSession.State() == StateNeedsIntervention {
+		return ErrSessionNeedsIntervention
+	}
+
+	if isPaused(sagaSession) {
+		return ErrSessionPaused
+	}
+
 	currentStep := sagaSession.CurrentStep()
 	if saga.Definition().Exists(currentStep) == false {
 		return ErrSessionStepAndDefinitionMismatch
 	}
 
-	uow, err = o.uowFactory(context.Background())
-	if err != nil {
-		return err
+	if isStaleResponse(sagaSession, packet.Payload()) {
+		return ErrStaleResponse
 	}
 
 	if sagaSession.State() != StateIsCompensating &&
@@ -120,6 +232,12 @@ func (o *orchestrator[Tx]) Orchestrate(saga Saga[Session, Tx], packet messagePac
 		return err
 	}
 
+	release, err := o.resumeParent(sagaSession, uow)
+	defer release()
+	if err != nil {
+		return err
+	}
+
 	err = uow.Commit()
 	if err != nil {
 		return err
@@ -128,50 +246,255 @@ func (o *orchestrator[Tx]) Orchestrate(saga Saga[Session, Tx], packet messagePac
 	return nil
 }
 
-func (o *orchestrator[Tx]) invokeStep(session Session, curStep Step, uow *UnitOfWork[Tx]) error {
-	var cmd Executable[Tx]
+func (o *orchestrator[Tx]) ExpireSession(ctx context.Context, saga Saga[Session, Tx], sessionID string, now time.Time) error {
+	ctx, span := o.tracer.Start(ctx, "expire "+saga.Name(), trace.WithAttributes(
+		AttributeSagaName.String(saga.Name()),
+		AttributeSessionID.String(sessionID),
+	))
+
+	err := o.withSession(ctx, sessionID, func() error {
+		return o.expireSession(ctx, saga, sessionID, now)
+	})
+	EndSpan(span, err)
+
+	if err != nil {
+		o.logFailure(ctx, "failed to expire session", err, LogKeySaga, saga.Name(), LogKeySessionID, sessionID)
+	}
+
+	return err
+}
+
+func (o *orchestrator[Tx]) expireSession(ctx context.Context, saga Saga[Session, Tx], sessionID string, now time.Time) error {
+	var uow *UnitOfWork[Tx]
 	var err error
 
-	switch curStep.(type) {
-	case remoteStep[Tx]:
-		// Invoke the remote step.
-		cmd = curStep.(remoteStep[Tx]).invocation(session)
-	case localStep[Tx]:
-		// Invoke the local step.
-		cmd, err = curStep.(localStep[Tx]).invocation(session)
-		if err != nil {
-			return err
-		}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	sagaSession, err := loadSession(ctx, saga.Repository(), sessionID)
+	if err != nil {
+		return err
+	}
+
+	if sagaSession.State() == StateCompleted || sagaSession.State() == StateFailed {
+		return ErrDeadSession
+	}
+
+	if sagaSession.State() == StateNeedsIntervention || isPaused(sagaSession) {
+		return nil
+	}
+
+	deadlineSession, ok := sagaSession.(DeadlineSession)
+	if !ok {
+		return nil
+	}
+
+	deadline := deadlineSession.Deadline()
+	if deadline.IsZero() || deadline.After(now) {
+		// The response arrived or the step was invoked again after the session was found.
+		return nil
+	}
+
+	currentStep := sagaSession.CurrentStep()
+	if saga.Definition().Exists(currentStep) == false {
+		return ErrSessionStepAndDefinitionMismatch
+	}
+
+	uow, err = o.newUnitOfWork(ctx, saga.Name(), sessionID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case sagaSession.IsPending() && sagaSession.State() == StateIsCompensating:
+		// No response of the compensation arrived in time.
+		o.clearPending(sagaSession)
+		o.record(sagaSession, currentStep, "", DirectionBackward, OutcomeTimedOut, nil, saga.Definition(), uow)
+		err = o.handleCompensationResult(sagaSession, ErrStepTimeout, currentStep, saga.Definition(), uow)
+	case sagaSession.IsPending():
+		// No response of the invocation arrived in time.
+		o.clearPending(sagaSession)
+		o.record(sagaSession, currentStep, "", DirectionForward, OutcomeTimedOut, nil, saga.Definition(), uow)
+		err = o.handleInvocationResult(sagaSession, ErrStepTimeout, currentStep, saga.Definition(), uow)
+	case sagaSession.State() == StateIsCompensating:
+		// The delay of a scheduled compensation retry has passed.
+		err = o.compensateStep(sagaSession, currentStep, saga.Definition(), uow)
+	case sagaSession.State() == StateIsRetrying:
+		// The delay of a scheduled invocation retry has passed.
+		err = o.invokeStep(sagaSession, currentStep, saga.Definition(), uow)
 	default:
-		panic("unknown step type")
+		return nil
 	}
 
-	err = uow.AddWorkUnit(cmd)
 	if err != nil {
 		return err
 	}
 
+	saver := saga.Repository().Save(sagaSession)
+	err = uow.AddWorkUnit(saver)
+	if err != nil {
+		return err
+	}
+
+	release, err := o.resumeParent(sagaSession, uow)
+	defer release()
+	if err != nil {
+		return err
+	}
+
+	return uow.Commit()
+}
+
+// isStaleResponse returns true if the response carries a step or an attempt other than the current ones of the session.
+func isStaleResponse(session Session, msg Message) bool {
+	stepMessage, ok := msg.(StepMessage)
+	if !ok {
+		return false
+	}
+
+	if stepMessage.StepName() != "" && stepMessage.StepName() != session.CurrentStep().Name() {
+		return true
+	}
+
+	if attemptSession, ok := session.(AttemptSession); ok && stepMessage.Attempt() > 0 {
+		return stepMessage.Attempt() != attemptSession.Attempt()
+	}
+
+	return false
+}
+
+// markPending marks the session as waiting for a response of the step, and sets the deadline if the step has a timeout.
+func (o *orchestrator[Tx]) markPending(session Session, step Step) {
 	session.SetPending(true)
 
+	if deadlineSession, ok := session.(DeadlineSession); ok {
+		var deadline time.Time
+		if step.Timeout() > 0 {
+			deadline = time.Now().Add(step.Timeout())
+		}
+		deadlineSession.SetDeadline(deadline)
+	}
+}
+
+// clearPending marks the session as no longer waiting for a response.
+func (o *orchestrator[Tx]) clearPending(session Session) {
+	session.SetPending(false)
+
+	if deadlineSession, ok := session.(DeadlineSession); ok {
+		deadlineSession.SetDeadline(time.Time{})
+	}
+}
+
+// attempt returns how many times the current action of the session has been tried, or zero if it is unknown.
+func (o *orchestrator[Tx]) attempt(session Session) int {
+	if attemptSession, ok := session.(AttemptSession); ok {
+		return attemptSession.Attempt()
+	}
+
+	return 0
+}
+
+func (o *orchestrator[Tx]) setAttempt(session Session, attempt int) {
+	if attemptSession, ok := session.(AttemptSession); ok {
+		attemptSession.SetAttempt(attempt)
+	}
+}
+
+// scheduleRetry makes the session wait for delay before the current action is retried by TimeoutSweeper.
+// It returns false if the session cannot hold a deadline, so the retry must be executed immediately.
+func (o *orchestrator[Tx]) scheduleRetry(session Session, delay time.Duration) bool {
+	deadlineSession, ok := session.(DeadlineSession)
+	if !ok || delay <= 0 {
+		return false
+	}
+
+	session.SetPending(false)
+	deadlineSession.SetDeadline(time.Now().Add(delay))
+	return true
+}
+
+func (o *orchestrator[Tx]) invokeStep(session Session, curStep Step, def Definition, uow *UnitOfWork[Tx]) error {
+	if step, ok := curStep.(parallelStep[Tx]); ok {
+		return o.invokeParallelStep(session, step, def, uow)
+	}
+
+	if step, ok := curStep.(subSagaStep[Tx]); ok {
+		return o.invokeSubSagaStep(session, step, def, uow)
+	}
+
+	span, inject := o.startStepSpan("invoke", session, curStep, "", uow)
+	cmd, err := o.invocationCommand(session, curStep, inject)
+	EndSpan(span, err)
+	if err != nil {
+		return err
+	}
+
+	err = uow.AddWorkUnit(cmd)
+	if err != nil {
+		return err
+	}
+
+	o.record(session, curStep, "", DirectionForward, OutcomeInvoked, nil, def, uow)
+	o.markPending(session, curStep)
+
 	return nil
 }
 
+// invocationCommand returns the executable which invokes the remote or local step.
+// The messages constructed for the invocation are passed to inject before they are saved.
+func (o *orchestrator[Tx]) invocationCommand(session Session, step Step, inject messageInjector) (Executable[Tx], error) {
+	switch step.(type) {
+	case remoteStep[Tx]:
+		// Invoke the remote step.
+		return step.(remoteStep[Tx]).invocation(session, inject), nil
+	case localStep[Tx]:
+		// Invoke the local step.
+		return step.(localStep[Tx]).invocation(session, inject)
+	default:
+		panic("unknown step type")
+	}
+}
+
 func (o *orchestrator[Tx]) stepForwardAndInvoke(session Session, curStep Step, def Definition, uow *UnitOfWork[Tx]) error {
 	var err error
 
 	nextStep := def.NextStep(curStep)
 	if nextStep == nil {
-		session.SetState(StateCompleted)
+		o.setState(session, StateCompleted, def, uow)
+		o.emit(session, EventSagaCompleted, nil, "", def, uow)
 		return nil
 	}
 
-	err = session.UpdateCurrentStep(nextStep)
+	err = o.enterStep(session, nextStep, def, uow)
 	if err != nil {
 		return err
 	}
 
-	if nextStep.IsInvocable() {
-		err = o.invokeStep(session, nextStep, uow)
+	return nil
+}
+
+// enterStep makes step the current step of the session, and invokes it.
+// If the step is not invocable or its conditions are not met, the session steps forward again.
+func (o *orchestrator[Tx]) enterStep(session Session, step Step, def Definition, uow *UnitOfWork[Tx]) error {
+	err := session.UpdateCurrentStep(step)
+	if err != nil {
+		return err
+	}
+	o.setState(session, StateCommon, def, uow)
+	o.setAttempt(session, 1)
+
+	execute, err := evaluateConditions(session, step)
+	if err != nil {
+		return err
+	}
+
+	if !execute {
+		o.record(session, step, "", DirectionForward, OutcomeSkipped, nil, def, uow)
+	}
+
+	if execute && step.IsInvocable() {
+		err = o.invokeStep(session, step, def, uow)
 		if err != nil {
 			return err
 		}
@@ -179,7 +502,7 @@ func (o *orchestrator[Tx]) stepForwardAndInvoke(session Session, curStep Step, d
 		return nil
 	}
 
-	err = o.stepForwardAndInvoke(session, nextStep, def, uow)
+	err = o.stepForwardAndInvoke(session, step, def, uow)
 	if err != nil {
 		return err
 	}
@@ -188,22 +511,47 @@ func (o *orchestrator[Tx]) stepForwardAndInvoke(session Session, curStep Step, d
 }
 
 func (o *orchestrator[Tx]) stepBackwardAndCompensate(session Session, curStep Step, def Definition, uow *UnitOfWork[Tx]) error {
+	if historySession, ok := session.(HistorySession); ok {
+		return o.stepBackwardByHistory(session, historySession, def, uow)
+	}
+
+	return o.compensateFrom(session, def.PrevStep(curStep), def, uow)
+}
+
+// compensateFrom compensates step if it was executed and is compensable, or the steps before it otherwise.
+// The session is failed if step is nil.
+func (o *orchestrator[Tx]) compensateFrom(session Session, step Step, def Definition, uow *UnitOfWork[Tx]) error {
 	var err error
 
-	prevStep := def.PrevStep(curStep)
-	if prevStep == nil {
-		session.SetState(StateFailed)
+	if step == nil {
+		o.setState(session, StateFailed, def, uow)
+		o.emit(session, EventSagaFailed, nil, "", def, uow)
 		return nil
 	}
 
-	err = session.UpdateCurrentStep(prevStep)
+	err = session.UpdateCurrentStep(step)
 	if err != nil {
 		return err
 	}
 
-	if prevStep.IsCompensable() {
-		session.SetState(StateIsCompensating)
-		err = o.compensateStep(session, prevStep, uow)
+	if !wasExecuted(session, step) {
+		// The step was skipped by its conditions, so there is nothing to compensate.
+		return o.stepBackwardAndCompensate(session, step, def, uow)
+	}
+
+	if parallel, ok := step.(parallelStep[Tx]); ok && step.IsCompensable() {
+		compensating, err := o.compensateParallelStep(session, parallel, def, uow)
+		if err != nil || compensating {
+			return err
+		}
+
+		return o.stepBackwardAndCompensate(session, step, def, uow)
+	}
+
+	if step.IsCompensable() {
+		o.setState(session, StateIsCompensating, def, uow)
+		o.setAttempt(session, 1)
+		err = o.compensateStep(session, step, def, uow)
 		if err != nil {
 			return err
 		}
@@ -211,7 +559,7 @@ func (o *orchestrator[Tx]) stepBackwardAndCompensate(session Session, curStep St
 		return nil
 	}
 
-	err = o.stepBackwardAndCompensate(session, prevStep, def, uow)
+	err = o.stepBackwardAndCompensate(session, step, def, uow)
 	if err != nil {
 		return err
 	}
@@ -219,21 +567,94 @@ func (o *orchestrator[Tx]) stepBackwardAndCompensate(session Session, curStep St
 	return nil
 }
 
+// stepBackwardByHistory compensates the last step which the history records as succeeded and not compensated yet.
+func (o *orchestrator[Tx]) stepBackwardByHistory(session Session, historySession HistorySession, def Definition, uow *UnitOfWork[Tx]) error {
+	step := stepToCompensate(historySession, def)
+	if step == nil {
+		o.setState(session, StateFailed, def, uow)
+		o.emit(session, EventSagaFailed, nil, "", def, uow)
+		return nil
+	}
+
+	err := session.UpdateCurrentStep(step)
+	if err != nil {
+		return err
+	}
+
+	if parallel, ok := step.(parallelStep[Tx]); ok {
+		compensating, err := o.compensateParallelStep(session, parallel, def, uow)
+		if err != nil || compensating {
+			return err
+		}
+
+		// No branch was left to compensate.
+		o.record(session, step, "", DirectionBackward, OutcomeSkipped, nil, def, uow)
+		return o.stepBackwardByHistory(session, historySession, def, uow)
+	}
+
+	o.setState(session, StateIsCompensating, def, uow)
+	o.setAttempt(session, 1)
+	return o.compensateStep(session, step, def, uow)
+}
+
 func (o *orchestrator[Tx]) handleInvocationResponse(session Session, origin ChannelName, msg Message, curStep Step, def Definition, uow *UnitOfWork[Tx]) error {
-	session.SetPending(false)
+	if step, ok := curStep.(parallelStep[Tx]); ok {
+		return o.handleParallelInvocationResponse(session, origin, msg, step, def, uow)
+	}
+
+	if _, ok := curStep.(subSagaStep[Tx]); ok {
+		// A sub-saga step is resumed by its child session, not by a response.
+		return ErrUnknownMessageOrigin
+	}
+
+	o.clearPending(session)
 
 	isFailure, err := o.isFailureInvocationResponse(origin, curStep)
 	if err != nil {
 		return err
 	}
 
+	var cause error
 	if isFailure {
-		var err error
-		if curStep.MustBeCompleted() {
-			err = o.retryInvocation(session, curStep, uow)
+		cause = &FailureResponseError{Response: msg}
+		o.record(session, curStep, "", DirectionForward, OutcomeFailed, msg, def, uow)
+	} else {
+		o.record(session, curStep, "", DirectionForward, OutcomeSucceeded, msg, def, uow)
+	}
+
+	return o.handleInvocationResult(session, cause, curStep, def, uow)
+}
+
+// handleInvocationResult moves the session by the result of the invocation of curStep. cause is nil if the invocation succeeded.
+func (o *orchestrator[Tx]) handleInvocationResult(session Session, cause error, curStep Step, def Definition, uow *UnitOfWork[Tx]) error {
+	var err error
+
+	if step, ok := curStep.(parallelStep[Tx]); ok && cause != nil {
+		// Branches that have not responded yet are handled as failed.
+		return o.failPendingBranches(session, step, def, uow)
+	}
+
+	if step, ok := curStep.(subSagaStep[Tx]); ok && errors.Is(cause, ErrStepTimeout) {
+		// The session of the sub-saga keeps running after the step timed out.
+		err = o.cancelSubSaga(session, step, uow)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cause != nil {
+		policy := optionsOf(curStep).retryPolicy
+		if curStep.MustBeCompleted() && policy.isRetriable(cause) {
+			err = o.retryInvocation(session, curStep, def, uow)
 			return err
 		}
 
+		if def.isAfterPivot(session, curStep) {
+			// The saga cannot step backward over its pivot step, so the session waits for an operator.
+			o.setState(session, StateNeedsIntervention, def, uow)
+			return nil
+		}
+
 		err = o.stepBackwardAndCompensate(session, curStep, def, uow)
 		return err
 	}
@@ -242,13 +663,26 @@ func (o *orchestrator[Tx]) handleInvocationResponse(session Session, origin Chan
 	return err
 }
 
-func (o *orchestrator[Tx]) retryInvocation(session Session, step Step, uow *UnitOfWork[Tx]) error {
+func (o *orchestrator[Tx]) retryInvocation(session Session, step Step, def Definition, uow *UnitOfWork[Tx]) error {
 	if !step.MustBeCompleted() {
 		return ErrRetryCalledOnNonRetryingStep
 	}
 
-	session.SetState(StateIsRetrying)
-	err := o.invokeStep(session, step, uow)
+	policy := optionsOf(step).retryPolicy
+	attempt := o.attempt(session)
+	if policy.isExhausted(attempt) {
+		o.setState(session, StateNeedsIntervention, def, uow)
+		return nil
+	}
+
+	o.setState(session, StateIsRetrying, def, uow)
+	o.setAttempt(session, attempt+1)
+	o.emit(session, EventStepRetried, step, "", def, uow)
+	if o.scheduleRetry(session, policy.delay(attempt)) {
+		return nil
+	}
+
+	err := o.invokeStep(session, step, def, uow)
 	return err
 }
 
@@ -305,15 +739,38 @@ func (o *orchestrator[Tx]) isFailureCompensationResponse(origin ChannelName, ste
 }
 
 func (o *orchestrator[Tx]) handleCompensationResponse(session Session, origin ChannelName, msg Message, curStep Step, def Definition, uow *UnitOfWork[Tx]) error {
-	session.SetPending(false)
+	if step, ok := curStep.(parallelStep[Tx]); ok {
+		return o.handleParallelCompensationResponse(session, origin, msg, step, def, uow)
+	}
+
+	if _, ok := curStep.(subSagaStep[Tx]); ok {
+		return ErrUnknownMessageOrigin
+	}
+
+	o.clearPending(session)
 
 	isFailure, err := o.isFailureCompensationResponse(origin, curStep)
 	if err != nil {
 		return err
 	}
 
+	var cause error
 	if isFailure {
-		err = o.retryCompensation(session, curStep, uow)
+		cause = &FailureResponseError{Response: msg}
+		o.record(session, curStep, "", DirectionBackward, OutcomeFailed, msg, def, uow)
+	} else {
+		o.record(session, curStep, "", DirectionBackward, OutcomeSucceeded, msg, def, uow)
+	}
+
+	return o.handleCompensationResult(session, cause, curStep, def, uow)
+}
+
+// handleCompensationResult moves the session by the result of the compensation of curStep. cause is nil if the compensation succeeded.
+func (o *orchestrator[Tx]) handleCompensationResult(session Session, cause error, curStep Step, def Definition, uow *UnitOfWork[Tx]) error {
+	var err error
+
+	if cause != nil {
+		err = o.retryCompensation(session, curStep, cause, def, uow)
 		return err
 	}
 
@@ -321,27 +778,45 @@ func (o *orchestrator[Tx]) handleCompensationResponse(session Session, origin Ch
 	return err
 }
 
-func (o *orchestrator[Tx]) retryCompensation(session Session, step Step, uow *UnitOfWork[Tx]) error {
-	session.SetState(StateIsCompensating)
-	err := o.compensateStep(session, step, uow)
+func (o *orchestrator[Tx]) retryCompensation(session Session, step Step, cause error, def Definition, uow *UnitOfWork[Tx]) error {
+	return o.retryCompensationBy(session, step, "", optionsOf(step).compensationRetryPolicy, cause, def, uow)
+}
+
+// retryCompensationBy retries the compensation of step by policy. branch is the name of the branch of a parallel step
+// whose compensation failed, or empty if the step itself failed.
+func (o *orchestrator[Tx]) retryCompensationBy(session Session, step Step, branch string, policy RetryPolicy, cause error, def Definition, uow *UnitOfWork[Tx]) error {
+	attempt := o.attempt(session)
+	if !policy.isRetriable(cause) || policy.isExhausted(attempt) {
+		// A compensation cannot be skipped, so the session waits for an operator.
+		o.setState(session, StateNeedsIntervention, def, uow)
+		return nil
+	}
+
+	o.setState(session, StateIsCompensating, def, uow)
+	o.setAttempt(session, attempt+1)
+	o.emit(session, EventStepRetried, step, branch, def, uow)
+	if o.scheduleRetry(session, policy.delay(attempt)) {
+		return nil
+	}
+
+	err := o.compensateStep(session, step, def, uow)
 	return err
 }
 
-func (o *orchestrator[Tx]) compensateStep(session Session, step Step, uow *UnitOfWork[Tx]) error {
-	var cmd Executable[Tx]
-	var err error
+func (o *orchestrator[Tx]) compensateStep(session Session, step Step, def Definition, uow *UnitOfWork[Tx]) error {
+	if step, ok := step.(parallelStep[Tx]); ok {
+		return o.recompensateParallelStep(session, step, def, uow)
+	}
 
-	switch step.(type) {
-	case remoteStep[Tx]:
-		msg := step.(remoteStep[Tx]).compEndpoint.SuccessResponseConstructor()(session)
-		cmd = step.(remoteStep[Tx]).compEndpoint.CommandRepository().SaveMessage(msg)
-	case localStep[Tx]:
-		cmd, err = step.(localStep[Tx]).compEndpoint.handler(session)
-		if err != nil {
-			return err
-		}
-	default:
-		panic("unknown step type")
+	if step, ok := step.(subSagaStep[Tx]); ok {
+		return o.compensateSubSagaStep(session, step, def, uow)
+	}
+
+	span, inject := o.startStepSpan("compensate", session, step, "", uow)
+	cmd, err := o.compensationCommand(session, step, inject)
+	EndSpan(span, err)
+	if err != nil {
+		return err
 	}
 
 	err = uow.AddWorkUnit(cmd)
@@ -349,7 +824,23 @@ func (o *orchestrator[Tx]) compensateStep(session Session, step Step, uow *UnitO
 		return err
 	}
 
-	session.SetPending(true)
+	o.record(session, step, "", DirectionBackward, OutcomeInvoked, nil, def, uow)
+	o.markPending(session, step)
 
 	return nil
 }
+
+// compensationCommand returns the executable which compensates the remote or local step.
+// The messages constructed for the compensation are passed to inject before they are saved.
+func (o *orchestrator[Tx]) compensationCommand(session Session, step Step, inject messageInjector) (Executable[Tx], error) {
+	switch step.(type) {
+	case remoteStep[Tx]:
+		msg := step.(remoteStep[Tx]).compEndpoint.SuccessResponseConstructor()(session)
+		inject(msg)
+		return step.(remoteStep[Tx]).compEndpoint.CommandRepository().SaveMessage(msg), nil
+	case localStep[Tx]:
+		return step.(localStep[Tx]).compEndpoint.handler(session)
+	default:
+		panic("unknown step type")
+	}
+}
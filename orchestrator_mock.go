@@ -1,5 +1,10 @@
 package saga
 
+import (
+	"context"
+	"time"
+)
+
 type mockOrchestrator[Tx TxContext] struct {
 }
 
@@ -12,3 +17,23 @@ func (m *mockOrchestrator[Tx]) StartSaga(saga Saga[Session, Tx], sessionArgs map
 	//TODO implement me
 	panic("implement me")
 }
+
+func (m *mockOrchestrator[Tx]) OrchestrateContext(ctx context.Context, saga Saga[Session, Tx], packet messagePacket) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m *mockOrchestrator[Tx]) StartSagaContext(ctx context.Context, saga Saga[Session, Tx], sessionArgs map[string]interface{}) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m *mockOrchestrator[Tx]) ExpireSession(ctx context.Context, saga Saga[Session, Tx], sessionID string, now time.Time) error {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m *mockOrchestrator[Tx]) Intervene(ctx context.Context, saga Saga[Session, Tx], sessionID string, intervention Intervention) error {
+	//TODO implement me
+	panic("implement me")
+}
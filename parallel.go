@@ -0,0 +1,327 @@
+package saga
+
+type BranchState int
+
+const (
+	// BranchStatePending means the branch is invoked and waits for its response.
+	BranchStatePending BranchState = iota
+	BranchStateSucceeded
+	BranchStateFailed
+	// BranchStateCompensating means the branch succeeded, but another branch failed, so it waits for the response of its compensation.
+	BranchStateCompensating
+	BranchStateCompensated
+	// BranchStateTimedOut means the step timed out before the branch responded. The branch is handled as failed,
+	// but it is compensated in case its invocation succeeded after all.
+	BranchStateTimedOut
+)
+
+// ParallelSession is an optional interface that must be implemented by Session to run parallel steps.
+// It holds the state of each branch of the parallel steps the session has invoked.
+type ParallelSession interface {
+	// BranchState returns the state of the branch of the parallel step, or false if the branch has not been invoked.
+	BranchState(step string, branch string) (BranchState, bool)
+
+	// SetBranchState sets the state of the branch of the parallel step.
+	SetBranchState(step string, branch string, state BranchState)
+}
+
+func newParallelStep[Tx TxContext](name string, branches Definition, options stepOptions) parallelStep[Tx] {
+	return parallelStep[Tx]{
+		stepOptions: options,
+		name:        name,
+		branches:    branches.steps,
+	}
+}
+
+// parallelStep invokes every branch at once, and waits for the responses of all of them.
+// If any branch fails, the branches which succeeded are compensated before the saga steps backward.
+type parallelStep[Tx TxContext] struct {
+	stepOptions
+	name     string
+	branches []Step
+}
+
+func (s parallelStep[Tx]) Name() string {
+	return s.name
+}
+
+func (s parallelStep[Tx]) IsCompensable() bool {
+	for _, branch := range s.branches {
+		if branch.IsCompensable() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s parallelStep[Tx]) IsInvocable() bool {
+	return len(s.branches) > 0
+}
+
+// MustBeCompleted returns false, since the step is not retried as a whole.
+// A failed branch fails the step, even if the branch is retriable.
+func (s parallelStep[Tx]) MustBeCompleted() bool {
+	return false
+}
+
+// Branches returns the steps invoked in parallel.
+func (s parallelStep[Tx]) Branches() []Step {
+	return s.branches
+}
+
+func (o *orchestrator[Tx]) invokeParallelStep(session Session, step parallelStep[Tx], def Definition, uow *UnitOfWork[Tx]) error {
+	parallelSession, ok := session.(ParallelSession)
+	if !ok {
+		return ErrSessionNotParallel
+	}
+
+	o.record(session, step, "", DirectionForward, OutcomeInvoked, nil, def, uow)
+
+	for _, branch := range step.branches {
+		span, inject := o.startStepSpan("invoke", session, step, branch.Name(), uow)
+		cmd, err := o.invocationCommand(session, branch, inject)
+		EndSpan(span, err)
+		if err != nil {
+			return err
+		}
+
+		err = uow.AddWorkUnit(cmd)
+		if err != nil {
+			return err
+		}
+
+		parallelSession.SetBranchState(step.name, branch.Name(), BranchStatePending)
+		o.record(session, step, branch.Name(), DirectionForward, OutcomeInvoked, nil, def, uow)
+	}
+
+	o.markPending(session, step)
+
+	return nil
+}
+
+func (o *orchestrator[Tx]) handleParallelInvocationResponse(session Session, origin ChannelName, msg Message, step parallelStep[Tx], def Definition, uow *UnitOfWork[Tx]) error {
+	parallelSession, ok := session.(ParallelSession)
+	if !ok {
+		return ErrSessionNotParallel
+	}
+
+	for _, branch := range step.branches {
+		state, _ := parallelSession.BranchState(step.name, branch.Name())
+		if state != BranchStatePending {
+			continue
+		}
+
+		isFailure, err := o.isFailureInvocationResponse(origin, branch)
+		if err != nil {
+			continue
+		}
+
+		if isFailure {
+			parallelSession.SetBranchState(step.name, branch.Name(), BranchStateFailed)
+			o.record(session, step, branch.Name(), DirectionForward, OutcomeFailed, msg, def, uow)
+		} else {
+			parallelSession.SetBranchState(step.name, branch.Name(), BranchStateSucceeded)
+			o.record(session, step, branch.Name(), DirectionForward, OutcomeSucceeded, msg, def, uow)
+		}
+
+		return o.settleParallelStep(session, step, def, uow)
+	}
+
+	return ErrUnknownMessageOrigin
+}
+
+// failPendingBranches handles the branches which have not responded yet as timed out.
+func (o *orchestrator[Tx]) failPendingBranches(session Session, step parallelStep[Tx], def Definition, uow *UnitOfWork[Tx]) error {
+	err := o.timeOutPendingBranches(session, step, def, uow)
+	if err != nil {
+		return err
+	}
+
+	return o.settleParallelStep(session, step, def, uow)
+}
+
+// timeOutPendingBranches marks the branches which have not responded yet as timed out, so that they are compensated.
+func (o *orchestrator[Tx]) timeOutPendingBranches(session Session, step parallelStep[Tx], def Definition, uow *UnitOfWork[Tx]) error {
+	parallelSession, ok := session.(ParallelSession)
+	if !ok {
+		return ErrSessionNotParallel
+	}
+
+	for _, branch := range step.branches {
+		state, _ := parallelSession.BranchState(step.name, branch.Name())
+		if state == BranchStatePending {
+			parallelSession.SetBranchState(step.name, branch.Name(), BranchStateTimedOut)
+			o.record(session, step, branch.Name(), DirectionForward, OutcomeTimedOut, nil, def, uow)
+		}
+	}
+
+	return nil
+}
+
+// settleParallelStep moves the session once every branch of the step has responded.
+// The saga steps forward if all branches succeeded, and compensates the succeeded branches otherwise.
+func (o *orchestrator[Tx]) settleParallelStep(session Session, step parallelStep[Tx], def Definition, uow *UnitOfWork[Tx]) error {
+	parallelSession := session.(ParallelSession)
+
+	failed := false
+	for _, branch := range step.branches {
+		state, _ := parallelSession.BranchState(step.name, branch.Name())
+		switch state {
+		case BranchStatePending:
+			// Keep waiting for the other responses.
+			return nil
+		case BranchStateFailed, BranchStateTimedOut:
+			failed = true
+		}
+	}
+
+	o.clearPending(session)
+
+	if !failed {
+		o.record(session, step, "", DirectionForward, OutcomeSucceeded, nil, def, uow)
+		return o.stepForwardAndInvoke(session, step, def, uow)
+	}
+
+	o.record(session, step, "", DirectionForward, OutcomeFailed, nil, def, uow)
+
+	compensating, err := o.compensateParallelStep(session, step, def, uow)
+	if err != nil || compensating {
+		return err
+	}
+
+	return o.stepBackwardAndCompensate(session, step, def, uow)
+}
+
+// compensateParallelStep compensates every branch of the step which succeeded or timed out, and is compensable.
+// It returns false if there is no branch to compensate.
+func (o *orchestrator[Tx]) compensateParallelStep(session Session, step parallelStep[Tx], def Definition, uow *UnitOfWork[Tx]) (bool, error) {
+	parallelSession, ok := session.(ParallelSession)
+	if !ok {
+		return false, ErrSessionNotParallel
+	}
+
+	var branches []Step
+	for _, branch := range step.branches {
+		state, _ := parallelSession.BranchState(step.name, branch.Name())
+		if (state == BranchStateSucceeded || state == BranchStateTimedOut) && branch.IsCompensable() {
+			branches = append(branches, branch)
+		}
+	}
+
+	if len(branches) == 0 {
+		return false, nil
+	}
+
+	// The session is compensating the step before the commands are constructed, so that they carry its attempt.
+	err := session.UpdateCurrentStep(step)
+	if err != nil {
+		return false, err
+	}
+
+	o.setState(session, StateIsCompensating, def, uow)
+	o.setAttempt(session, 1)
+	o.record(session, step, "", DirectionBackward, OutcomeInvoked, nil, def, uow)
+
+	for _, branch := range branches {
+		span, inject := o.startStepSpan("compensate", session, step, branch.Name(), uow)
+		cmd, err := o.compensationCommand(session, branch, inject)
+		EndSpan(span, err)
+		if err != nil {
+			return false, err
+		}
+
+		err = uow.AddWorkUnit(cmd)
+		if err != nil {
+			return false, err
+		}
+
+		parallelSession.SetBranchState(step.name, branch.Name(), BranchStateCompensating)
+		o.record(session, step, branch.Name(), DirectionBackward, OutcomeInvoked, nil, def, uow)
+	}
+
+	o.markPending(session, step)
+
+	return true, nil
+}
+
+// recompensateParallelStep compensates again the branches of the step which are still compensating.
+func (o *orchestrator[Tx]) recompensateParallelStep(session Session, step parallelStep[Tx], def Definition, uow *UnitOfWork[Tx]) error {
+	parallelSession, ok := session.(ParallelSession)
+	if !ok {
+		return ErrSessionNotParallel
+	}
+
+	o.record(session, step, "", DirectionBackward, OutcomeInvoked, nil, def, uow)
+
+	for _, branch := range step.branches {
+		state, _ := parallelSession.BranchState(step.name, branch.Name())
+		if state != BranchStateCompensating {
+			continue
+		}
+
+		span, inject := o.startStepSpan("compensate", session, step, branch.Name(), uow)
+		cmd, err := o.compensationCommand(session, branch, inject)
+		EndSpan(span, err)
+		if err != nil {
+			return err
+		}
+
+		err = uow.AddWorkUnit(cmd)
+		if err != nil {
+			return err
+		}
+
+		o.record(session, step, branch.Name(), DirectionBackward, OutcomeInvoked, nil, def, uow)
+	}
+
+	o.markPending(session, step)
+
+	return nil
+}
+
+func (o *orchestrator[Tx]) handleParallelCompensationResponse(session Session, origin ChannelName, msg Message, step parallelStep[Tx], def Definition, uow *UnitOfWork[Tx]) error {
+	parallelSession, ok := session.(ParallelSession)
+	if !ok {
+		return ErrSessionNotParallel
+	}
+
+	for _, branch := range step.branches {
+		state, _ := parallelSession.BranchState(step.name, branch.Name())
+		if state != BranchStateCompensating {
+			continue
+		}
+
+		isFailure, err := o.isFailureCompensationResponse(origin, branch)
+		if err != nil {
+			continue
+		}
+
+		if isFailure {
+			// The branch stays compensating. Retrying advances the attempt of the session, so the compensations of
+			// the other branches which are still compensating are sent again and their earlier responses are stale.
+			o.record(session, step, branch.Name(), DirectionBackward, OutcomeFailed, msg, def, uow)
+			o.clearPending(session)
+
+			policy := optionsOf(branch).compensationRetryPolicy
+			return o.retryCompensationBy(session, step, branch.Name(), policy, &FailureResponseError{Response: msg}, def, uow)
+		}
+
+		parallelSession.SetBranchState(step.name, branch.Name(), BranchStateCompensated)
+		o.record(session, step, branch.Name(), DirectionBackward, OutcomeSucceeded, msg, def, uow)
+
+		for _, other := range step.branches {
+			state, _ := parallelSession.BranchState(step.name, other.Name())
+			if state == BranchStateCompensating {
+				// Keep waiting for the other compensations.
+				return nil
+			}
+		}
+
+		o.clearPending(session)
+		o.record(session, step, "", DirectionBackward, OutcomeSucceeded, nil, def, uow)
+		return o.stepBackwardAndCompensate(session, step, def, uow)
+	}
+
+	return ErrUnknownMessageOrigin
+}
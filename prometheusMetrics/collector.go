@@ -0,0 +1,233 @@
+package prometheusMetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/violetpay-org/go-saga"
+	"github.com/violetpay-org/go-saga/messageRelayer"
+	"time"
+)
+
+var (
+	_ saga.MetricsCollector           = (*Collector)(nil)
+	_ messageRelayer.MetricsCollector = (*Collector)(nil)
+	_ prometheus.Collector            = (*Collector)(nil)
+)
+
+// Collector exposes the measurements of the orchestrator and the relayer as Prometheus metrics.
+// Register it to a Prometheus registry, to the registry of sagas by saga.NewMetricsListener,
+// and to the relayer by messageRelayer.WithMetrics.
+type Collector struct {
+	sagasStarted      *prometheus.CounterVec
+	sagasFinished     *prometheus.CounterVec
+	sagaDuration      *prometheus.HistogramVec
+	stepsFinished     *prometheus.CounterVec
+	stepLatency       *prometheus.HistogramVec
+	stepRetries       *prometheus.CounterVec
+	compensations     *prometheus.CounterVec
+	interventions     *prometheus.CounterVec
+	stateTransitions  *prometheus.CounterVec
+	messagesPublished *prometheus.CounterVec
+	messagesFailed    *prometheus.CounterVec
+	batchDuration     *prometheus.HistogramVec
+}
+
+// New returns a collector whose metrics are prefixed with the namespace.
+func New(namespace string) *Collector {
+	return &Collector{
+		sagasStarted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "saga",
+			Name:      "sessions_started_total",
+			Help:      "Number of sessions started.",
+		}, []string{"saga"}),
+		sagasFinished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "saga",
+			Name:      "sessions_finished_total",
+			Help:      "Number of sessions completed or failed.",
+		}, []string{"saga", "state"}),
+		sagaDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "saga",
+			Name:      "session_duration_seconds",
+			Help:      "How long sessions ran until they were completed or failed.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 10),
+		}, []string{"saga", "state"}),
+		stepsFinished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "saga",
+			Name:      "steps_finished_total",
+			Help:      "Number of invocations and compensations of steps which succeeded or failed.",
+		}, []string{"saga", "step", "direction", "result"}),
+		stepLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "saga",
+			Name:      "step_latency_seconds",
+			Help:      "How long invocations and compensations of steps took until they were answered.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"saga", "step", "direction"}),
+		stepRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "saga",
+			Name:      "step_retries_total",
+			Help:      "Number of retries of invocations and compensations of steps.",
+		}, []string{"saga", "step"}),
+		compensations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "saga",
+			Name:      "compensations_started_total",
+			Help:      "Number of compensations of steps started.",
+		}, []string{"saga", "step"}),
+		interventions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "saga",
+			Name:      "sessions_needing_intervention_total",
+			Help:      "Number of sessions which stopped until an operator acts on them.",
+		}, []string{"saga"}),
+		stateTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "saga",
+			Name:      "session_state_transitions_total",
+			Help:      "Number of changes of the states of sessions.",
+		}, []string{"saga", "from", "to"}),
+		messagesPublished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "relayer",
+			Name:      "messages_published_total",
+			Help:      "Number of messages published.",
+		}, []string{"channel", "source"}),
+		messagesFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "relayer",
+			Name:      "messages_failed_total",
+			Help:      "Number of messages which failed to be published.",
+		}, []string{"channel", "source"}),
+		batchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "relayer",
+			Name:      "batch_duration_seconds",
+			Help:      "How long batches of the relayer took.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+	}
+}
+
+func (c *Collector) SagaStarted(sagaName string) {
+	c.sagasStarted.WithLabelValues(sagaName).Inc()
+}
+
+func (c *Collector) SagaFinished(sagaName string, state saga.State, duration time.Duration) {
+	c.sagasFinished.WithLabelValues(sagaName, stateLabel(state)).Inc()
+	if duration > 0 {
+		c.sagaDuration.WithLabelValues(sagaName, stateLabel(state)).Observe(duration.Seconds())
+	}
+}
+
+func (c *Collector) StepFinished(sagaName string, step string, direction saga.Direction, succeeded bool, latency time.Duration) {
+	result := "succeeded"
+	if !succeeded {
+		result = "failed"
+	}
+
+	c.stepsFinished.WithLabelValues(sagaName, step, directionLabel(direction), result).Inc()
+	if latency > 0 {
+		c.stepLatency.WithLabelValues(sagaName, step, directionLabel(direction)).Observe(latency.Seconds())
+	}
+}
+
+func (c *Collector) StepRetried(sagaName string, step string) {
+	c.stepRetries.WithLabelValues(sagaName, step).Inc()
+}
+
+func (c *Collector) CompensationStarted(sagaName string, step string) {
+	c.compensations.WithLabelValues(sagaName, step).Inc()
+}
+
+func (c *Collector) SagaNeedsIntervention(sagaName string) {
+	c.interventions.WithLabelValues(sagaName).Inc()
+}
+
+func (c *Collector) SagaStateChanged(sagaName string, from saga.State, to saga.State) {
+	c.stateTransitions.WithLabelValues(sagaName, stateLabel(from), stateLabel(to)).Inc()
+}
+
+func (c *Collector) MessagePublished(channel saga.ChannelName, deadLetter bool) {
+	c.messagesPublished.WithLabelValues(string(channel), sourceLabel(deadLetter)).Inc()
+}
+
+func (c *Collector) MessageFailed(channel saga.ChannelName, deadLetter bool) {
+	c.messagesFailed.WithLabelValues(string(channel), sourceLabel(deadLetter)).Inc()
+}
+
+func (c *Collector) BatchRelayed(duration time.Duration, err error) {
+	result := "succeeded"
+	if err != nil {
+		result = "failed"
+	}
+
+	c.batchDuration.WithLabelValues(result).Observe(duration.Seconds())
+}
+
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.sagasStarted,
+		c.sagasFinished,
+		c.sagaDuration,
+		c.stepsFinished,
+		c.stepLatency,
+		c.stepRetries,
+		c.compensations,
+		c.interventions,
+		c.stateTransitions,
+		c.messagesPublished,
+		c.messagesFailed,
+		c.batchDuration,
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+func stateLabel(state saga.State) string {
+	switch state {
+	case saga.StateCommon:
+		return "common"
+	case saga.StateCompleted:
+		return "completed"
+	case saga.StateFailed:
+		return "failed"
+	case saga.StateIsCompensating:
+		return "compensating"
+	case saga.StateIsRetrying:
+		return "retrying"
+	case saga.StateNeedsIntervention:
+		return "needs_intervention"
+	}
+
+	return "unknown"
+}
+
+func directionLabel(direction saga.Direction) string {
+	if direction == saga.DirectionBackward {
+		return "backward"
+	}
+
+	return "forward"
+}
+
+func sourceLabel(deadLetter bool) string {
+	if deadLetter {
+		return "dead_letter"
+	}
+
+	return "outbox"
+}
@@ -0,0 +1,111 @@
+package prometheusMetrics
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/violetpay-org/go-saga"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollector(t *testing.T) {
+	t.Run("should count sessions, steps and messages by their labels", func(t *testing.T) {
+		c := New("test")
+
+		c.SagaStarted("order")
+		c.SagaStarted("order")
+		c.SagaFinished("order", saga.StateCompleted, 0)
+		c.SagaFinished("order", saga.StateFailed, 0)
+		c.StepFinished("order", "pay", saga.DirectionForward, true, 0)
+		c.StepFinished("order", "pay", saga.DirectionBackward, false, 0)
+		c.StepRetried("order", "pay")
+		c.CompensationStarted("order", "pay")
+		c.SagaNeedsIntervention("order")
+		c.SagaStateChanged("order", saga.StateCommon, saga.StateIsRetrying)
+		c.SagaStateChanged("order", saga.StateIsRetrying, saga.StateNeedsIntervention)
+		c.SagaStateChanged("order", saga.StateNeedsIntervention, saga.StateIsCompensating)
+		c.SagaStateChanged("order", saga.StateIsCompensating, saga.StateFailed)
+		c.MessagePublished("payments", false)
+		c.MessagePublished("payments", true)
+		c.MessageFailed("payments", false)
+
+		expected := `
+# HELP test_saga_sessions_started_total Number of sessions started.
+# TYPE test_saga_sessions_started_total counter
+test_saga_sessions_started_total{saga="order"} 2
+# HELP test_saga_sessions_finished_total Number of sessions completed or failed.
+# TYPE test_saga_sessions_finished_total counter
+test_saga_sessions_finished_total{saga="order",state="completed"} 1
+test_saga_sessions_finished_total{saga="order",state="failed"} 1
+# HELP test_saga_steps_finished_total Number of invocations and compensations of steps which succeeded or failed.
+# TYPE test_saga_steps_finished_total counter
+test_saga_steps_finished_total{direction="backward",result="failed",saga="order",step="pay"} 1
+test_saga_steps_finished_total{direction="forward",result="succeeded",saga="order",step="pay"} 1
+# HELP test_saga_step_retries_total Number of retries of invocations and compensations of steps.
+# TYPE test_saga_step_retries_total counter
+test_saga_step_retries_total{saga="order",step="pay"} 1
+# HELP test_saga_compensations_started_total Number of compensations of steps started.
+# TYPE test_saga_compensations_started_total counter
+test_saga_compensations_started_total{saga="order",step="pay"} 1
+# HELP test_saga_sessions_needing_intervention_total Number of sessions which stopped until an operator acts on them.
+# TYPE test_saga_sessions_needing_intervention_total counter
+test_saga_sessions_needing_intervention_total{saga="order"} 1
+# HELP test_saga_session_state_transitions_total Number of changes of the states of sessions.
+# TYPE test_saga_session_state_transitions_total counter
+test_saga_session_state_transitions_total{from="common",saga="order",to="retrying"} 1
+test_saga_session_state_transitions_total{from="compensating",saga="order",to="failed"} 1
+test_saga_session_state_transitions_total{from="needs_intervention",saga="order",to="compensating"} 1
+test_saga_session_state_transitions_total{from="retrying",saga="order",to="needs_intervention"} 1
+# HELP test_relayer_messages_published_total Number of messages published.
+# TYPE test_relayer_messages_published_total counter
+test_relayer_messages_published_total{channel="payments",source="dead_letter"} 1
+test_relayer_messages_published_total{channel="payments",source="outbox"} 1
+# HELP test_relayer_messages_failed_total Number of messages which failed to be published.
+# TYPE test_relayer_messages_failed_total counter
+test_relayer_messages_failed_total{channel="payments",source="outbox"} 1
+`
+
+		err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+			"test_saga_sessions_started_total",
+			"test_saga_sessions_finished_total",
+			"test_saga_steps_finished_total",
+			"test_saga_step_retries_total",
+			"test_saga_compensations_started_total",
+			"test_saga_sessions_needing_intervention_total",
+			"test_saga_session_state_transitions_total",
+			"test_relayer_messages_published_total",
+			"test_relayer_messages_failed_total",
+		)
+		assert.Nil(t, err)
+	})
+
+	t.Run("should observe durations only when they are known", func(t *testing.T) {
+		c := New("test")
+
+		c.SagaFinished("order", saga.StateCompleted, 0)
+		c.StepFinished("order", "pay", saga.DirectionForward, true, 0)
+		assert.Equal(t, 0, testutil.CollectAndCount(c, "test_saga_session_duration_seconds", "test_saga_step_latency_seconds"))
+
+		c.SagaFinished("order", saga.StateCompleted, time.Second)
+		c.StepFinished("order", "pay", saga.DirectionForward, true, time.Second)
+		c.BatchRelayed(time.Second, nil)
+		c.BatchRelayed(time.Second, errors.New("failed"))
+		assert.Equal(t, 1, testutil.CollectAndCount(c, "test_saga_session_duration_seconds"))
+		assert.Equal(t, 1, testutil.CollectAndCount(c, "test_saga_step_latency_seconds"))
+		assert.Equal(t, 2, testutil.CollectAndCount(c, "test_relayer_batch_duration_seconds"))
+	})
+
+	t.Run("should be registered to a Prometheus registry", func(t *testing.T) {
+		registry := prometheus.NewPedanticRegistry()
+		assert.Nil(t, registry.Register(New("test")))
+
+		c := New("test")
+		c.SagaStarted("order")
+		problems, err := testutil.CollectAndLint(c)
+		assert.Nil(t, err)
+		assert.Empty(t, problems)
+	})
+}
@@ -0,0 +1,144 @@
+package saga
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// SessionQuery filters the sessions listed by SessionQueryRepository. The zero value lists every session.
+type SessionQuery struct {
+	// SagaName is the name of the saga of the sessions to list. Only the repositories shared by several sagas
+	// must filter by it. Registry.QuerySessions sets it to the name of the queried saga.
+	SagaName string
+
+	// States are the states of the sessions to list. Sessions in any state are listed if it is empty.
+	States []State
+
+	// Pending filters the sessions by whether they are waiting for a response, if it is not nil.
+	Pending *bool
+
+	// CurrentStep is the name of the current step of the sessions to list, or empty to list sessions at any step.
+	CurrentStep string
+
+	// UpdatedBefore lists only the sessions saved last before it, if it is not zero.
+	// Sessions which do not implement TimestampedSession are not listed then.
+	UpdatedBefore time.Time
+
+	// DeadlineBefore lists only the sessions whose deadline is not zero and before it, if it is not zero.
+	DeadlineBefore time.Time
+
+	// After is the ID of the last session of the previous page. Sessions are ordered by their IDs,
+	// so the next page lists the sessions whose IDs are greater than After.
+	After string
+
+	// Limit is the maximum number of sessions to list. Zero means no limit.
+	Limit int
+}
+
+// SessionQueryRepository is an optional interface that can be implemented by SessionRepository
+// to let operators, dashboards and TimeoutSweeper list the sessions of a saga.
+type SessionQueryRepository[S Session] interface {
+	// QuerySessions returns at most query.Limit sessions matching the query, ordered by their IDs.
+	QuerySessions(ctx context.Context, query SessionQuery) ([]S, error)
+}
+
+// SagaSession is an optional interface that can be implemented by Session to keep the name of its saga,
+// which SessionFactory receives as "sagaName". It lets repositories shared by several sagas filter by SagaName.
+type SagaSession interface {
+	// SagaName returns the name of the saga the session belongs to.
+	SagaName() string
+}
+
+// TimestampedSession is an optional interface that can be implemented by Session to keep when it was saved last,
+// which is required to query sessions by UpdatedBefore. SessionRepository.Save sets it.
+type TimestampedSession interface {
+	// UpdatedAt returns when the session was saved last.
+	UpdatedAt() time.Time
+
+	// SetUpdatedAt sets when the session was saved last.
+	SetUpdatedAt(updatedAt time.Time)
+}
+
+// Matches returns true if the session matches the filters of the query. It ignores After and Limit.
+func (q SessionQuery) Matches(session Session) bool {
+	if sagaSession, ok := session.(SagaSession); ok && q.SagaName != "" && sagaSession.SagaName() != q.SagaName {
+		return false
+	}
+
+	if len(q.States) > 0 && !containsState(q.States, session.State()) {
+		return false
+	}
+
+	if q.Pending != nil && session.IsPending() != *q.Pending {
+		return false
+	}
+
+	if q.CurrentStep != "" && (session.CurrentStep() == nil || session.CurrentStep().Name() != q.CurrentStep) {
+		return false
+	}
+
+	if !q.UpdatedBefore.IsZero() {
+		timestamped, ok := session.(TimestampedSession)
+		if !ok || !timestamped.UpdatedAt().Before(q.UpdatedBefore) {
+			return false
+		}
+	}
+
+	if !q.DeadlineBefore.IsZero() {
+		deadlineSession, ok := session.(DeadlineSession)
+		if !ok || deadlineSession.Deadline().IsZero() || !deadlineSession.Deadline().Before(q.DeadlineBefore) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterSessions returns the page of the sessions matching the query, ordered by their IDs.
+// It lets repositories which hold their sessions in memory implement SessionQueryRepository.
+func FilterSessions[S Session](sessions []S, query SessionQuery) []S {
+	var filtered []S
+	for _, session := range sessions {
+		if session.ID() > query.After && query.Matches(session) {
+			filtered = append(filtered, session)
+		}
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return filtered[i].ID() < filtered[j].ID()
+	})
+
+	if query.Limit > 0 && len(filtered) > query.Limit {
+		filtered = filtered[:query.Limit]
+	}
+
+	return filtered
+}
+
+func containsState(states []State, state State) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+// QuerySessions lists the sessions of the saga registered with the given name.
+// It fails with ErrSessionQueryNotSupported if the repository of the saga does not implement SessionQueryRepository.
+func (r *Registry[Tx]) QuerySessions(ctx context.Context, sagaName string, query SessionQuery) ([]Session, error) {
+	s, ok := r.findSaga(sagaName)
+	if !ok {
+		return nil, ErrSagaNotFound
+	}
+
+	repository, ok := s.Repository().(SessionQueryRepository[Session])
+	if !ok {
+		return nil, ErrSessionQueryNotSupported
+	}
+
+	query.SagaName = sagaName
+	return repository.QuerySessions(ctx, query)
+}
@@ -1,15 +1,37 @@
 package saga
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
 	"sync"
+	"time"
 )
 
 func NewRegistry[Tx TxContext](orchestrator Orchestrator[Tx]) *Registry[Tx] {
-	return &Registry[Tx]{
-		sagas:        make([]Saga[Session, Tx], 0),
+	r := &Registry[Tx]{
+		sagas:        make(map[string]Saga[Session, Tx]),
 		mutex:        sync.Mutex{},
 		orchestrator: orchestrator,
+		logger:       slog.Default(),
 	}
+
+	if o, ok := orchestrator.(loggerHolder); ok {
+		r.logger = o.loggerOf()
+	}
+
+	if o, ok := orchestrator.(registryBinder[Tx]); ok {
+		o.bindRegistry(r)
+	}
+
+	return r
+}
+
+// registryBinder is implemented by the orchestrators which need the registry they are used by,
+// to find the sagas started by sub-saga steps.
+type registryBinder[Tx TxContext] interface {
+	bindRegistry(registry *Registry[Tx])
 }
 
 func RegisterSagaTo[S Session, Tx TxContext](r *Registry[Tx], s Saga[S, Tx]) error {
@@ -17,53 +39,67 @@ func RegisterSagaTo[S Session, Tx TxContext](r *Registry[Tx], s Saga[S, Tx]) err
 		return ErrRegisterInvalidSaga
 	}
 
-	if r.HasSaga(s.Name()) {
-		return ErrRegisterInvalidSaga
+	if err := s.Definition().Validate(); err != nil {
+		return err
 	}
 
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	r.sagas = append(r.sagas, convertSaga(s))
+	if _, ok := r.sagas[s.Name()]; ok {
+		return ErrRegisterInvalidSaga
+	}
+
+	r.sagas[s.Name()] = convertSaga(s)
 
 	return nil
 }
 
 type Registry[Tx TxContext] struct {
-	sagas        []Saga[Session, Tx]
+	// sagas indexes the registered sagas by their names.
+	sagas        map[string]Saga[Session, Tx]
 	mutex        sync.Mutex
 	orchestrator Orchestrator[Tx]
-}
 
-func (r *Registry[Tx]) consumeMessage(packet messagePacket) error {
-	sessID := packet.Payload().SessionID()
+	listeners      []Listener[Tx]
+	asyncListeners []AsyncListener
 
-	type Orchestrations func() error
-	orchestrations := make([]Orchestrations, 0)
+	logger *slog.Logger
+}
 
-	func() {
-		r.mutex.Lock()
-		defer r.mutex.Unlock()
-		for _, s := range r.sagas {
-			if s.hasPublishedSaga(sessID) {
-				f := func() error {
-					return r.orchestrator.Orchestrate(s, packet)
-				}
-				orchestrations = append(orchestrations, f)
-			}
-		}
-	}()
+func (r *Registry[Tx]) consumeMessage(ctx context.Context, packet messagePacket) error {
+	s, ok := r.route(packet.Payload())
+	if !ok {
+		r.log().DebugContext(ctx, "message is not of any saga",
+			LogKeyMessageID, packet.Payload().ID(),
+			LogKeySessionID, packet.Payload().SessionID(),
+			LogKeyChannel, string(packet.Origin()),
+		)
+		return nil
+	}
 
-	for _, f := range orchestrations {
-		if err := f(); err != nil {
-			return err
-		}
+	return r.orchestrator.OrchestrateContext(ctx, s, packet)
+}
+
+// route returns the saga which the message belongs to, or false if no saga of the registry published its session.
+// A message carrying a saga name by RoutedMessage is routed by the name,
+// and the others by the saga name their session IDs are prefixed with.
+func (r *Registry[Tx]) route(msg Message) (Saga[Session, Tx], bool) {
+	if routed, ok := msg.(RoutedMessage); ok && routed.SagaName() != "" {
+		return r.findSaga(routed.SagaName())
 	}
 
-	return nil
+	return r.findSagaOfSession(msg.SessionID())
 }
 
+// StartSaga starts a new session of the saga registered with the given name.
 func (r *Registry[Tx]) StartSaga(sagaName string, sessionArgs map[string]interface{}) error {
+	return r.StartSagaContext(context.Background(), sagaName, sessionArgs)
+}
+
+// StartSagaContext is like StartSaga but uses ctx for the unit of work of the new session.
+// If ctx is canceled before the unit of work is committed, the session is not started.
+func (r *Registry[Tx]) StartSagaContext(ctx context.Context, sagaName string, sessionArgs map[string]interface{}) error {
 	if sagaName == "" {
 		return ErrInvalidSagaStart
 	}
@@ -72,32 +108,195 @@ func (r *Registry[Tx]) StartSaga(sagaName string, sessionArgs map[string]interfa
 		return ErrInvalidSagaStart
 	}
 
+	target, ok := r.findSaga(sagaName)
+	if !ok {
+		return ErrSagaNotFound
+	}
+
+	return r.orchestrator.StartSagaContext(ctx, target, sessionArgs)
+}
+
+// Saga returns the saga registered with the given name.
+func (r *Registry[Tx]) Saga(sagaName string) (Saga[Session, Tx], bool) {
+	return r.findSaga(sagaName)
+}
+
+// Sagas returns the registered sagas ordered by their names.
+func (r *Registry[Tx]) Sagas() []Saga[Session, Tx] {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
-	var target *Saga[Session, Tx]
+	sagas := make([]Saga[Session, Tx], 0, len(r.sagas))
 	for _, s := range r.sagas {
-		if s.Name() == sagaName {
-			target = &s
-			break
+		sagas = append(sagas, s)
+	}
+
+	sort.Slice(sagas, func(i, j int) bool {
+		return sagas[i].name < sagas[j].name
+	})
+
+	return sagas
+}
+
+// findSaga returns the saga registered with the given name.
+func (r *Registry[Tx]) findSaga(sagaName string) (Saga[Session, Tx], bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	s, ok := r.sagas[sagaName]
+	return s, ok
+}
+
+// findSagaOfSession returns the saga whose name prefixes the session ID, as the IDs generated by the sagas are.
+// If the names of several sagas prefix the session ID, the longest one is chosen,
+// so that a saga named "order-payment" is not mistaken for a saga named "order".
+func (r *Registry[Tx]) findSagaOfSession(sessionID string) (Saga[Session, Tx], bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var found Saga[Session, Tx]
+	ok := false
+	for name, s := range r.sagas {
+		if s.hasPublishedSaga(sessionID) && (!ok || len(name) > len(found.name)) {
+			found = s
+			ok = true
 		}
 	}
 
-	if target == nil {
+	return found, ok
+}
+
+// AddListener registers a listener which receives the events of the sessions within the unit of work which causes them.
+func (r *Registry[Tx]) AddListener(listener Listener[Tx]) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.listeners = append(r.listeners, listener)
+}
+
+// AddAsyncListener registers a listener which receives the events of the sessions after their unit of work is committed.
+func (r *Registry[Tx]) AddAsyncListener(listener AsyncListener) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.asyncListeners = append(r.asyncListeners, listener)
+}
+
+// PauseSession makes the orchestrator reject the responses of the session of the saga until it is resumed.
+// The session must implement PausableSession.
+func (r *Registry[Tx]) PauseSession(ctx context.Context, sagaName string, sessionID string) error {
+	return r.intervene(ctx, sagaName, sessionID, InterventionPause)
+}
+
+// ResumeSession makes the orchestrator handle the responses of the paused session of the saga again.
+// The responses rejected while the session was paused must be delivered again.
+func (r *Registry[Tx]) ResumeSession(ctx context.Context, sagaName string, sessionID string) error {
+	return r.intervene(ctx, sagaName, sessionID, InterventionResume)
+}
+
+// RetryStep invokes or compensates the current step of the session of the saga again,
+// as one more attempt than the retry policy of the step allows.
+func (r *Registry[Tx]) RetryStep(ctx context.Context, sagaName string, sessionID string) error {
+	return r.intervene(ctx, sagaName, sessionID, InterventionRetry)
+}
+
+// CancelSession compensates the session of the saga from its current step.
+// It fails with ErrSessionAfterPivot if the pivot step of the saga was executed.
+func (r *Registry[Tx]) CancelSession(ctx context.Context, sagaName string, sessionID string) error {
+	return r.intervene(ctx, sagaName, sessionID, InterventionCancel)
+}
+
+// SkipStep moves the session of the saga past the invocation or compensation of its current step
+// without waiting for the response.
+func (r *Registry[Tx]) SkipStep(ctx context.Context, sagaName string, sessionID string) error {
+	return r.intervene(ctx, sagaName, sessionID, InterventionSkip)
+}
+
+// CompleteSession completes the session of the saga at its current step.
+func (r *Registry[Tx]) CompleteSession(ctx context.Context, sagaName string, sessionID string) error {
+	return r.intervene(ctx, sagaName, sessionID, InterventionComplete)
+}
+
+// FailSession fails the session of the saga at its current step without compensating it.
+func (r *Registry[Tx]) FailSession(ctx context.Context, sagaName string, sessionID string) error {
+	return r.intervene(ctx, sagaName, sessionID, InterventionFail)
+}
+
+func (r *Registry[Tx]) intervene(ctx context.Context, sagaName string, sessionID string, intervention Intervention) error {
+	target, ok := r.findSaga(sagaName)
+	if !ok {
 		return ErrSagaNotFound
 	}
 
-	return r.orchestrator.StartSaga(*target, sessionArgs)
+	return r.orchestrator.Intervene(ctx, target, sessionID, intervention)
+}
+
+// SetLogger sets the logger of the registry. The default is the logger of the orchestrator.
+func (r *Registry[Tx]) SetLogger(logger *slog.Logger) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.logger = logger
+}
+
+// log returns the logger of the registry.
+func (r *Registry[Tx]) log() *slog.Logger {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.logger
+}
+
+// listenersOf returns the listeners registered so far.
+func (r *Registry[Tx]) listenersOf() ([]Listener[Tx], []AsyncListener) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]Listener[Tx](nil), r.listeners...), append([]AsyncListener(nil), r.asyncListeners...)
 }
 
 func (r *Registry[Tx]) HasSaga(sagaName string) bool {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
+
+	_, ok := r.sagas[sagaName]
+	return ok
+}
+
+func (r *Registry[Tx]) expireSessions(ctx context.Context, now time.Time, batchSize int) error {
+	r.mutex.Lock()
+	sagas := make([]Saga[Session, Tx], 0, len(r.sagas))
 	for _, s := range r.sagas {
-		if s.Name() == sagaName {
-			return true
+		sagas = append(sagas, s)
+	}
+	r.mutex.Unlock()
+
+	var errs []error
+	for _, s := range sagas {
+		repository, ok := s.Repository().(ExpiredSessionRepository[Session])
+		if !ok {
+			continue
+		}
+
+		sessions, err := repository.LoadExpired(ctx, now, batchSize)
+		if err != nil {
+			r.log().ErrorContext(ctx, "failed to load expired sessions", LogKeySaga, s.Name(), LogKeyError, err)
+			errs = append(errs, err)
+			continue
+		}
+
+		for _, sess := range sessions {
+			if sagaSession, ok := sess.(SagaSession); ok && sagaSession.SagaName() != "" && sagaSession.SagaName() != s.Name() {
+				// The repository is shared by several sagas, so the session is expired by its own saga.
+				continue
+			}
+
+			err = r.orchestrator.ExpireSession(ctx, s, sess.ID(), now)
+			if err != nil && !errors.Is(err, ErrDeadSession) {
+				errs = append(errs, err)
+			}
 		}
 	}
 
-	return false
+	return errors.Join(errs...)
 }
@@ -0,0 +1,124 @@
+package saga
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides whether and when a failed invocation or compensation of a step is tried again.
+// The zero value retries immediately and forever, which is the behavior of Retry().
+//
+// Counting attempts requires the session to implement AttemptSession, and delaying retries requires
+// the session to implement DeadlineSession, because delayed retries are executed by TimeoutSweeper.
+// Otherwise MaxAttempts is not enforced and retries are executed immediately.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. Zero means unlimited.
+	MaxAttempts int
+
+	// Backoff returns the delay before a retry. Nil means retrying immediately.
+	Backoff Backoff
+
+	// RetryOn classifies the cause of a failure. The cause is a *FailureResponseError when a failure response arrived,
+	// or ErrStepTimeout when the step timed out. Nil means every failure is retriable.
+	RetryOn func(cause error) bool
+}
+
+// isRetriable returns true if the failure caused by cause can be retried.
+func (p RetryPolicy) isRetriable(cause error) bool {
+	if p.RetryOn == nil {
+		return true
+	}
+
+	return p.RetryOn(cause)
+}
+
+// isExhausted returns true if no more attempts are allowed after the given number of attempts.
+func (p RetryPolicy) isExhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
+
+// delay returns how long to wait before the retry which follows the given number of attempts.
+func (p RetryPolicy) delay(attempts int) time.Duration {
+	if p.Backoff == nil {
+		return 0
+	}
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	return p.Backoff(attempts)
+}
+
+// Backoff returns the delay before the retry which follows the given number of attempts, starting from 1.
+type Backoff func(attempts int) time.Duration
+
+// FixedBackoff waits the same delay before every retry.
+func FixedBackoff(delay time.Duration) Backoff {
+	return func(attempts int) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff waits initial before the first retry, and multiplies the delay by multiplier for each following retry.
+// The delay never exceeds max, unless max is zero, and then it never exceeds the longest time.Duration.
+func ExponentialBackoff(initial time.Duration, max time.Duration, multiplier float64) Backoff {
+	return func(attempts int) time.Duration {
+		delay := float64(initial) * math.Pow(multiplier, float64(attempts-1))
+		if max > 0 && delay > float64(max) {
+			return max
+		}
+
+		return durationOf(delay)
+	}
+}
+
+// WithJitter randomizes the delay of b by up to fraction of it in both directions, so that retries of many sessions
+// are spread over time. For example, a fraction of 0.2 turns a delay of 10s into a delay between 8s and 12s.
+func (b Backoff) WithJitter(fraction float64) Backoff {
+	return func(attempts int) time.Duration {
+		delay := float64(b(attempts))
+		jitter := delay * fraction * (rand.Float64()*2 - 1)
+		return durationOf(delay + jitter)
+	}
+}
+
+// durationOf converts nanoseconds to a duration, clamped between zero and the longest time.Duration,
+// since converting a float64 out of the range of int64 gives an undefined value.
+func durationOf(nanoseconds float64) time.Duration {
+	switch {
+	case nanoseconds >= math.MaxInt64:
+		return math.MaxInt64
+	case nanoseconds > 0:
+		return time.Duration(nanoseconds)
+	default:
+		return 0
+	}
+}
+
+// FailureResponseError is the cause of a failure passed to RetryPolicy.RetryOn when a failure response arrived.
+type FailureResponseError struct {
+	// Response is the failure response message.
+	Response Message
+}
+
+func (e *FailureResponseError) Error() string {
+	return "failure response received"
+}
+
+// IsFailureResponse returns true if cause is a failure response, and calls f with the response if f is not nil.
+// It is a helper for writing RetryPolicy.RetryOn.
+func IsFailureResponse(cause error, f func(response Message) bool) bool {
+	var failure *FailureResponseError
+	if !errors.As(cause, &failure) {
+		return false
+	}
+
+	if f == nil {
+		return true
+	}
+
+	return f(failure.Response)
+}
@@ -0,0 +1,87 @@
+package saga
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("Zero policy retries immediately and forever", func(t *testing.T) {
+		policy := RetryPolicy{}
+
+		assert.False(t, policy.isExhausted(100))
+		assert.True(t, policy.isRetriable(ErrStepTimeout))
+		assert.Equal(t, time.Duration(0), policy.delay(3))
+	})
+
+	t.Run("Max attempts", func(t *testing.T) {
+		policy := RetryPolicy{MaxAttempts: 3}
+
+		assert.False(t, policy.isExhausted(2))
+		assert.True(t, policy.isExhausted(3))
+	})
+
+	t.Run("Retry on", func(t *testing.T) {
+		policy := RetryPolicy{
+			RetryOn: func(cause error) bool {
+				return errors.Is(cause, ErrStepTimeout)
+			},
+		}
+
+		assert.True(t, policy.isRetriable(ErrStepTimeout))
+		assert.False(t, policy.isRetriable(&FailureResponseError{}))
+	})
+
+	t.Run("Fixed backoff", func(t *testing.T) {
+		policy := RetryPolicy{Backoff: FixedBackoff(time.Second)}
+
+		assert.Equal(t, time.Second, policy.delay(1))
+		assert.Equal(t, time.Second, policy.delay(5))
+	})
+
+	t.Run("Exponential backoff", func(t *testing.T) {
+		policy := RetryPolicy{Backoff: ExponentialBackoff(time.Second, 5*time.Second, 2)}
+
+		assert.Equal(t, time.Second, policy.delay(0))
+		assert.Equal(t, time.Second, policy.delay(1))
+		assert.Equal(t, 2*time.Second, policy.delay(2))
+		assert.Equal(t, 4*time.Second, policy.delay(3))
+		assert.Equal(t, 5*time.Second, policy.delay(4))
+	})
+
+	t.Run("Exponential backoff without max", func(t *testing.T) {
+		policy := RetryPolicy{Backoff: ExponentialBackoff(time.Second, 0, 2)}
+
+		assert.Equal(t, 1024*time.Second, policy.delay(11))
+		assert.Equal(t, time.Duration(math.MaxInt64), policy.delay(100))
+		assert.Equal(t, time.Duration(math.MaxInt64), policy.delay(10000))
+
+		jittered := policy.Backoff.WithJitter(0.2)
+		for i := 0; i < 100; i++ {
+			assert.Greater(t, jittered(100), time.Duration(0))
+		}
+	})
+
+	t.Run("Jitter", func(t *testing.T) {
+		backoff := FixedBackoff(10 * time.Second).WithJitter(0.2)
+
+		for i := 0; i < 100; i++ {
+			delay := backoff(1)
+			assert.GreaterOrEqual(t, delay, 8*time.Second)
+			assert.LessOrEqual(t, delay, 12*time.Second)
+		}
+	})
+
+	t.Run("Is failure response", func(t *testing.T) {
+		response := newMockMessage()
+
+		assert.False(t, IsFailureResponse(ErrStepTimeout, nil))
+		assert.True(t, IsFailureResponse(&FailureResponseError{Response: response}, nil))
+		assert.True(t, IsFailureResponse(&FailureResponseError{Response: response}, func(m Message) bool {
+			return m == response
+		}))
+	})
+}
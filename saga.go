@@ -1,9 +1,11 @@
 package saga
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/uuid"
 	"strings"
+	"time"
 )
 
 func convertSaga[S Session, Tx TxContext](src Saga[S, Tx]) Saga[Session, Tx] {
@@ -13,11 +15,50 @@ func convertSaga[S Session, Tx TxContext](src Saga[S, Tx]) Saga[Session, Tx] {
 		return sess
 	}
 
+	var loadExpired func(ctx context.Context, now time.Time, limit int) ([]Session, error)
+	if r, ok := src.repository.(ExpiredSessionRepository[S]); ok {
+		loadExpired = func(ctx context.Context, now time.Time, limit int) ([]Session, error) {
+			ss, err := r.LoadExpired(ctx, now, limit)
+			if err != nil {
+				return nil, err
+			}
+			var sessions []Session
+			for _, s := range ss {
+				sessions = append(sessions, s)
+			}
+			return sessions, nil
+		}
+	}
+
+	var query func(ctx context.Context, query SessionQuery) ([]Session, error)
+	if r, ok := src.repository.(SessionQueryRepository[S]); ok {
+		query = func(ctx context.Context, query SessionQuery) ([]Session, error) {
+			ss, err := r.QuerySessions(ctx, query)
+			if err != nil {
+				return nil, err
+			}
+			var sessions []Session
+			for _, s := range ss {
+				sessions = append(sessions, s)
+			}
+			return sessions, nil
+		}
+	}
+
+	if loadExpired == nil && query != nil {
+		// The sessions past their deadline can be found by a query too.
+		loadExpired = func(ctx context.Context, now time.Time, limit int) ([]Session, error) {
+			return query(ctx, SessionQuery{SagaName: src.name, DeadlineBefore: now, Limit: limit})
+		}
+	}
+
 	var repository SessionRepository[Session, Tx]
 	repository = &sessionRepository[Tx]{
-		load:   func(id string) (Session, error) { return src.repository.Load(id) },
-		save:   func(sess Session) Executable[Tx] { return src.repository.Save(sess.(S)) },
-		delete: func(sess Session) Executable[Tx] { return src.repository.Delete(sess.(S)) },
+		load:        func(ctx context.Context, id string) (Session, error) { return loadSession(ctx, src.repository, id) },
+		loadExpired: loadExpired,
+		query:       query,
+		save:        func(sess Session) Executable[Tx] { return src.repository.Save(sess.(S)) },
+		delete:      func(sess Session) Executable[Tx] { return src.repository.Delete(sess.(S)) },
 	}
 
 	return NewSaga[Session, Tx](src.name, src.definition, factory, repository)
@@ -31,6 +72,8 @@ type Saga[S Session, Tx TxContext] struct {
 }
 
 func NewSaga[S Session, Tx TxContext](name string, def Definition, factory SessionFactory[S], repository SessionRepository[S, Tx]) Saga[S, Tx] {
+	def.sagaName = name
+
 	return Saga[S, Tx]{
 		name:       name,
 		definition: def,
@@ -55,15 +98,12 @@ func (s *Saga[S, Tx]) createSession(args map[string]interface{}) S {
 	if args["id"] == nil {
 		args["id"] = fmt.Sprintf("%s-%s", s.name, uuid.New().String())
 	}
+	args["sagaName"] = s.name
 
 	return s.factory(args)
 }
 
-func extractSagaName(sessid string) string {
-	return strings.Split(sessid, "-")[0]
-}
-
+// hasPublishedSaga returns true if the session ID is prefixed with the name of the saga, as the IDs generated by the saga are.
 func (s *Saga[S, Tx]) hasPublishedSaga(sessid string) bool {
-	sagaName := extractSagaName(sessid)
-	return s.name == sagaName
+	return strings.HasPrefix(sessid, s.name+"-")
 }
@@ -1,5 +1,11 @@
 package saga
 
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
 type State int
 
 const (
@@ -8,8 +14,31 @@ const (
 	StateFailed
 	StateIsCompensating
 	StateIsRetrying
+	// StateNeedsIntervention means the retries of a step ran out, so the session stopped until an operator acts on it.
+	StateNeedsIntervention
 )
 
+func (s State) String() string {
+	switch s {
+	case StateCommon:
+		return "Common"
+	case StateCompleted:
+		return "Completed"
+	case StateFailed:
+		return "Failed"
+	case StateIsCompensating:
+		return "IsCompensating"
+	case StateIsRetrying:
+		return "IsRetrying"
+	case StateNeedsIntervention:
+		return "NeedsIntervention"
+	}
+
+	return "State(" + strconv.Itoa(int(s)) + ")"
+}
+
+// SessionFactory creates a new session by the arguments given to StartSaga.
+// The arguments always contain "id", the ID of the session, and "sagaName", the name of the saga which starts the session.
 type SessionFactory[S Session] func(map[string]interface{}) S
 
 //type SessionID string
@@ -43,8 +72,29 @@ type Session interface {
 	SetState(state State)
 }
 
+// DeadlineSession is an optional interface that can be implemented by Session to support step timeouts and delayed retries.
+// The orchestrator sets the deadline when it invokes or compensates a step which has a timeout,
+// or when it schedules a retry, and clears it when a response arrives.
+type DeadlineSession interface {
+	// Deadline returns the time until the session waits for a response. Zero time means no deadline.
+	Deadline() time.Time
+
+	// SetDeadline sets the deadline of the session.
+	SetDeadline(deadline time.Time)
+}
+
+// AttemptSession is an optional interface that can be implemented by Session to count the attempts
+// of the current invocation or compensation, which is required to enforce RetryPolicy.MaxAttempts.
+type AttemptSession interface {
+	// Attempt returns how many times the current action of the current step has been tried.
+	Attempt() int
+
+	// SetAttempt sets the attempt count of the session.
+	SetAttempt(attempt int)
+}
+
 type SessionRepository[S Session, Tx TxContext] interface {
-	// Load finds a session by its ID.
+	// Load finds a session by its ID. It should fail with ErrSessionNotFound if there is no session with the ID.
 	Load(id string) (S, error)
 
 	// Save saves a session.
@@ -54,14 +104,65 @@ type SessionRepository[S Session, Tx TxContext] interface {
 	Delete(sess S) Executable[Tx]
 }
 
+// SessionContextRepository is an optional interface that can be implemented by SessionRepository
+// to receive the context of the caller when loading a session.
+// If the repository does not implement this interface, Load is used instead.
+type SessionContextRepository[S Session] interface {
+	// LoadContext finds a session by its ID, respecting the cancellation and deadline of ctx.
+	LoadContext(ctx context.Context, id string) (S, error)
+}
+
+// ExpiredSessionRepository is an optional interface that can be implemented by SessionRepository
+// to let TimeoutSweeper find the sessions which passed their deadline.
+type ExpiredSessionRepository[S Session] interface {
+	// LoadExpired returns at most limit sessions whose deadline is non-zero and before now.
+	LoadExpired(ctx context.Context, now time.Time, limit int) ([]S, error)
+}
+
+// loadSession loads a session from the repository, using LoadContext if the repository supports it.
+func loadSession[S Session, Tx TxContext](ctx context.Context, repository SessionRepository[S, Tx], id string) (S, error) {
+	if err := ctx.Err(); err != nil {
+		var empty S
+		return empty, err
+	}
+
+	if r, ok := repository.(SessionContextRepository[S]); ok {
+		return r.LoadContext(ctx, id)
+	}
+
+	return repository.Load(id)
+}
+
 type sessionRepository[Tx TxContext] struct {
-	load   func(id string) (Session, error)
-	save   func(sess Session) Executable[Tx]
-	delete func(sess Session) Executable[Tx]
+	load        func(ctx context.Context, id string) (Session, error)
+	loadExpired func(ctx context.Context, now time.Time, limit int) ([]Session, error)
+	query       func(ctx context.Context, query SessionQuery) ([]Session, error)
+	save        func(sess Session) Executable[Tx]
+	delete      func(sess Session) Executable[Tx]
 }
 
 func (s *sessionRepository[Tx]) Load(id string) (Session, error) {
-	return s.load(id)
+	return s.load(context.Background(), id)
+}
+
+func (s *sessionRepository[Tx]) LoadContext(ctx context.Context, id string) (Session, error) {
+	return s.load(ctx, id)
+}
+
+func (s *sessionRepository[Tx]) LoadExpired(ctx context.Context, now time.Time, limit int) ([]Session, error) {
+	if s.loadExpired == nil {
+		return nil, nil
+	}
+
+	return s.loadExpired(ctx, now, limit)
+}
+
+func (s *sessionRepository[Tx]) QuerySessions(ctx context.Context, query SessionQuery) ([]Session, error) {
+	if s.query == nil {
+		return nil, ErrSessionQueryNotSupported
+	}
+
+	return s.query(ctx, query)
 }
 
 func (s *sessionRepository[Tx]) Save(sess Session) Executable[Tx] {
@@ -1,7 +1,5 @@
 package saga
 
-import "errors"
-
 type mockSession struct {
 	id          string
 	currentStep Step
@@ -54,7 +52,7 @@ func (m *mockSessionRepository[S, Tx]) Load(id string) (s S, err error) {
 		return *sess, nil
 	}
 
-	return s, errors.New("session not found")
+	return s, ErrSessionNotFound
 }
 
 func (m *mockSessionRepository[S, Tx]) Save(sess S) Executable[Tx] {
@@ -0,0 +1,54 @@
+package sqlStore
+
+import (
+	"encoding/json"
+	"github.com/violetpay-org/go-saga"
+)
+
+// Codec encodes the part of a message which the columns of the message tables do not hold into a payload,
+// and restores the message from the payload. The ID, the session, the saga, the step, the attempt, the trigger,
+// the headers and the creation time of the message are held by the columns.
+type Codec[M saga.Message] interface {
+	// Encode returns the payload of the message.
+	Encode(message M) ([]byte, error)
+
+	// Decode returns the message restored from the fields held by the columns and the payload.
+	Decode(metadata saga.AbstractMessage, payload []byte) (M, error)
+}
+
+// NewCodec returns the codec which encodes and decodes by the functions.
+func NewCodec[M saga.Message](encode func(message M) ([]byte, error), decode func(metadata saga.AbstractMessage, payload []byte) (M, error)) Codec[M] {
+	return codec[M]{encode: encode, decode: decode}
+}
+
+type codec[M saga.Message] struct {
+	encode func(message M) ([]byte, error)
+	decode func(metadata saga.AbstractMessage, payload []byte) (M, error)
+}
+
+func (c codec[M]) Encode(message M) ([]byte, error) {
+	return c.encode(message)
+}
+
+func (c codec[M]) Decode(metadata saga.AbstractMessage, payload []byte) (M, error) {
+	return c.decode(metadata, payload)
+}
+
+// NewJSONCodec returns the codec which encodes the exported fields of the message as JSON.
+// newMessage returns the message which embeds the restored saga.AbstractMessage, and the payload is decoded into it.
+func NewJSONCodec[M saga.Message](newMessage func(metadata saga.AbstractMessage) M) Codec[M] {
+	return NewCodec(
+		func(message M) ([]byte, error) {
+			return json.Marshal(message)
+		},
+		func(metadata saga.AbstractMessage, payload []byte) (M, error) {
+			message := newMessage(metadata)
+			if err := json.Unmarshal(payload, &message); err != nil {
+				var zero M
+				return zero, err
+			}
+
+			return message, nil
+		},
+	)
+}
@@ -0,0 +1,121 @@
+package sqlStore
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect is what the repositories of the package need to know about the SQL of the database.
+type Dialect struct {
+	// Placeholder returns the placeholder of the nth parameter of a statement, counted from 1.
+	Placeholder func(n int) string
+
+	// SkipLocked is appended to the statement which selects the messages to claim, so that concurrent relayers
+	// skip the rows locked by each other instead of waiting for them. It is empty if the database has no such clause,
+	// and then a relayer claiming a message which another relayer has just claimed leaves the message to it.
+	SkipLocked string
+}
+
+var (
+	DialectSQLite = Dialect{
+		Placeholder: questionMark,
+	}
+	DialectMySQL = Dialect{
+		Placeholder: questionMark,
+		SkipLocked:  " FOR UPDATE SKIP LOCKED",
+	}
+	DialectPostgreSQL = Dialect{
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		SkipLocked:  " FOR UPDATE SKIP LOCKED",
+	}
+)
+
+func questionMark(n int) string {
+	return "?"
+}
+
+// rebind replaces the question marks of the query with the placeholders of the dialect.
+func (d Dialect) rebind(query string) string {
+	if d.Placeholder == nil {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(d.Placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// RepositoryOption configures the repositories of the package.
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	dialect         Dialect
+	outboxTable     string
+	deadLetterTable string
+	sessionTable    string
+	claimLease      time.Duration
+}
+
+func newRepositoryOptions(opts []RepositoryOption) repositoryOptions {
+	o := repositoryOptions{
+		dialect:         DialectSQLite,
+		outboxTable:     DefaultOutboxTable,
+		deadLetterTable: DefaultDeadLetterTable,
+		sessionTable:    DefaultSessionTable,
+		claimLease:      DefaultClaimLease,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithDialect sets the dialect of the database. The default is DialectSQLite.
+func WithDialect(dialect Dialect) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.dialect = dialect
+	}
+}
+
+// WithOutboxTable sets the name of the outbox table. The default is DefaultOutboxTable.
+// The name is put into the statements as it is, so it must not come from untrusted input.
+func WithOutboxTable(table string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.outboxTable = table
+	}
+}
+
+// WithDeadLetterTable sets the name of the dead-letter table. The default is DefaultDeadLetterTable.
+// The name is put into the statements as it is, so it must not come from untrusted input.
+func WithDeadLetterTable(table string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.deadLetterTable = table
+	}
+}
+
+// WithSessionTable sets the name of the session table. The default is DefaultSessionTable.
+// The name is put into the statements as it is, so it must not come from untrusted input.
+func WithSessionTable(table string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.sessionTable = table
+	}
+}
+
+// WithClaimLease sets how long the messages loaded from the outbox or the dead letters are hidden from other loads.
+// The relayer must delete or move them within the lease, or they are relayed again. The default is DefaultClaimLease.
+func WithClaimLease(lease time.Duration) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.claimLease = lease
+	}
+}
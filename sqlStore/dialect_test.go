@@ -0,0 +1,39 @@
+package sqlStore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDialect(t *testing.T) {
+	query := "SELECT id FROM saga_outbox WHERE channel = ? AND claimed_until <= ? LIMIT ?"
+
+	t.Run("should keep the question marks of SQLite and MySQL", func(t *testing.T) {
+		assert.Equal(t, query, DialectSQLite.rebind(query))
+		assert.Equal(t, query, DialectMySQL.rebind(query))
+	})
+
+	t.Run("should number the placeholders of PostgreSQL", func(t *testing.T) {
+		assert.Equal(t,
+			"SELECT id FROM saga_outbox WHERE channel = $1 AND claimed_until <= $2 LIMIT $3",
+			DialectPostgreSQL.rebind(query),
+		)
+	})
+
+	t.Run("should keep the query of a dialect without placeholders", func(t *testing.T) {
+		assert.Equal(t, query, Dialect{}.rebind(query))
+	})
+
+	t.Run("should skip the locked rows only where the database can", func(t *testing.T) {
+		assert.Empty(t, DialectSQLite.SkipLocked)
+		assert.Equal(t, " FOR UPDATE SKIP LOCKED", DialectMySQL.SkipLocked)
+		assert.Equal(t, " FOR UPDATE SKIP LOCKED", DialectPostgreSQL.SkipLocked)
+	})
+
+	t.Run("should rebind the statements of the repositories by the dialect", func(t *testing.T) {
+		options := newRepositoryOptions([]RepositoryOption{WithDialect(DialectPostgreSQL), WithOutboxTable("outbox")})
+		assert.Equal(t, "outbox", options.outboxTable)
+		assert.Equal(t, DefaultDeadLetterTable, options.deadLetterTable)
+		assert.Equal(t, "DELETE FROM outbox WHERE channel = $1 AND id = $2", options.dialect.rebind("DELETE FROM outbox WHERE channel = ? AND id = ?"))
+	})
+}
@@ -0,0 +1,325 @@
+package sqlStore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/violetpay-org/go-saga"
+	"time"
+)
+
+const (
+	DefaultOutboxTable     = "saga_outbox"
+	DefaultDeadLetterTable = "saga_dead_letter"
+
+	// DefaultClaimLease is how long loaded messages are hidden from other loads by default.
+	DefaultClaimLease = 30 * time.Second
+)
+
+// MessageTableSchema is the statement creating an outbox or a dead-letter table, with the name of the table
+// in place of %[1]s. Both tables have the same columns, and several channels can share them:
+//
+//	channel        the name of the channel the message is sent to
+//	id             the ID of the message, unique within the channel
+//	session_id     the ID of the session of the message
+//	saga_name      the name of the saga of the session, or empty if the message is not a saga.RoutedMessage
+//	step_name      the name of the step of the message, or empty if the message is not a saga.StepMessage
+//	attempt        the attempt of the step of the message, or zero if the message is not a saga.StepMessage
+//	triggered_by   the trigger of the message
+//	headers        the headers of the message as a JSON object, empty if the message is not a saga.HeaderMessage
+//	payload        the rest of the message, encoded by the Codec of the repository
+//	created_at     when the message was created, in Unix nanoseconds
+//	claimed_until  until when the message is hidden from loads, in Unix nanoseconds
+//
+// The types are portable, but PostgreSQL needs BYTEA in place of BLOB. An index on
+// (channel, claimed_until, created_at) keeps the loads fast when the table grows.
+const MessageTableSchema = `CREATE TABLE %[1]s (
+	channel       VARCHAR(255) NOT NULL,
+	id            VARCHAR(255) NOT NULL,
+	session_id    VARCHAR(255) NOT NULL,
+	saga_name     VARCHAR(255) NOT NULL,
+	step_name     VARCHAR(255) NOT NULL,
+	attempt       INTEGER      NOT NULL,
+	triggered_by  TEXT         NOT NULL,
+	headers       TEXT         NOT NULL,
+	payload       BLOB         NOT NULL,
+	created_at    BIGINT       NOT NULL,
+	claimed_until BIGINT       NOT NULL DEFAULT 0,
+	PRIMARY KEY (channel, id)
+)`
+
+const messageColumns = "id, session_id, saga_name, step_name, attempt, triggered_by, headers, payload, created_at"
+
+// MessageRepository keeps the outbox and the dead letters of a channel in the tables described by MessageTableSchema.
+// It implements saga.AbstractMessageRepository, saga.AbstractMessageLoadContextRepository
+// and saga.AbstractMessagePeekRepository.
+//
+// Loading messages claims them: each load runs in its own transaction, which hides the loaded messages from other
+// loads for the claim lease, so that concurrent relayers never publish a message twice while it is being relayed.
+// The messages which are neither deleted nor moved within the lease are loaded again.
+// Peeking at the messages, as the admin handler does to list them, neither claims them nor skips the claimed ones.
+type MessageRepository[M saga.Message] struct {
+	db      *sql.DB
+	channel saga.ChannelName
+	codec   Codec[M]
+	options repositoryOptions
+}
+
+// NewMessageRepository returns the repository of the messages of the channel, which are encoded by the codec.
+func NewMessageRepository[M saga.Message](db *sql.DB, channel saga.ChannelName, codec Codec[M], opts ...RepositoryOption) *MessageRepository[M] {
+	return &MessageRepository[M]{
+		db:      db,
+		channel: channel,
+		codec:   codec,
+		options: newRepositoryOptions(opts),
+	}
+}
+
+func (r *MessageRepository[M]) GetMessagesFromOutbox(batchSize int) ([]M, error) {
+	return r.GetMessagesFromOutboxContext(context.Background(), batchSize)
+}
+
+func (r *MessageRepository[M]) GetMessagesFromDeadLetter(batchSize int) ([]M, error) {
+	return r.GetMessagesFromDeadLetterContext(context.Background(), batchSize)
+}
+
+func (r *MessageRepository[M]) GetMessagesFromOutboxContext(ctx context.Context, batchSize int) ([]M, error) {
+	return r.claim(ctx, r.options.outboxTable, batchSize)
+}
+
+func (r *MessageRepository[M]) GetMessagesFromDeadLetterContext(ctx context.Context, batchSize int) ([]M, error) {
+	return r.claim(ctx, r.options.deadLetterTable, batchSize)
+}
+
+func (r *MessageRepository[M]) PeekMessagesFromOutbox(ctx context.Context, limit int) ([]M, error) {
+	return r.peek(ctx, r.options.outboxTable, limit)
+}
+
+func (r *MessageRepository[M]) PeekMessagesFromDeadLetter(ctx context.Context, limit int) ([]M, error) {
+	return r.peek(ctx, r.options.deadLetterTable, limit)
+}
+
+func (r *MessageRepository[M]) SaveMessage(message M) saga.Executable[*sql.Tx] {
+	return r.insert(r.options.outboxTable, []M{message})
+}
+
+func (r *MessageRepository[M]) SaveMessages(messages []M) saga.Executable[*sql.Tx] {
+	return r.insert(r.options.outboxTable, messages)
+}
+
+func (r *MessageRepository[M]) SaveDeadLetter(message M) saga.Executable[*sql.Tx] {
+	return r.insert(r.options.deadLetterTable, []M{message})
+}
+
+func (r *MessageRepository[M]) SaveDeadLetters(messages []M) saga.Executable[*sql.Tx] {
+	return r.insert(r.options.deadLetterTable, messages)
+}
+
+func (r *MessageRepository[M]) DeleteMessage(message M) saga.Executable[*sql.Tx] {
+	return r.delete(r.options.outboxTable, []M{message})
+}
+
+func (r *MessageRepository[M]) DeleteMessages(messages []M) saga.Executable[*sql.Tx] {
+	return r.delete(r.options.outboxTable, messages)
+}
+
+func (r *MessageRepository[M]) DeleteDeadLetter(message M) saga.Executable[*sql.Tx] {
+	return r.delete(r.options.deadLetterTable, []M{message})
+}
+
+func (r *MessageRepository[M]) DeleteDeadLetters(messages []M) saga.Executable[*sql.Tx] {
+	return r.delete(r.options.deadLetterTable, messages)
+}
+
+// claim loads at most batchSize unclaimed messages of the channel from the table, oldest first,
+// and hides them from other loads for the claim lease.
+func (r *MessageRepository[M]) claim(ctx context.Context, table string, batchSize int) ([]M, error) {
+	if batchSize <= 0 {
+		return nil, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixNano()
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE channel = ? AND claimed_until <= ? ORDER BY created_at, id LIMIT ?%s",
+		messageColumns, table, r.options.dialect.SkipLocked,
+	)
+
+	rows, err := tx.QueryContext(ctx, r.options.dialect.rebind(query), string(r.channel), now, batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := r.scan(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// The condition on claimed_until leaves the messages claimed by another load in the meantime to that load,
+	// for the databases which do not lock the selected rows.
+	update := r.options.dialect.rebind(fmt.Sprintf(
+		"UPDATE %s SET claimed_until = ? WHERE channel = ? AND id = ? AND claimed_until <= ?", table,
+	))
+	claimedUntil := time.Now().Add(r.options.claimLease).UnixNano()
+
+	var claimed []M
+	for _, message := range messages {
+		result, err := tx.ExecContext(ctx, update, claimedUntil, string(r.channel), message.ID(), now)
+		if err != nil {
+			return nil, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+
+		if affected == 1 {
+			claimed = append(claimed, message)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+// peek loads at most limit messages of the channel from the table, oldest first, whether they are claimed or not.
+func (r *MessageRepository[M]) peek(ctx context.Context, table string, limit int) ([]M, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	query := r.options.dialect.rebind(fmt.Sprintf(
+		"SELECT %s FROM %s WHERE channel = ? ORDER BY created_at, id LIMIT ?", messageColumns, table,
+	))
+
+	rows, err := r.db.QueryContext(ctx, query, string(r.channel), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.scan(rows)
+}
+
+func (r *MessageRepository[M]) scan(rows *sql.Rows) ([]M, error) {
+	defer rows.Close()
+
+	var messages []M
+	for rows.Next() {
+		var id, sessionID, sagaName, stepName, trigger, encodedHeaders string
+		var attempt int
+		var payload []byte
+		var createdAt int64
+
+		err := rows.Scan(&id, &sessionID, &sagaName, &stepName, &attempt, &trigger, &encodedHeaders, &payload, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+
+		var headers map[string]string
+		if encodedHeaders != "" {
+			if err := json.Unmarshal([]byte(encodedHeaders), &headers); err != nil {
+				return nil, err
+			}
+		}
+
+		metadata := saga.RestoreAbstractMessage(id, sessionID, sagaName, stepName, attempt, trigger, time.Unix(0, createdAt), headers)
+		message, err := r.codec.Decode(metadata, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+func (r *MessageRepository[M]) insert(table string, messages []M) saga.Executable[*sql.Tx] {
+	query := r.options.dialect.rebind(fmt.Sprintf(
+		"INSERT INTO %s (channel, %s) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", table, messageColumns,
+	))
+
+	return func(tx *sql.Tx) error {
+		for _, message := range messages {
+			args, err := r.argsOf(message)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec(query, args...); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+func (r *MessageRepository[M]) delete(table string, messages []M) saga.Executable[*sql.Tx] {
+	query := r.options.dialect.rebind(fmt.Sprintf("DELETE FROM %s WHERE channel = ? AND id = ?", table))
+
+	return func(tx *sql.Tx) error {
+		for _, message := range messages {
+			if _, err := tx.Exec(query, string(r.channel), message.ID()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// argsOf returns the values of the columns of the message, in the order of the insert statement.
+func (r *MessageRepository[M]) argsOf(message M) ([]interface{}, error) {
+	payload, err := r.codec.Encode(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var sagaName string
+	if routedMessage, ok := saga.Message(message).(saga.RoutedMessage); ok {
+		sagaName = routedMessage.SagaName()
+	}
+
+	var stepName string
+	var attempt int
+	if stepMessage, ok := saga.Message(message).(saga.StepMessage); ok {
+		stepName = stepMessage.StepName()
+		attempt = stepMessage.Attempt()
+	}
+
+	var headers []byte
+	if headerMessage, ok := saga.Message(message).(saga.HeaderMessage); ok && len(headerMessage.Headers()) > 0 {
+		headers, err = json.Marshal(headerMessage.Headers())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if payload == nil {
+		payload = []byte{}
+	}
+
+	return []interface{}{
+		string(r.channel),
+		message.ID(),
+		message.SessionID(),
+		sagaName,
+		stepName,
+		attempt,
+		message.Trigger(),
+		string(headers),
+		payload,
+		message.CreatedAt().UnixNano(),
+	}, nil
+}
@@ -0,0 +1,188 @@
+package sqlStore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/violetpay-org/go-saga"
+	"testing"
+	"time"
+)
+
+type orderMessage struct {
+	saga.AbstractMessage
+	Amount int
+}
+
+func newOrderMessage(id string, createdAt time.Time) orderMessage {
+	return orderMessage{AbstractMessage: saga.NewAbstractMessageWithTime(id, "OrderSaga-"+id, "Triggered by test", createdAt)}
+}
+
+var orderMessageCodec = NewJSONCodec(func(metadata saga.AbstractMessage) orderMessage {
+	return orderMessage{AbstractMessage: metadata}
+})
+
+func TestMessageRepository(t *testing.T) {
+	openMessageTables := func(t *testing.T) *sql.DB {
+		return openSQLite(t,
+			fmt.Sprintf(MessageTableSchema, DefaultOutboxTable),
+			fmt.Sprintf(MessageTableSchema, DefaultDeadLetterTable),
+		)
+	}
+
+	t.Run("should claim messages from the outbox oldest first and restore them by the codec", func(t *testing.T) {
+		db := openMessageTables(t)
+		repository := NewMessageRepository[orderMessage](db, "OrderChannel", orderMessageCodec)
+		otherRepository := NewMessageRepository[orderMessage](db, "OtherChannel", orderMessageCodec)
+
+		createdAt := time.Now().Add(-time.Minute)
+		first := orderMessage{
+			AbstractMessage: saga.RestoreAbstractMessage("1", "OrderSaga-1", "OrderSaga", "Pay", 2, "Triggered by test", createdAt, nil),
+			Amount:          100,
+		}
+		first.Headers()["traceparent"] = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+		second := newOrderMessage("2", createdAt.Add(time.Second))
+		third := newOrderMessage("3", time.Now())
+
+		commit(t, db,
+			repository.SaveMessages([]orderMessage{third, second}),
+			repository.SaveMessage(first),
+			otherRepository.SaveMessage(first),
+		)
+
+		claimed, err := repository.GetMessagesFromOutbox(2)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 2)
+		assert.Equal(t, "1", claimed[0].ID())
+		assert.Equal(t, "OrderSaga-1", claimed[0].SessionID())
+		assert.Equal(t, "OrderSaga", claimed[0].SagaName())
+		assert.Equal(t, "Pay", claimed[0].StepName())
+		assert.Equal(t, 2, claimed[0].Attempt())
+		assert.Equal(t, "Triggered by test", claimed[0].Trigger())
+		assert.Equal(t, first.Headers(), claimed[0].Headers())
+		assert.True(t, createdAt.Equal(claimed[0].CreatedAt()))
+		assert.Equal(t, 100, claimed[0].Amount)
+		assert.Equal(t, "2", claimed[1].ID())
+
+		// The claimed messages are hidden from the next load, and the messages of other channels are not claimed
+		claimed, err = repository.GetMessagesFromOutboxContext(context.Background(), 10)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 1)
+		assert.Equal(t, "3", claimed[0].ID())
+
+		claimed, err = otherRepository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 1)
+
+		claimed, err = repository.GetMessagesFromOutbox(0)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 0)
+	})
+
+	t.Run("should load the claimed messages again when their lease runs out", func(t *testing.T) {
+		db := openMessageTables(t)
+		repository := NewMessageRepository[orderMessage](db, "OrderChannel", orderMessageCodec, WithClaimLease(50*time.Millisecond))
+
+		commit(t, db, repository.SaveMessages([]orderMessage{
+			newOrderMessage("1", time.Now().Add(-time.Second)),
+			newOrderMessage("2", time.Now()),
+		}))
+
+		claimed, err := repository.GetMessagesFromOutbox(1)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 1)
+		assert.Equal(t, "1", claimed[0].ID())
+
+		claimed, err = repository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 1)
+		assert.Equal(t, "2", claimed[0].ID())
+
+		claimed, err = repository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 0)
+
+		time.Sleep(60 * time.Millisecond)
+
+		claimed, err = repository.GetMessagesFromOutbox(10)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 2)
+	})
+
+	t.Run("should peek at the messages without claiming them", func(t *testing.T) {
+		db := openMessageTables(t)
+		repository := NewMessageRepository[orderMessage](db, "OrderChannel", orderMessageCodec)
+
+		commit(t, db,
+			repository.SaveMessages([]orderMessage{newOrderMessage("1", time.Now().Add(-time.Second)), newOrderMessage("2", time.Now())}),
+			repository.SaveDeadLetter(newOrderMessage("3", time.Now())),
+		)
+
+		peeked, err := repository.PeekMessagesFromOutbox(context.Background(), 10)
+		assert.Nil(t, err)
+		assert.Len(t, peeked, 2)
+
+		claimed, err := repository.GetMessagesFromOutbox(1)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 1)
+
+		// The claimed messages are still listed
+		peeked, err = repository.PeekMessagesFromOutbox(context.Background(), 10)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"1", "2"}, []string{peeked[0].ID(), peeked[1].ID()})
+
+		peeked, err = repository.PeekMessagesFromDeadLetter(context.Background(), 10)
+		assert.Nil(t, err)
+		assert.Len(t, peeked, 1)
+
+		claimed, err = repository.GetMessagesFromDeadLetter(10)
+		assert.Nil(t, err)
+		assert.Len(t, claimed, 1)
+		assert.Equal(t, "3", claimed[0].ID())
+
+		// The peek is kept by the repository handed to the channels
+		converted := saga.ConvertMessageRepository[orderMessage, *sql.Tx](repository)
+		peekRepository, ok := converted.(saga.AbstractMessagePeekRepository[saga.Message])
+		assert.True(t, ok)
+		listed, err := peekRepository.PeekMessagesFromOutbox(context.Background(), 10)
+		assert.Nil(t, err)
+		assert.Len(t, listed, 2)
+	})
+
+	t.Run("should move messages to the dead letters", func(t *testing.T) {
+		db := openMessageTables(t)
+		repository := NewMessageRepository[orderMessage](db, "OrderChannel", orderMessageCodec)
+
+		message := newOrderMessage("1", time.Now())
+		message.Amount = 100
+		commit(t, db, repository.SaveMessages([]orderMessage{message, newOrderMessage("2", time.Now())}))
+		commit(t, db, repository.DeleteMessage(message), repository.SaveDeadLetter(message))
+
+		deadLetters, err := repository.GetMessagesFromDeadLetter(10)
+		assert.Nil(t, err)
+		assert.Len(t, deadLetters, 1)
+		assert.Equal(t, 100, deadLetters[0].Amount)
+		assert.Equal(t, 1, countRows(t, db, DefaultOutboxTable))
+
+		commit(t, db, repository.DeleteDeadLetters(deadLetters), repository.DeleteMessages([]orderMessage{newOrderMessage("2", time.Now())}))
+		assert.Equal(t, 0, countRows(t, db, DefaultOutboxTable))
+		assert.Equal(t, 0, countRows(t, db, DefaultDeadLetterTable))
+	})
+
+	t.Run("should keep the messages in the tables given by the options", func(t *testing.T) {
+		db := openSQLite(t, fmt.Sprintf(MessageTableSchema, "outbox"), fmt.Sprintf(MessageTableSchema, "dead_letter"))
+		repository := NewMessageRepository[orderMessage](db, "OrderChannel", orderMessageCodec,
+			WithOutboxTable("outbox"),
+			WithDeadLetterTable("dead_letter"),
+		)
+
+		commit(t, db,
+			repository.SaveMessage(newOrderMessage("1", time.Now())),
+			repository.SaveDeadLetter(newOrderMessage("2", time.Now())),
+		)
+
+		assert.Equal(t, 1, countRows(t, db, "outbox"))
+		assert.Equal(t, 1, countRows(t, db, "dead_letter"))
+	})
+}
@@ -0,0 +1,385 @@
+package sqlStore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/violetpay-org/go-saga"
+	"strings"
+	"time"
+)
+
+// DefaultSessionTable is the name of the session table by default.
+const DefaultSessionTable = "saga_session"
+
+// SessionTableSchema is the statement creating a session table, with the name of the table in place of %[1]s.
+// Several sagas can share the table:
+//
+//	id            the ID of the session
+//	saga_name     the name of the saga of the session
+//	current_step  the name of the current step of the session, or empty if it has none
+//	state         the saga.State of the session
+//	pending       1 if the session is waiting for a response, 0 otherwise
+//	version       the version of the session, counted up by every save
+//	deadline      the deadline of the session in Unix nanoseconds, or zero if it has none
+//	updated_at    when the session was saved last, in Unix nanoseconds
+//	payload       the rest of the session, encoded by the SessionCodec of the repository
+//
+// The types are portable, but PostgreSQL needs BYTEA in place of BLOB. An index on (saga_name, deadline)
+// keeps TimeoutSweeper fast when the table grows.
+const SessionTableSchema = `CREATE TABLE %[1]s (
+	id           VARCHAR(255) NOT NULL,
+	saga_name    VARCHAR(255) NOT NULL,
+	current_step VARCHAR(255) NOT NULL,
+	state        INTEGER      NOT NULL,
+	pending      SMALLINT     NOT NULL,
+	version      BIGINT       NOT NULL,
+	deadline     BIGINT       NOT NULL,
+	updated_at   BIGINT       NOT NULL,
+	payload      BLOB         NOT NULL,
+	PRIMARY KEY (saga_name, id)
+)`
+
+const sessionColumns = "id, current_step, state, pending, version, deadline, updated_at, payload"
+
+// SessionCodec encodes the part of a session which the columns of the session table do not hold into a payload,
+// like the attempt, the history and the fields of the saga, and restores it from the payload.
+type SessionCodec[S saga.Session] interface {
+	// Encode returns the payload of the session.
+	Encode(session S) ([]byte, error)
+
+	// Decode restores the payload into the session created by the saga.SessionFactory of the repository,
+	// and returns the restored session.
+	Decode(session S, payload []byte) (S, error)
+}
+
+// NewSessionCodec returns the codec which encodes and decodes by the functions.
+func NewSessionCodec[S saga.Session](encode func(session S) ([]byte, error), decode func(session S, payload []byte) (S, error)) SessionCodec[S] {
+	return sessionCodec[S]{encode: encode, decode: decode}
+}
+
+type sessionCodec[S saga.Session] struct {
+	encode func(session S) ([]byte, error)
+	decode func(session S, payload []byte) (S, error)
+}
+
+func (c sessionCodec[S]) Encode(session S) ([]byte, error) {
+	return c.encode(session)
+}
+
+func (c sessionCodec[S]) Decode(session S, payload []byte) (S, error) {
+	return c.decode(session, payload)
+}
+
+// NewJSONSessionCodec returns the codec which encodes the session as JSON. The session must be a pointer
+// whose exported fields, or whose json.Marshaler and json.Unmarshaler, hold the state of the session,
+// like a session embedding saga.BaseSession which encodes its own fields as saga.BaseSession describes.
+func NewJSONSessionCodec[S saga.Session]() SessionCodec[S] {
+	return NewSessionCodec(
+		func(session S) ([]byte, error) {
+			return json.Marshal(session)
+		},
+		func(session S, payload []byte) (S, error) {
+			err := json.Unmarshal(payload, &session)
+			return session, err
+		},
+	)
+}
+
+// SessionRepository keeps the sessions of a saga in the table described by SessionTableSchema.
+// It implements saga.SessionRepository, saga.SessionContextRepository and saga.SessionQueryRepository,
+// so the sessions past their deadline are found by TimeoutSweeper too.
+//
+// The repository restores the ID, the current step, the state and the pending flag of a loaded session,
+// and its version, deadline and update time if the session implements saga.VersionedSession,
+// saga.DeadlineSession and saga.TimestampedSession. The codec restores the rest.
+// Saving a saga.VersionedSession fails with saga.ErrSessionVersionConflict if the stored session has another version.
+type SessionRepository[S saga.Session] struct {
+	db         *sql.DB
+	sagaName   string
+	definition saga.Definition
+	factory    saga.SessionFactory[S]
+	codec      SessionCodec[S]
+	options    repositoryOptions
+}
+
+// NewSessionRepository returns the repository of the sessions of the saga with the name and the definition.
+// The current step of a loaded session is found in the definition by its name, and the session is created
+// by the factory before the codec restores it.
+func NewSessionRepository[S saga.Session](db *sql.DB, sagaName string, definition saga.Definition, factory saga.SessionFactory[S], codec SessionCodec[S], opts ...RepositoryOption) *SessionRepository[S] {
+	return &SessionRepository[S]{
+		db:         db,
+		sagaName:   sagaName,
+		definition: definition,
+		factory:    factory,
+		codec:      codec,
+		options:    newRepositoryOptions(opts),
+	}
+}
+
+func (r *SessionRepository[S]) Load(id string) (S, error) {
+	return r.LoadContext(context.Background(), id)
+}
+
+func (r *SessionRepository[S]) LoadContext(ctx context.Context, id string) (S, error) {
+	query := r.options.dialect.rebind(fmt.Sprintf(
+		"SELECT %s FROM %s WHERE saga_name = ? AND id = ?", sessionColumns, r.options.sessionTable,
+	))
+
+	rows, err := r.db.QueryContext(ctx, query, r.sagaName, id)
+	if err != nil {
+		var empty S
+		return empty, err
+	}
+
+	sessions, err := r.scan(rows)
+	if err != nil {
+		var empty S
+		return empty, err
+	}
+
+	if len(sessions) == 0 {
+		var empty S
+		return empty, saga.ErrSessionNotFound
+	}
+
+	return sessions[0], nil
+}
+
+// QuerySessions lists the sessions of the saga matching the query. UpdatedBefore is compared with the time
+// the sessions were saved last by the repository, even if they do not implement saga.TimestampedSession.
+func (r *SessionRepository[S]) QuerySessions(ctx context.Context, query saga.SessionQuery) ([]S, error) {
+	if query.SagaName != "" && query.SagaName != r.sagaName {
+		return nil, nil
+	}
+
+	conditions := []string{"saga_name = ?"}
+	args := []interface{}{r.sagaName}
+
+	if len(query.States) > 0 {
+		placeholders := make([]string, len(query.States))
+		for i, state := range query.States {
+			placeholders[i] = "?"
+			args = append(args, int(state))
+		}
+		conditions = append(conditions, "state IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if query.Pending != nil {
+		conditions = append(conditions, "pending = ?")
+		args = append(args, flagOf(*query.Pending))
+	}
+
+	if query.CurrentStep != "" {
+		conditions = append(conditions, "current_step = ?")
+		args = append(args, query.CurrentStep)
+	}
+
+	if !query.UpdatedBefore.IsZero() {
+		conditions = append(conditions, "updated_at < ?")
+		args = append(args, query.UpdatedBefore.UnixNano())
+	}
+
+	if !query.DeadlineBefore.IsZero() {
+		conditions = append(conditions, "deadline <> 0", "deadline < ?")
+		args = append(args, query.DeadlineBefore.UnixNano())
+	}
+
+	if query.After != "" {
+		conditions = append(conditions, "id > ?")
+		args = append(args, query.After)
+	}
+
+	statement := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s ORDER BY id", sessionColumns, r.options.sessionTable, strings.Join(conditions, " AND "),
+	)
+	if query.Limit > 0 {
+		statement += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, r.options.dialect.rebind(statement), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.scan(rows)
+}
+
+// Save updates the stored session, or inserts the session if it is not stored yet.
+func (r *SessionRepository[S]) Save(sess S) saga.Executable[*sql.Tx] {
+	return func(tx *sql.Tx) error {
+		payload, err := r.codec.Encode(sess)
+		if err != nil {
+			return err
+		}
+
+		if payload == nil {
+			payload = []byte{}
+		}
+
+		var version int64
+		versionedSession, versioned := saga.Session(sess).(saga.VersionedSession)
+		if versioned {
+			version = versionedSession.Version()
+		}
+
+		var currentStep string
+		if step := sess.CurrentStep(); step != nil {
+			currentStep = step.Name()
+		}
+
+		var deadline int64
+		if deadlineSession, ok := saga.Session(sess).(saga.DeadlineSession); ok && !deadlineSession.Deadline().IsZero() {
+			deadline = deadlineSession.Deadline().UnixNano()
+		}
+
+		updatedAt := time.Now()
+		values := []interface{}{currentStep, int(sess.State()), flagOf(sess.IsPending()), deadline, updatedAt.UnixNano(), payload}
+
+		update := fmt.Sprintf(
+			"UPDATE %s SET current_step = ?, state = ?, pending = ?, deadline = ?, updated_at = ?, payload = ?, version = version + 1 WHERE saga_name = ? AND id = ?",
+			r.options.sessionTable,
+		)
+		args := append(values, r.sagaName, sess.ID())
+		if versioned {
+			update += " AND version = ?"
+			args = append(args, version)
+		}
+
+		result, err := tx.Exec(r.options.dialect.rebind(update), args...)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			err := r.insert(tx, sess.ID(), version, values)
+			if err != nil {
+				return err
+			}
+		}
+
+		if versioned {
+			versionedSession.SetVersion(version + 1)
+		}
+
+		if timestampedSession, ok := saga.Session(sess).(saga.TimestampedSession); ok {
+			timestampedSession.SetUpdatedAt(updatedAt)
+		}
+
+		return nil
+	}
+}
+
+// insert inserts the session which the update did not find, unless another version of the session is stored.
+func (r *SessionRepository[S]) insert(tx *sql.Tx, id string, version int64, values []interface{}) error {
+	var stored int
+	err := tx.QueryRow(
+		r.options.dialect.rebind(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE saga_name = ? AND id = ?", r.options.sessionTable)),
+		r.sagaName, id,
+	).Scan(&stored)
+	if err != nil {
+		return err
+	}
+
+	if stored > 0 {
+		return saga.ErrSessionVersionConflict
+	}
+
+	insert := fmt.Sprintf(
+		"INSERT INTO %s (current_step, state, pending, deadline, updated_at, payload, saga_name, id, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		r.options.sessionTable,
+	)
+	args := append(values, r.sagaName, id, version+1)
+
+	_, err = tx.Exec(r.options.dialect.rebind(insert), args...)
+	return err
+}
+
+func (r *SessionRepository[S]) Delete(sess S) saga.Executable[*sql.Tx] {
+	query := r.options.dialect.rebind(fmt.Sprintf("DELETE FROM %s WHERE saga_name = ? AND id = ?", r.options.sessionTable))
+
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query, r.sagaName, sess.ID())
+		return err
+	}
+}
+
+func (r *SessionRepository[S]) scan(rows *sql.Rows) ([]S, error) {
+	defer rows.Close()
+
+	var sessions []S
+	for rows.Next() {
+		var id, currentStep string
+		var state, pending int
+		var version, deadline, updatedAt int64
+		var payload []byte
+
+		err := rows.Scan(&id, &currentStep, &state, &pending, &version, &deadline, &updatedAt, &payload)
+		if err != nil {
+			return nil, err
+		}
+
+		session, err := r.restore(id, currentStep, saga.State(state), pending != 0, version, deadline, updatedAt, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *SessionRepository[S]) restore(id string, currentStep string, state saga.State, pending bool, version int64, deadline int64, updatedAt int64, payload []byte) (S, error) {
+	session, err := r.codec.Decode(r.factory(map[string]interface{}{"id": id, "sagaName": r.sagaName}), payload)
+	if err != nil {
+		return session, err
+	}
+
+	if currentStep != "" {
+		step := r.definition.FindStep(currentStep)
+		if step == nil {
+			return session, fmt.Errorf("%w: step %q of session %s is not defined", saga.ErrSessionStepAndDefinitionMismatch, currentStep, id)
+		}
+
+		if err := session.UpdateCurrentStep(step); err != nil {
+			return session, err
+		}
+	}
+
+	session.SetState(state)
+	session.SetPending(pending)
+
+	if versionedSession, ok := saga.Session(session).(saga.VersionedSession); ok {
+		versionedSession.SetVersion(version)
+	}
+
+	if deadlineSession, ok := saga.Session(session).(saga.DeadlineSession); ok {
+		if deadline == 0 {
+			deadlineSession.SetDeadline(time.Time{})
+		} else {
+			deadlineSession.SetDeadline(time.Unix(0, deadline))
+		}
+	}
+
+	if timestampedSession, ok := saga.Session(session).(saga.TimestampedSession); ok {
+		timestampedSession.SetUpdatedAt(time.Unix(0, updatedAt))
+	}
+
+	return session, nil
+}
+
+func flagOf(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
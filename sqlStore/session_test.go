@@ -0,0 +1,248 @@
+package sqlStore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/violetpay-org/go-saga"
+	"testing"
+	"time"
+)
+
+type orderSession struct {
+	saga.BaseSession
+	Amount int
+}
+
+func (s *orderSession) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.fields())
+}
+
+func (s *orderSession) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, s.fields())
+}
+
+func (s *orderSession) fields() interface{} {
+	return &struct {
+		Session *saga.BaseSession
+		Amount  *int
+	}{&s.BaseSession, &s.Amount}
+}
+
+func newOrderSession(args map[string]interface{}) *orderSession {
+	return &orderSession{BaseSession: saga.NewBaseSession(args)}
+}
+
+func TestSessionRepository(t *testing.T) {
+	newDefinition := func(db *sql.DB, steps ...string) saga.Definition {
+		messages := NewMessageRepository[orderMessage](db, "OrderChannel", orderMessageCodec)
+		constructor := func(session *orderSession) orderMessage {
+			return orderMessage{AbstractMessage: saga.NewAbstractMessageFromSession(session.ID(), session, "Triggered by test")}
+		}
+		endpoint := saga.NewLocalEndpoint[*orderSession, orderMessage, orderMessage, *sql.Tx](
+			"OrderSuccessChannel",
+			constructor,
+			messages,
+			"OrderFailureChannel",
+			constructor,
+			messages,
+			func(session saga.Session) (saga.Executable[*sql.Tx], error) {
+				return func(tx *sql.Tx) error {
+					return nil
+				}, nil
+			},
+		)
+
+		builder := saga.NewStepBuilder[*sql.Tx]()
+		for _, step := range steps {
+			builder = builder.Step(step).LocalInvoke(endpoint)
+		}
+
+		def, err := builder.Build()
+		assert.Nil(t, err)
+		return def
+	}
+
+	openSessionTable := func(t *testing.T) (*sql.DB, saga.Definition, *SessionRepository[*orderSession]) {
+		db := openSQLite(t, fmt.Sprintf(SessionTableSchema, DefaultSessionTable))
+		def := newDefinition(db, "Reserve", "Pay")
+		return db, def, NewSessionRepository[*orderSession](db, "OrderSaga", def, newOrderSession, NewJSONSessionCodec[*orderSession]())
+	}
+
+	t.Run("should save and load sessions with their steps, versions and payloads", func(t *testing.T) {
+		db, def, repository := openSessionTable(t)
+
+		deadline := time.Now().Add(-time.Second)
+		session := newOrderSession(map[string]interface{}{"id": "OrderSaga-1", "sagaName": "OrderSaga"})
+		session.Amount = 100
+		assert.Nil(t, session.UpdateCurrentStep(def.FindStep("Pay")))
+		session.SetPending(true)
+		session.SetState(saga.StateIsRetrying)
+		session.SetAttempt(2)
+		session.SetDeadline(deadline)
+		session.SetPaused(true)
+		session.SetCondition("isMember", true)
+		session.SetBranchState("Pay", "Card", saga.BranchStateSucceeded)
+		session.SetParentSession("ParentSaga", "ParentSaga-1")
+		session.SetChildSessionID("Reserve", "ChildSaga-1")
+		session.AppendHistory(saga.HistoryEntry{Step: "Reserve", Direction: saga.DirectionForward, Outcome: saga.OutcomeSucceeded})
+		commit(t, db, repository.Save(session))
+		assert.Equal(t, int64(1), session.Version())
+		assert.False(t, session.UpdatedAt().IsZero())
+
+		loaded, err := repository.Load("OrderSaga-1")
+		assert.Nil(t, err)
+		assert.Equal(t, "OrderSaga-1", loaded.ID())
+		assert.Equal(t, "OrderSaga", loaded.SagaName())
+		assert.Equal(t, "Pay", loaded.CurrentStep().Name())
+		assert.True(t, loaded.IsPending())
+		assert.Equal(t, saga.StateIsRetrying, loaded.State())
+		assert.Equal(t, 2, loaded.Attempt())
+		assert.True(t, deadline.Equal(loaded.Deadline()))
+		assert.Equal(t, int64(1), loaded.Version())
+		assert.True(t, session.CreatedAt().Equal(loaded.CreatedAt()))
+		assert.True(t, loaded.Paused())
+		assert.Equal(t, 100, loaded.Amount)
+		result, evaluated := loaded.Condition("isMember")
+		assert.True(t, result && evaluated)
+		state, ok := loaded.BranchState("Pay", "Card")
+		assert.True(t, ok)
+		assert.Equal(t, saga.BranchStateSucceeded, state)
+		parentSagaName, parentID := loaded.ParentSession()
+		assert.Equal(t, []string{"ParentSaga", "ParentSaga-1"}, []string{parentSagaName, parentID})
+		childID, ok := loaded.ChildSessionID("Reserve")
+		assert.True(t, ok)
+		assert.Equal(t, "ChildSaga-1", childID)
+		assert.Equal(t, session.History(), loaded.History())
+
+		_, err = repository.LoadContext(context.Background(), "OrderSaga-2")
+		assert.ErrorIs(t, err, saga.ErrSessionNotFound)
+	})
+
+	t.Run("should fail to save a session whose stored version is newer", func(t *testing.T) {
+		db, def, repository := openSessionTable(t)
+
+		session := newOrderSession(map[string]interface{}{"id": "OrderSaga-1", "sagaName": "OrderSaga"})
+		assert.Nil(t, session.UpdateCurrentStep(def.FindStep("Reserve")))
+		commit(t, db, repository.Save(session))
+
+		loaded, err := repository.Load("OrderSaga-1")
+		assert.Nil(t, err)
+		stale, err := repository.Load("OrderSaga-1")
+		assert.Nil(t, err)
+
+		loaded.SetPending(true)
+		commit(t, db, repository.Save(loaded))
+		assert.Equal(t, int64(2), loaded.Version())
+
+		uow, err := NewUnitOfWorkFactory(db)(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, uow.AddWorkUnit(repository.Save(stale)))
+		assert.ErrorIs(t, uow.Commit(), saga.ErrSessionVersionConflict)
+
+		// A new session with the ID of a stored one conflicts with it instead of replacing it
+		uow, err = NewUnitOfWorkFactory(db)(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, uow.AddWorkUnit(repository.Save(newOrderSession(map[string]interface{}{"id": "OrderSaga-1", "sagaName": "OrderSaga"}))))
+		assert.ErrorIs(t, uow.Commit(), saga.ErrSessionVersionConflict)
+
+		stored, err := repository.Load("OrderSaga-1")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), stored.Version())
+		assert.True(t, stored.IsPending())
+	})
+
+	t.Run("should query sessions by their fields", func(t *testing.T) {
+		db, def, repository := openSessionTable(t)
+
+		first := newOrderSession(map[string]interface{}{"id": "OrderSaga-1", "sagaName": "OrderSaga"})
+		assert.Nil(t, first.UpdateCurrentStep(def.FindStep("Pay")))
+		first.SetState(saga.StateIsRetrying)
+		first.SetDeadline(time.Now().Add(-time.Second))
+		second := newOrderSession(map[string]interface{}{"id": "OrderSaga-2", "sagaName": "OrderSaga"})
+		assert.Nil(t, second.UpdateCurrentStep(def.FindStep("Reserve")))
+		second.SetPending(true)
+		commit(t, db, repository.Save(first), repository.Save(second))
+
+		// The sessions of another saga share the table
+		other := NewSessionRepository[*orderSession](db, "OtherSaga", def, newOrderSession, NewJSONSessionCodec[*orderSession]())
+		commit(t, db, other.Save(newOrderSession(map[string]interface{}{"id": "OrderSaga-1", "sagaName": "OtherSaga"})))
+
+		pending := true
+		sessions, err := repository.QuerySessions(context.Background(), saga.SessionQuery{Pending: &pending})
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, "OrderSaga-2", sessions[0].ID())
+
+		sessions, err = repository.QuerySessions(context.Background(), saga.SessionQuery{DeadlineBefore: time.Now()})
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, "OrderSaga-1", sessions[0].ID())
+
+		sessions, err = repository.QuerySessions(context.Background(), saga.SessionQuery{CurrentStep: "Reserve"})
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, "OrderSaga-2", sessions[0].ID())
+
+		sessions, err = repository.QuerySessions(context.Background(), saga.SessionQuery{
+			States: []saga.State{saga.StateCommon, saga.StateIsRetrying},
+			After:  "OrderSaga-1",
+			Limit:  10,
+		})
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, "OrderSaga-2", sessions[0].ID())
+
+		sessions, err = repository.QuerySessions(context.Background(), saga.SessionQuery{UpdatedBefore: time.Now(), Limit: 1})
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, "OrderSaga-1", sessions[0].ID())
+
+		sessions, err = repository.QuerySessions(context.Background(), saga.SessionQuery{SagaName: "OtherSaga"})
+		assert.Nil(t, err)
+		assert.Len(t, sessions, 0)
+
+		commit(t, db, repository.Delete(second))
+		_, err = repository.Load("OrderSaga-2")
+		assert.ErrorIs(t, err, saga.ErrSessionNotFound)
+		assert.Equal(t, 2, countRows(t, db, DefaultSessionTable))
+	})
+
+	t.Run("should fail to load a session whose step is not defined", func(t *testing.T) {
+		db, def, repository := openSessionTable(t)
+
+		session := newOrderSession(map[string]interface{}{"id": "OrderSaga-1", "sagaName": "OrderSaga"})
+		assert.Nil(t, session.UpdateCurrentStep(def.FindStep("Pay")))
+		commit(t, db, repository.Save(session))
+
+		renamed := NewSessionRepository[*orderSession](db, "OrderSaga", newDefinition(db, "Reserve"), newOrderSession, NewJSONSessionCodec[*orderSession]())
+		_, err := renamed.Load("OrderSaga-1")
+		assert.ErrorIs(t, err, saga.ErrSessionStepAndDefinitionMismatch)
+	})
+
+	t.Run("should encode sessions embedding BaseSession as JSON", func(t *testing.T) {
+		db, def, _ := openSessionTable(t)
+
+		session := newOrderSession(map[string]interface{}{"id": "OrderSaga-1", "sagaName": "OrderSaga"})
+		session.Amount = 100
+		assert.Nil(t, session.UpdateCurrentStep(def.FindStep("Pay")))
+
+		codec := NewJSONSessionCodec[*orderSession]()
+		payload, err := codec.Encode(session)
+		assert.Nil(t, err)
+
+		// A session decoded without the repository finds its step in the definition
+		decoded, err := codec.Decode(&orderSession{}, payload)
+		assert.Nil(t, err)
+		assert.Equal(t, 100, decoded.Amount)
+		assert.Nil(t, decoded.CurrentStep())
+		assert.Nil(t, decoded.RestoreCurrentStep(def))
+		assert.Equal(t, "Pay", decoded.CurrentStep().Name())
+		assert.ErrorIs(t, decoded.RestoreCurrentStep(newDefinition(db, "Reserve")), saga.ErrSessionStepAndDefinitionMismatch)
+
+		_, err = codec.Decode(&orderSession{}, []byte("{"))
+		assert.IsType(t, &json.SyntaxError{}, err)
+	})
+}
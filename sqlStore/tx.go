@@ -0,0 +1,116 @@
+// Package sqlStore keeps sagas in databases through database/sql. It runs the units of work of the orchestrator
+// and the relayer in transactions, and keeps the sessions of sagas and the outbox and the dead letters of channels
+// in tables.
+package sqlStore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/violetpay-org/go-saga"
+)
+
+// Option configures the transactions begun by TxHandler.
+type Option func(*sql.TxOptions)
+
+// WithIsolationLevel sets the isolation level of the transactions. The default is the one of the driver.
+func WithIsolationLevel(level sql.IsolationLevel) Option {
+	return func(o *sql.TxOptions) {
+		o.Isolation = level
+	}
+}
+
+// WithReadOnly makes the transactions read-only, if the driver supports it.
+func WithReadOnly() Option {
+	return func(o *sql.TxOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// TxHandler begins, commits and rolls back the transactions of the units of work on the database.
+type TxHandler struct {
+	db      *sql.DB
+	options sql.TxOptions
+}
+
+// NewTxHandler returns the handler of the transactions on the database.
+func NewTxHandler(db *sql.DB, opts ...Option) *TxHandler {
+	h := &TxHandler{db: db}
+	for _, opt := range opts {
+		opt(&h.options)
+	}
+
+	return h
+}
+
+func (h *TxHandler) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return h.db.BeginTx(ctx, &h.options)
+}
+
+func (h *TxHandler) Commit(tx *sql.Tx) error {
+	return tx.Commit()
+}
+
+// Rollback rolls back the transaction. It does nothing if the transaction was not begun or is already done,
+// since the unit of work rolls back after every commit too.
+func (h *TxHandler) Rollback(tx *sql.Tx) error {
+	if tx == nil {
+		return nil
+	}
+
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+
+	return nil
+}
+
+// joinedTxHandler hands the transaction of the caller to a unit of work, and leaves its commit and rollback
+// to the caller.
+type joinedTxHandler struct {
+	tx *sql.Tx
+}
+
+func (h joinedTxHandler) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return h.tx, nil
+}
+
+func (h joinedTxHandler) Commit(tx *sql.Tx) error {
+	return nil
+}
+
+func (h joinedTxHandler) Rollback(tx *sql.Tx) error {
+	return nil
+}
+
+type txKey struct{}
+
+// WithTx returns ctx carrying the transaction, so that the units of work created with it by the factory
+// of NewUnitOfWorkFactory join the transaction instead of beginning their own.
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFrom returns the transaction carried by ctx, or false if ctx carries none.
+func TxFrom(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok && tx != nil
+}
+
+// NewUnitOfWorkFactory returns the factory of the units of work which run in transactions on the database.
+// A unit of work created with a context carrying a transaction by WithTx runs in that transaction instead,
+// so that a saga can be started or a response handled together with the writes of the caller.
+// The caller owns the joined transaction: committing the unit of work neither commits nor rolls it back,
+// so the caller must roll it back if the unit of work fails. The async listeners of the joined unit of work
+// are notified when the unit of work is committed, before the caller commits the transaction.
+func NewUnitOfWorkFactory(db *sql.DB, opts ...Option) saga.UnitOfWorkFactory[*sql.Tx] {
+	handler := NewTxHandler(db, opts...)
+
+	return func(ctx context.Context) (*saga.UnitOfWork[*sql.Tx], error) {
+		if tx, ok := TxFrom(ctx); ok {
+			return saga.NewUnitOfWork[*sql.Tx](ctx, joinedTxHandler{tx: tx}), nil
+		}
+
+		return saga.NewUnitOfWork[*sql.Tx](ctx, handler), nil
+	}
+}
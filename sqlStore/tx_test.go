@@ -0,0 +1,161 @@
+package sqlStore
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/violetpay-org/go-saga"
+	_ "modernc.org/sqlite"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnitOfWorkFactory(t *testing.T) {
+	insertItem := func(name string) saga.Executable[*sql.Tx] {
+		return func(tx *sql.Tx) error {
+			_, err := tx.Exec("INSERT INTO items (name) VALUES (?)", name)
+			return err
+		}
+	}
+
+	t.Run("should commit the work units in one transaction and roll back all of them on failure", func(t *testing.T) {
+		db := openSQLite(t, "CREATE TABLE items (name TEXT NOT NULL UNIQUE)")
+		factory := NewUnitOfWorkFactory(db, WithIsolationLevel(sql.LevelSerializable))
+
+		uow, err := factory(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, uow.AddWorkUnit(insertItem("first")))
+		assert.Nil(t, uow.AddWorkUnit(insertItem("second")))
+		assert.Nil(t, uow.Commit())
+		assert.Equal(t, 2, countRows(t, db, "items"))
+
+		uow, err = factory(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, uow.AddWorkUnit(insertItem("third")))
+		assert.Nil(t, uow.AddWorkUnit(insertItem("first")))
+		assert.NotNil(t, uow.Commit())
+		assert.Equal(t, 2, countRows(t, db, "items"))
+	})
+
+	t.Run("should join the transaction of the caller and leave its rollback to the caller", func(t *testing.T) {
+		db := openSQLite(t, "CREATE TABLE items (name TEXT NOT NULL UNIQUE)")
+		factory := NewUnitOfWorkFactory(db)
+
+		tx, err := db.Begin()
+		assert.Nil(t, err)
+		assert.Nil(t, insertItem("by caller")(tx))
+
+		uow, err := factory(WithTx(context.Background(), tx))
+		assert.Nil(t, err)
+		assert.Nil(t, uow.AddWorkUnit(insertItem("by unit of work")))
+		assert.Nil(t, uow.Commit())
+
+		// Committing the unit of work leaves the transaction open
+		var count int
+		assert.Nil(t, tx.QueryRow("SELECT COUNT(*) FROM items").Scan(&count))
+		assert.Equal(t, 2, count)
+
+		assert.Nil(t, tx.Rollback())
+		assert.Equal(t, 0, countRows(t, db, "items"))
+	})
+
+	t.Run("should join the transaction of the caller and leave its commit to the caller", func(t *testing.T) {
+		db := openSQLite(t, "CREATE TABLE items (name TEXT NOT NULL UNIQUE)")
+		factory := NewUnitOfWorkFactory(db)
+
+		tx, err := db.Begin()
+		assert.Nil(t, err)
+
+		uow, err := factory(WithTx(context.Background(), tx))
+		assert.Nil(t, err)
+		assert.Nil(t, uow.AddWorkUnit(insertItem("by unit of work")))
+		assert.Nil(t, uow.Commit())
+
+		assert.Nil(t, tx.Commit())
+		assert.Equal(t, 1, countRows(t, db, "items"))
+	})
+
+	t.Run("should leave the joined transaction open when the unit of work fails", func(t *testing.T) {
+		db := openSQLite(t, "CREATE TABLE items (name TEXT NOT NULL UNIQUE)")
+		factory := NewUnitOfWorkFactory(db)
+
+		tx, err := db.Begin()
+		assert.Nil(t, err)
+		assert.Nil(t, insertItem("by caller")(tx))
+
+		uow, err := factory(WithTx(context.Background(), tx))
+		assert.Nil(t, err)
+		assert.Nil(t, uow.AddWorkUnit(insertItem("by caller")))
+		assert.NotNil(t, uow.Commit())
+
+		// The caller decides what happens to the writes made before the failure
+		assert.Nil(t, tx.Commit())
+		assert.Equal(t, 1, countRows(t, db, "items"))
+	})
+}
+
+func TestTxHandler(t *testing.T) {
+	t.Run("should ignore rollbacks of transactions which are done or were not begun", func(t *testing.T) {
+		db := openSQLite(t)
+		handler := NewTxHandler(db)
+
+		assert.Nil(t, handler.Rollback(nil))
+
+		tx, err := handler.BeginTx(context.Background())
+		assert.Nil(t, err)
+		assert.Nil(t, handler.Commit(tx))
+		assert.Nil(t, handler.Rollback(tx))
+	})
+
+	t.Run("should carry the transaction in the context", func(t *testing.T) {
+		db := openSQLite(t)
+
+		_, ok := TxFrom(context.Background())
+		assert.False(t, ok)
+
+		tx, err := db.Begin()
+		assert.Nil(t, err)
+		defer tx.Rollback()
+
+		carried, ok := TxFrom(WithTx(context.Background(), tx))
+		assert.True(t, ok)
+		assert.Same(t, tx, carried)
+	})
+}
+
+// commit runs the executables in a unit of work on the database.
+func commit(t *testing.T, db *sql.DB, executables ...saga.Executable[*sql.Tx]) {
+	uow, err := NewUnitOfWorkFactory(db)(context.Background())
+	assert.Nil(t, err)
+	for _, executable := range executables {
+		assert.Nil(t, uow.AddWorkUnit(executable))
+	}
+	assert.Nil(t, uow.Commit())
+}
+
+// countRows returns the number of rows of the table.
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	var count int
+	assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&count))
+	return count
+}
+
+// openSQLite opens a database in a temporary file, and creates its tables by the statements.
+// The database is closed when the test finishes.
+func openSQLite(t *testing.T, statements ...string) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "saga.db"))
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	// A transaction holds the only connection, so that SQLite never reports the database as locked.
+	db.SetMaxOpenConns(1)
+
+	for _, statement := range statements {
+		_, err := db.Exec(statement)
+		assert.Nil(t, err)
+	}
+
+	return db
+}
@@ -1,5 +1,7 @@
 package saga
 
+import "time"
+
 type Step interface {
 	// Name returns the name of the step.
 	Name() string
@@ -12,10 +14,40 @@ type Step interface {
 
 	// MustBeCompleted returns true if the invocation action must be completed. So If it is true, something will be retried until it is completed.
 	MustBeCompleted() bool
+
+	// Timeout returns how long the step waits for a response before it is handled as failed. Zero means no timeout.
+	Timeout() time.Duration
+}
+
+// stepOptions holds the options of a step that do not depend on the kind of the step.
+type stepOptions struct {
+	timeout                 time.Duration
+	retryPolicy             RetryPolicy
+	compensationRetryPolicy RetryPolicy
+	conditions              []stepCondition
+	pivot                   bool
+}
+
+func (o stepOptions) Timeout() time.Duration {
+	return o.timeout
+}
+
+func (o stepOptions) options() stepOptions {
+	return o
+}
+
+// optionsOf returns the options of the step, or the zero options if the step has none.
+func optionsOf(step Step) stepOptions {
+	if s, ok := step.(interface{ options() stepOptions }); ok {
+		return s.options()
+	}
+
+	return stepOptions{}
 }
 
-func newRemoteStep[Tx TxContext](name string, endpoint Endpoint[Tx]) remoteStep[Tx] {
+func newRemoteStep[Tx TxContext](name string, endpoint Endpoint[Tx], options stepOptions) remoteStep[Tx] {
 	return remoteStep[Tx]{
+		stepOptions:    options,
 		name:           name,
 		invocation:     newRemoteInvocationAction(endpoint),
 		invokeEndpoint: endpoint,
@@ -25,6 +57,7 @@ func newRemoteStep[Tx TxContext](name string, endpoint Endpoint[Tx]) remoteStep[
 
 func newRemoteStepWithCompensation[Tx TxContext](step remoteStep[Tx], endpoint Endpoint[Tx]) remoteStep[Tx] {
 	return remoteStep[Tx]{
+		stepOptions:    step.stepOptions,
 		name:           step.name,
 		invocation:     step.invocation,
 		invokeEndpoint: step.invokeEndpoint,
@@ -34,8 +67,11 @@ func newRemoteStepWithCompensation[Tx TxContext](step remoteStep[Tx], endpoint E
 	}
 }
 
-func newRemoteStepWithRetry[Tx TxContext](step remoteStep[Tx]) remoteStep[Tx] {
+func newRemoteStepWithRetry[Tx TxContext](step remoteStep[Tx], policy RetryPolicy) remoteStep[Tx] {
+	step.stepOptions.retryPolicy = policy
+
 	return remoteStep[Tx]{
+		stepOptions:    step.stepOptions,
 		name:           step.name,
 		invocation:     step.invocation,
 		invokeEndpoint: step.invokeEndpoint,
@@ -46,6 +82,7 @@ func newRemoteStepWithRetry[Tx TxContext](step remoteStep[Tx]) remoteStep[Tx] {
 }
 
 type remoteStep[Tx TxContext] struct {
+	stepOptions
 	name string
 
 	invocation     invokeAction[Tx]
@@ -72,32 +109,43 @@ func (s remoteStep[Tx]) MustBeCompleted() bool {
 	return s.retry == true
 }
 
+func (s remoteStep[Tx]) invocationChannels() (ChannelName, ChannelName) {
+	return s.invokeEndpoint.SuccessResChannel(), s.invokeEndpoint.FailureResChannel()
+}
+
+func (s remoteStep[Tx]) compensationChannels() (ChannelName, ChannelName) {
+	return s.compEndpoint.SuccessResChannel(), s.compEndpoint.FailureResChannel()
+}
+
 func (s remoteStep[Tx]) SetRetry(retry bool) remoteStep[Tx] {
 	s.retry = retry
 	return s
 }
 
 func newRemoteCompensationAction[Tx TxContext](endpoint Endpoint[Tx]) compensateAction[Tx] {
-	return func(s Session) Executable[Tx] {
+	return func(s Session, inject messageInjector) Executable[Tx] {
 		command := endpoint.CommandConstructor()(s)
+		inject(command)
 		return endpoint.CommandRepository().SaveMessage(command)
 	}
 }
 
-type compensateAction[Tx TxContext] func(Session) Executable[Tx]
+type compensateAction[Tx TxContext] func(Session, messageInjector) Executable[Tx]
 
 func newRemoteInvocationAction[Tx TxContext](endpoint Endpoint[Tx]) invokeAction[Tx] {
-	return func(s Session) Executable[Tx] {
+	return func(s Session, inject messageInjector) Executable[Tx] {
 		command := endpoint.CommandConstructor()(s)
+		inject(command)
 		return endpoint.CommandRepository().SaveMessage(command)
 	}
 }
 
-type invokeAction[Tx TxContext] func(Session) Executable[Tx]
+type invokeAction[Tx TxContext] func(Session, messageInjector) Executable[Tx]
 
-func newLocalStep[Tx TxContext](name string, endpoint LocalEndpoint[Tx]) localStep[Tx] {
+func newLocalStep[Tx TxContext](name string, endpoint LocalEndpoint[Tx], options stepOptions) localStep[Tx] {
 	return localStep[Tx]{
-		name: name,
+		stepOptions: options,
+		name:        name,
 
 		invocation:     newLocalInvokeAction(endpoint),
 		invokeEndpoint: endpoint,
@@ -107,6 +155,7 @@ func newLocalStep[Tx TxContext](name string, endpoint LocalEndpoint[Tx]) localSt
 
 func newLocalStepWithCompensation[Tx TxContext](step localStep[Tx], endpoint LocalEndpoint[Tx]) localStep[Tx] {
 	return localStep[Tx]{
+		stepOptions:    step.stepOptions,
 		name:           step.name,
 		invocation:     step.invocation,
 		invokeEndpoint: step.invokeEndpoint,
@@ -116,8 +165,11 @@ func newLocalStepWithCompensation[Tx TxContext](step localStep[Tx], endpoint Loc
 	}
 }
 
-func newLocalStepWithRetry[Tx TxContext](step localStep[Tx]) localStep[Tx] {
+func newLocalStepWithRetry[Tx TxContext](step localStep[Tx], policy RetryPolicy) localStep[Tx] {
+	step.stepOptions.retryPolicy = policy
+
 	return localStep[Tx]{
+		stepOptions:    step.stepOptions,
 		name:           step.name,
 		invocation:     step.invocation,
 		invokeEndpoint: step.invokeEndpoint,
@@ -128,6 +180,7 @@ func newLocalStepWithRetry[Tx TxContext](step localStep[Tx]) localStep[Tx] {
 }
 
 type localStep[Tx TxContext] struct {
+	stepOptions
 	name string
 
 	invocation     localInvokeAction[Tx]
@@ -154,20 +207,30 @@ func (s localStep[Tx]) MustBeCompleted() bool {
 	return s.retry == true
 }
 
+func (s localStep[Tx]) invocationChannels() (ChannelName, ChannelName) {
+	return s.invokeEndpoint.SuccessResChannel(), s.invokeEndpoint.FailureResChannel()
+}
+
+func (s localStep[Tx]) compensationChannels() (ChannelName, ChannelName) {
+	return s.compEndpoint.SuccessResChannel(), s.compEndpoint.FailureResChannel()
+}
+
 func (s localStep[Tx]) SetRetry(retry bool) localStep[Tx] {
 	s.retry = retry
 	return s
 }
 
 func newLocalCompensateAction[Tx TxContext](endpoint LocalEndpoint[Tx]) localCompensateAction[Tx] {
-	action := func(s Session) (Executable[Tx], error) {
+	action := func(s Session, inject messageInjector) (Executable[Tx], error) {
 		cmd, err := endpoint.handle(s)
 		if err != nil {
 			msg := endpoint.FailureResponseConstructor()(s)
+			inject(msg)
 			return endpoint.FailureResRepository().SaveMessage(msg), nil
 		}
 
 		msg := endpoint.SuccessResponseConstructor()(s)
+		inject(msg)
 		cmd2 := endpoint.SuccessResRepository().SaveMessage(msg)
 		return CombineExecutables(cmd, cmd2), nil
 	}
@@ -175,20 +238,22 @@ func newLocalCompensateAction[Tx TxContext](endpoint LocalEndpoint[Tx]) localCom
 	return action
 }
 
-type localCompensateAction[Tx TxContext] func(Session) (Executable[Tx], error)
+type localCompensateAction[Tx TxContext] func(Session, messageInjector) (Executable[Tx], error)
 
 func newLocalInvokeAction[Tx TxContext](endpoint LocalEndpoint[Tx]) localInvokeAction[Tx] {
-	return func(s Session) (Executable[Tx], error) {
+	return func(s Session, inject messageInjector) (Executable[Tx], error) {
 		cmd, err := endpoint.handle(s)
 		if err != nil {
 			msg := endpoint.FailureResponseConstructor()(s)
+			inject(msg)
 			return endpoint.FailureResRepository().SaveMessage(msg), nil
 		}
 
 		msg := endpoint.SuccessResponseConstructor()(s)
+		inject(msg)
 		cmd2 := endpoint.SuccessResRepository().SaveMessage(msg)
 		return CombineExecutables(cmd, cmd2), nil
 	}
 }
 
-type localInvokeAction[Tx TxContext] func(Session) (Executable[Tx], error)
+type localInvokeAction[Tx TxContext] func(Session, messageInjector) (Executable[Tx], error)
@@ -0,0 +1,370 @@
+package saga
+
+import "errors"
+
+// SubSagaArgs returns the arguments of the session of a sub-saga, by the data of the parent session.
+type SubSagaArgs func(parent Session) map[string]interface{}
+
+// SubSagaSession is an optional interface that must be implemented by Session to run sub-saga steps,
+// and by the sessions of the sagas which are started by sub-saga steps.
+type SubSagaSession interface {
+	// ParentSession returns the saga name and the ID of the session which started this session by a sub-saga step,
+	// or empty strings if there is none.
+	ParentSession() (sagaName string, sessionID string)
+
+	// SetParentSession links this session to the session which started it.
+	SetParentSession(sagaName string, sessionID string)
+
+	// ChildSessionID returns the ID of the session started by the named sub-saga step, or false if the step has not started one.
+	ChildSessionID(step string) (string, bool)
+
+	// SetChildSessionID links the named sub-saga step to the session it started.
+	SetChildSessionID(step string, id string)
+}
+
+func newSubSagaStep[Tx TxContext](name string, sagaName string, args SubSagaArgs, options stepOptions) subSagaStep[Tx] {
+	return subSagaStep[Tx]{
+		stepOptions: options,
+		name:        name,
+		saga:        sagaName,
+		args:        args,
+	}
+}
+
+// subSagaStep starts a session of another registered saga, and waits until the session is completed or failed.
+// If the step is compensable, the completed session is compensated by stepping backward from its last step.
+type subSagaStep[Tx TxContext] struct {
+	stepOptions
+	name string
+
+	saga        string
+	args        SubSagaArgs
+	compensable bool
+}
+
+func (s subSagaStep[Tx]) Name() string {
+	return s.name
+}
+
+func (s subSagaStep[Tx]) IsCompensable() bool {
+	return s.compensable
+}
+
+func (s subSagaStep[Tx]) IsInvocable() bool {
+	return true
+}
+
+// MustBeCompleted returns false, since a failed session of the sub-saga is not started again.
+// The retriable steps of the sub-saga are retried within its session.
+func (s subSagaStep[Tx]) MustBeCompleted() bool {
+	return false
+}
+
+// SagaName returns the name of the saga started by the step.
+func (s subSagaStep[Tx]) SagaName() string {
+	return s.saga
+}
+
+// findSaga returns the saga registered with the given name to the registry the orchestrator is used by.
+func (o *orchestrator[Tx]) findSaga(sagaName string) (Saga[Session, Tx], error) {
+	if o.registry == nil {
+		return Saga[Session, Tx]{}, ErrSagaNotFound
+	}
+
+	s, ok := o.registry.findSaga(sagaName)
+	if !ok {
+		return Saga[Session, Tx]{}, ErrSagaNotFound
+	}
+
+	return s, nil
+}
+
+// invokeSubSagaStep starts the session of the sub-saga in the unit of work of the parent session.
+// It does not start the child by Registry.StartSaga, which commits a unit of work of its own, so that the child
+// and the parent waiting for it are saved together or not at all. Otherwise the child is started like startSaga does.
+func (o *orchestrator[Tx]) invokeSubSagaStep(session Session, step subSagaStep[Tx], def Definition, uow *UnitOfWork[Tx]) error {
+	parent, ok := session.(SubSagaSession)
+	if !ok {
+		return ErrSessionNotSubSaga
+	}
+
+	childSaga, err := o.findSaga(step.saga)
+	if err != nil {
+		return err
+	}
+
+	childDef := childSaga.Definition()
+	firstStep := childDef.FirstStep()
+	if firstStep == nil {
+		return ErrSagaHasNoSteps
+	}
+
+	args := make(map[string]interface{})
+	if step.args != nil {
+		for key, value := range step.args(session) {
+			args[key] = value
+		}
+	}
+
+	child := childSaga.createSession(args)
+	if child == nil {
+		return ErrSessionCreationFailed
+	}
+
+	if child.ID() == "" {
+		return ErrSessionIDEmpty
+	}
+
+	linked, ok := child.(SubSagaSession)
+	if !ok {
+		return ErrSessionNotSubSaga
+	}
+
+	linked.SetParentSession(def.sagaName, session.ID())
+	parent.SetChildSessionID(step.name, child.ID())
+
+	err = uow.AddWorkUnit(childSaga.Repository().Save(child))
+	if err != nil {
+		return err
+	}
+
+	o.emit(child, EventSagaStarted, nil, "", childDef, uow)
+	o.record(session, step, "", DirectionForward, OutcomeInvoked, nil, def, uow)
+	o.markPending(session, step)
+
+	err = o.enterStep(child, firstStep, childDef, uow)
+	if err != nil {
+		return err
+	}
+
+	// The child finishes at once if none of its steps waits for a response.
+	return o.settleSubSagaStep(session, child, step, def, uow)
+}
+
+// compensateSubSagaStep undoes the session of the sub-saga step, and waits until the session has failed.
+// A completed session is compensated backward from its last step, and a running session is canceled.
+func (o *orchestrator[Tx]) compensateSubSagaStep(session Session, step subSagaStep[Tx], def Definition, uow *UnitOfWork[Tx]) error {
+	o.record(session, step, "", DirectionBackward, OutcomeInvoked, nil, def, uow)
+	o.markPending(session, step)
+
+	childSaga, child, err := o.loadChild(session, step, uow)
+	if err != nil {
+		return err
+	}
+
+	if child == nil {
+		// No session was started, so there is nothing to compensate.
+		o.clearPending(session)
+		o.record(session, step, "", DirectionBackward, OutcomeSucceeded, nil, def, uow)
+		return o.handleCompensationResult(session, nil, step, def, uow)
+	}
+
+	switch child.State() {
+	case StateCompleted:
+		err = o.compensateChild(childSaga, child, uow)
+	case StateCommon, StateIsRetrying:
+		// The parent is compensated or canceled while the child is running.
+		err = o.cancelChild(childSaga, child, uow)
+	default:
+		// The child is already compensating, or waits for an operator.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = uow.AddWorkUnit(childSaga.Repository().Save(child))
+	if err != nil {
+		return err
+	}
+
+	// The child is failed at once if none of its steps waits for a compensation response.
+	return o.settleSubSagaStep(session, child, step, def, uow)
+}
+
+// cancelSubSaga cancels the session of the sub-saga step if it is running, since the parent does not wait for it anymore.
+func (o *orchestrator[Tx]) cancelSubSaga(session Session, step subSagaStep[Tx], uow *UnitOfWork[Tx]) error {
+	childSaga, child, err := o.loadChild(session, step, uow)
+	if err != nil || child == nil {
+		return err
+	}
+
+	if child.State() != StateCommon && child.State() != StateIsRetrying {
+		return nil
+	}
+
+	err = o.cancelChild(childSaga, child, uow)
+	if err != nil {
+		return err
+	}
+
+	return uow.AddWorkUnit(childSaga.Repository().Save(child))
+}
+
+// loadChild returns the saga and the session started by the sub-saga step, or a nil session if the step has not started one.
+// The child is changed without its session lock, so a concurrent change of the child fails the unit of work
+// by the version check of the repository.
+func (o *orchestrator[Tx]) loadChild(session Session, step subSagaStep[Tx], uow *UnitOfWork[Tx]) (Saga[Session, Tx], Session, error) {
+	parent, ok := session.(SubSagaSession)
+	if !ok {
+		return Saga[Session, Tx]{}, nil, ErrSessionNotSubSaga
+	}
+
+	childID, ok := parent.ChildSessionID(step.name)
+	if !ok {
+		return Saga[Session, Tx]{}, nil, nil
+	}
+
+	childSaga, err := o.findSaga(step.saga)
+	if err != nil {
+		return Saga[Session, Tx]{}, nil, err
+	}
+
+	child, err := loadSession(uow.Context(), childSaga.Repository(), childID)
+	if err != nil {
+		return Saga[Session, Tx]{}, nil, err
+	}
+
+	return childSaga, child, nil
+}
+
+// compensateChild makes the completed session of a sub-saga step backward from its last step.
+func (o *orchestrator[Tx]) compensateChild(childSaga Saga[Session, Tx], child Session, uow *UnitOfWork[Tx]) error {
+	childDef := childSaga.Definition()
+	lastStep := childDef.FindStep(child.CurrentStep().Name())
+	if lastStep == nil {
+		return ErrSessionStepAndDefinitionMismatch
+	}
+
+	if historySession, ok := child.(HistorySession); ok {
+		return o.stepBackwardByHistory(child, historySession, childDef, uow)
+	}
+
+	return o.compensateFrom(child, lastStep, childDef, uow)
+}
+
+// cancelChild compensates the running session of a sub-saga step from its current step, like an operator canceling it.
+// A session after its pivot step cannot be canceled, so it is left to complete and compensated then.
+func (o *orchestrator[Tx]) cancelChild(childSaga Saga[Session, Tx], child Session, uow *UnitOfWork[Tx]) error {
+	childDef := childSaga.Definition()
+	curStep := childDef.FindStep(child.CurrentStep().Name())
+	if curStep == nil {
+		return ErrSessionStepAndDefinitionMismatch
+	}
+
+	err := o.cancelSession(child, curStep, childDef, uow)
+	if errors.Is(err, ErrSessionAfterPivot) {
+		return nil
+	}
+
+	return err
+}
+
+// settleSubSagaStep moves the parent session by the state of its child session, if the child has finished.
+// A child failed while the parent is compensating means the child has been compensated, and a child completed
+// while the parent is compensating is compensated now. The child is saved by the caller.
+func (o *orchestrator[Tx]) settleSubSagaStep(session Session, child Session, step subSagaStep[Tx], def Definition, uow *UnitOfWork[Tx]) error {
+	compensating := session.State() == StateIsCompensating
+
+	switch {
+	case child.State() == StateFailed && compensating:
+		o.clearPending(session)
+		o.record(session, step, "", DirectionBackward, OutcomeSucceeded, nil, def, uow)
+		return o.handleCompensationResult(session, nil, step, def, uow)
+	case child.State() == StateFailed:
+		o.clearPending(session)
+		o.record(session, step, "", DirectionForward, OutcomeFailed, nil, def, uow)
+		return o.handleInvocationResult(session, ErrSubSagaFailed, step, def, uow)
+	case child.State() == StateCompleted && compensating:
+		// The child completed after it could not be canceled, for example because it was past its pivot step.
+		childSaga, err := o.findSaga(step.saga)
+		if err != nil {
+			return err
+		}
+
+		err = o.compensateChild(childSaga, child, uow)
+		if err != nil {
+			return err
+		}
+
+		return o.settleSubSagaStep(session, child, step, def, uow)
+	case child.State() == StateCompleted:
+		o.clearPending(session)
+		o.record(session, step, "", DirectionForward, OutcomeSucceeded, nil, def, uow)
+		return o.handleInvocationResult(session, nil, step, def, uow)
+	}
+
+	return nil
+}
+
+// resumeParent moves the parent session in the unit of work, if the session was started by a sub-saga step and has finished.
+// If the session lock is enabled, the lock of the parent is held until release is called, after the unit of work is done.
+// Sessions are locked from the child to the parent only, so the locks cannot deadlock.
+func (o *orchestrator[Tx]) resumeParent(session Session, uow *UnitOfWork[Tx]) (release func(), err error) {
+	release = func() {}
+
+	if session.State() != StateCompleted && session.State() != StateFailed {
+		return release, nil
+	}
+
+	child, ok := session.(SubSagaSession)
+	if !ok {
+		return release, nil
+	}
+
+	parentSagaName, parentID := child.ParentSession()
+	if parentID == "" {
+		return release, nil
+	}
+
+	parentSaga, err := o.findSaga(parentSagaName)
+	if err != nil {
+		return release, err
+	}
+
+	if o.locks != nil {
+		release = o.locks.lock(parentID)
+	}
+
+	parent, err := loadSession(uow.Context(), parentSaga.Repository(), parentID)
+	if err != nil {
+		return release, err
+	}
+
+	if !parent.IsPending() || parent.CurrentStep() == nil {
+		// The parent does not wait for the child anymore, for example because the step timed out.
+		return release, nil
+	}
+
+	def := parentSaga.Definition()
+	step, ok := def.FindStep(parent.CurrentStep().Name()).(subSagaStep[Tx])
+	if !ok {
+		return release, nil
+	}
+
+	linked, ok := parent.(SubSagaSession)
+	if !ok {
+		return release, nil
+	}
+
+	if childID, ok := linked.ChildSessionID(step.name); !ok || childID != session.ID() {
+		return release, nil
+	}
+
+	err = o.settleSubSagaStep(parent, session, step, def, uow)
+	if err != nil {
+		return release, err
+	}
+
+	err = uow.AddWorkUnit(parentSaga.Repository().Save(parent))
+	if err != nil {
+		return release, err
+	}
+
+	releaseParent := release
+	releaseAncestors, err := o.resumeParent(parent, uow)
+	return func() {
+		releaseAncestors()
+		releaseParent()
+	}, err
+}
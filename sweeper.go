@@ -0,0 +1,41 @@
+package saga
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TimeoutSweeper finds the pending sessions which passed the deadline of their current step,
+// and handles them as if a failure response had arrived. So the step is retried if it must be completed,
+// and compensated otherwise.
+//
+// Only sagas whose SessionRepository implements ExpiredSessionRepository or SessionQueryRepository,
+// and whose sessions implement DeadlineSession, are swept. TimeoutSweeper implements messageRelayer.BatchJob, so it can be run periodically
+// with messageRelayer.StartBatchRun.
+type TimeoutSweeper[Tx TxContext] struct {
+	batchSize int
+	mutex     sync.Mutex
+	registry  *Registry[Tx]
+}
+
+func NewTimeoutSweeper[Tx TxContext](registry *Registry[Tx], batchSize int) *TimeoutSweeper[Tx] {
+	return &TimeoutSweeper[Tx]{
+		batchSize: batchSize,
+		mutex:     sync.Mutex{},
+		registry:  registry,
+	}
+}
+
+func (s *TimeoutSweeper[Tx]) Execute() error {
+	return s.ExecuteContext(context.Background())
+}
+
+// ExecuteContext sweeps at most batchSize expired sessions of each saga.
+// Errors of each session are joined and returned after every session is handled.
+func (s *TimeoutSweeper[Tx]) ExecuteContext(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.registry.expireSessions(ctx, time.Now(), s.batchSize)
+}
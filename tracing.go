@@ -0,0 +1,110 @@
+package saga
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer which the spans of the orchestrator are started by.
+const TracerName = "github.com/violetpay-org/go-saga"
+
+// Attributes set on the spans of the orchestrator.
+const (
+	AttributeSagaName     = attribute.Key("saga.name")
+	AttributeSessionID    = attribute.Key("saga.session.id")
+	AttributeStep         = attribute.Key("saga.step")
+	AttributeBranch       = attribute.Key("saga.branch")
+	AttributeAttempt      = attribute.Key("saga.attempt")
+	AttributeMessageID    = attribute.Key("saga.message.id")
+	AttributeChannel      = attribute.Key("saga.channel")
+	AttributeIntervention = attribute.Key("saga.intervention")
+)
+
+// WithTracerProvider sets the provider of the tracer which the orchestrator starts spans by.
+// The default is the global provider of OpenTelemetry.
+func WithTracerProvider[Tx TxContext](provider trace.TracerProvider) OrchestratorOption[Tx] {
+	return func(o *orchestratorOptions[Tx]) {
+		o.tracerProvider = provider
+	}
+}
+
+// WithPropagator sets the propagator which the orchestrator injects trace context into messages by,
+// and extracts trace context from responses by. The default is the global propagator of OpenTelemetry.
+func WithPropagator[Tx TxContext](propagator propagation.TextMapPropagator) OrchestratorOption[Tx] {
+	return func(o *orchestratorOptions[Tx]) {
+		o.propagator = propagator
+	}
+}
+
+// InjectTraceContext sets the trace context of ctx to the headers of the message, if the message has headers.
+func InjectTraceContext(ctx context.Context, propagator propagation.TextMapPropagator, msg Message) {
+	headerMessage, ok := msg.(HeaderMessage)
+	if !ok || headerMessage.Headers() == nil {
+		return
+	}
+
+	propagator.Inject(ctx, propagation.MapCarrier(headerMessage.Headers()))
+}
+
+// ExtractTraceContext returns ctx with the trace context carried by the headers of the message.
+func ExtractTraceContext(ctx context.Context, propagator propagation.TextMapPropagator, msg Message) context.Context {
+	headerMessage, ok := msg.(HeaderMessage)
+	if !ok || headerMessage.Headers() == nil {
+		return ctx
+	}
+
+	return propagator.Extract(ctx, propagation.MapCarrier(headerMessage.Headers()))
+}
+
+// EndSpan records err on the span if it is not nil, and ends the span.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// messageInjector adds the trace context of a step to the messages constructed for the step.
+type messageInjector func(msg Message)
+
+func newTracing[Tx TxContext](options orchestratorOptions[Tx]) (trace.Tracer, propagation.TextMapPropagator) {
+	provider := options.tracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	propagator := options.propagator
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+
+	return provider.Tracer(TracerName), propagator
+}
+
+// startStepSpan starts the span of the invocation or compensation of the step, or of one of its branches,
+// as a child of the span of the unit of work. The messages constructed by the returned injector carry the span.
+func (o *orchestrator[Tx]) startStepSpan(operation string, session Session, step Step, branch string, uow *UnitOfWork[Tx]) (trace.Span, messageInjector) {
+	attributes := []attribute.KeyValue{
+		AttributeSessionID.String(session.ID()),
+		AttributeStep.String(step.Name()),
+		AttributeAttempt.Int(o.attempt(session)),
+	}
+
+	name := operation + " " + step.Name()
+	if branch != "" {
+		name += "/" + branch
+		attributes = append(attributes, AttributeBranch.String(branch))
+	}
+
+	ctx, span := o.tracer.Start(uow.Context(), name, trace.WithAttributes(attributes...))
+
+	return span, func(msg Message) {
+		InjectTraceContext(ctx, o.propagator, msg)
+	}
+}
@@ -2,44 +2,13 @@ package saga
 
 import (
 	"context"
-	"log"
+	"log/slog"
 )
 
-//type MySQLTxContext struct {
-//	Tx *sql.Tx
-//}
-//
-//type MySQLTxHandler struct {
-//	DB *sql.DB
-//}
-//
-//func (ctor *MySQLTxHandler) BeginTx(context context.Context) (ctx MySQLTxContext, error error) {
-//	tx, err := ctor.DB.BeginTx(context, nil)
-//	if err != nil {
-//		return MySQLTxContext{}, err
-//	}
-//
-//	return MySQLTxContext{Tx: tx}, nil
-//}
-//
-//func (ctor *MySQLTxHandler) Commit(ctx MySQLTxContext) error {
-//	if err := ctx.Tx.Commit(); err != nil {
-//		return err
-//	}
-//
-//	return nil
-//}
-//
-//func (ctor *MySQLTxHandler) Rollback(ctx MySQLTxContext) error {
-//	if err := ctx.Tx.Rollback(); err != nil {
-//		return err
-//	}
-//
-//	return nil
-//}
-
 type TxContext interface{}
 
+// TxHandler begins, commits and rolls back the transactions of units of work.
+// sqlStore.TxHandler implements it for database/sql.
 type TxHandler[Tx TxContext] interface {
 	BeginTx(ctx context.Context) (tx Tx, error error)
 	Commit(ctx Tx) error
@@ -74,6 +43,13 @@ type UnitOfWork[Tx TxContext] struct {
 	unitChan chan Executable[Tx]
 	ctx      context.Context
 	commited bool
+	logger   *slog.Logger
+
+	// deferred are executed after the work units, in the same transaction.
+	deferred []Executable[Tx]
+
+	// committed are run after the transaction is committed.
+	committed []func(ctx context.Context)
 }
 
 func NewUnitOfWork[Tx TxContext](ctx context.Context, handler TxHandler[Tx]) *UnitOfWork[Tx] {
@@ -81,6 +57,7 @@ func NewUnitOfWork[Tx TxContext](ctx context.Context, handler TxHandler[Tx]) *Un
 		handler:  handler,
 		unitChan: make(chan Executable[Tx], 100),
 		ctx:      ctx,
+		logger:   slog.Default(),
 	}
 }
 
@@ -111,6 +88,7 @@ commitLoop:
 			backupChan <- executable
 			err := executable(ctx)
 			if err != nil {
+				u.logger.WarnContext(u.ctx, "work unit failed", LogKeyError, err)
 				errors <- err
 			}
 		default:
@@ -119,6 +97,14 @@ commitLoop:
 		}
 	}
 
+	for _, executable := range u.deferred {
+		err := executable(ctx)
+		if err != nil {
+			u.logger.WarnContext(u.ctx, "deferred work unit failed", LogKeyError, err)
+			errors <- err
+		}
+	}
+
 	select {
 	case err := <-errors:
 		u.unitChan = backupChan
@@ -129,11 +115,38 @@ commitLoop:
 
 	close(backupChan)
 
-	log.Print()
 	return nil
 }
 
+// deferWorkUnit adds a work unit which is executed after the other work units, in the same transaction.
+func (u *UnitOfWork[Tx]) deferWorkUnit(workUnit Executable[Tx]) {
+	u.deferred = append(u.deferred, workUnit)
+}
+
+// afterCommit adds a function which is run in a new goroutine once the transaction is committed.
+// The functions are run in the order they were added, with a context which is not canceled with the unit of work.
+func (u *UnitOfWork[Tx]) afterCommit(fn func(ctx context.Context)) {
+	u.committed = append(u.committed, fn)
+}
+
+// SetLogger sets the logger which the unit of work logs its failed work units with. The default is slog.Default().
+// The orchestrator and the relayer set it to their own loggers for the units of work they create.
+func (u *UnitOfWork[Tx]) SetLogger(logger *slog.Logger) {
+	u.logger = logger
+}
+
+// Context returns the context the unit of work was created with.
+func (u *UnitOfWork[Tx]) Context() context.Context {
+	return u.ctx
+}
+
+// Commit begins a transaction with the context of the unit of work, and executes every work unit in it.
+// If the context is already canceled or past its deadline, nothing is executed and the context error is returned.
 func (u *UnitOfWork[Tx]) Commit() error {
+	if err := u.ctx.Err(); err != nil {
+		return err
+	}
+
 	tx, err := u.handler.BeginTx(u.ctx)
 	defer u.handler.Rollback(tx)
 	if err != nil {
@@ -147,8 +160,18 @@ func (u *UnitOfWork[Tx]) Commit() error {
 
 	err = u.handler.Commit(tx)
 	if err != nil {
+		u.logger.ErrorContext(u.ctx, "failed to commit transaction", LogKeyError, err)
 		return err
 	}
 
+	if len(u.committed) > 0 {
+		ctx := context.WithoutCancel(u.ctx)
+		go func() {
+			for _, fn := range u.committed {
+				fn(ctx)
+			}
+		}()
+	}
+
 	return nil
 }
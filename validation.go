@@ -0,0 +1,191 @@
+package saga
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationProblem is a problem found in a definition.
+type ValidationProblem struct {
+	// Step is the name of the step which has the problem, or empty if the problem is about the whole definition.
+	Step string
+
+	// Branch is the name of the branch if the step is a parallel step and the problem is about one of its branches.
+	Branch string
+
+	// Err is the validation error of the problem, such as ErrDuplicateStepName.
+	Err error
+}
+
+func (p ValidationProblem) Error() string {
+	switch {
+	case p.Branch != "":
+		return fmt.Sprintf("step %q, branch %q: %s", p.Step, p.Branch, p.Err)
+	case p.Step != "":
+		return fmt.Sprintf("step %q: %s", p.Step, p.Err)
+	default:
+		return p.Err.Error()
+	}
+}
+
+func (p ValidationProblem) Unwrap() error {
+	return p.Err
+}
+
+// ValidationError is returned by StepBuilder.Build and RegisterSagaTo if the definition cannot be executed safely.
+// It lists every problem found in the definition, and errors.Is matches the validation error of any of them.
+type ValidationError struct {
+	Problems []ValidationProblem
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		messages = append(messages, problem.Error())
+	}
+
+	return "invalid saga definition: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Problems))
+	for _, problem := range e.Problems {
+		errs = append(errs, problem)
+	}
+
+	return errs
+}
+
+// Validate returns a *ValidationError if the definition cannot be executed safely, or nil otherwise.
+func (d Definition) Validate() error {
+	v := &validator{}
+	v.validateDefinition(d)
+
+	return v.err()
+}
+
+type validator struct {
+	problems []ValidationProblem
+}
+
+func (v *validator) add(problem ValidationProblem) {
+	v.problems = append(v.problems, problem)
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Problems: v.problems}
+}
+
+func (v *validator) validateDefinition(d Definition) {
+	v.validateSteps(d.steps, "")
+	v.validatePivot(d.steps)
+}
+
+// validateSteps validates the steps of a definition, or the branches of the parallel step named parent.
+func (v *validator) validateSteps(steps []Step, parent string) {
+	names := make(map[string]bool)
+
+	// A response to a parallel step is matched to its branch by the channel, so branches cannot share one.
+	invocationChannels := make(map[ChannelName]bool)
+	compensationChannels := make(map[ChannelName]bool)
+
+	for _, step := range steps {
+		problem := ValidationProblem{Step: step.Name()}
+		if parent != "" {
+			problem = ValidationProblem{Step: parent, Branch: step.Name()}
+		}
+
+		report := func(err error) {
+			problem.Err = err
+			v.add(problem)
+		}
+
+		if step.Name() == "" {
+			report(ErrStepNameEmpty)
+		} else if names[step.Name()] {
+			report(ErrDuplicateStepName)
+		}
+		names[step.Name()] = true
+
+		s, responded := step.(respondedStep)
+		if parent != "" && !responded {
+			report(ErrUnsupportedBranchStep)
+		}
+
+		if responded {
+			success, failure := s.invocationChannels()
+			if success == "" || failure == "" {
+				report(ErrInvocationWithoutResponseChannel)
+			}
+
+			if parent != "" && isSharedChannel(invocationChannels, success, failure) {
+				report(ErrBranchResponseChannelShared)
+			}
+
+			if step.IsCompensable() {
+				success, failure = s.compensationChannels()
+				if success == "" || failure == "" {
+					report(ErrCompensationWithoutResponseChannel)
+				}
+
+				if parent != "" && isSharedChannel(compensationChannels, success, failure) {
+					report(ErrBranchResponseChannelShared)
+				}
+			}
+		}
+
+		// A nested parallel step is reported as an unsupported branch, so its branches are not validated.
+		if s, ok := step.(interface{ Branches() []Step }); ok && parent == "" {
+			v.validateSteps(s.Branches(), step.Name())
+		}
+
+		if s, ok := step.(interface{ SagaName() string }); ok && s.SagaName() == "" {
+			report(ErrSubSagaNameEmpty)
+		}
+	}
+}
+
+// isSharedChannel returns true if channels already has one of the given channels, and adds them to channels.
+func isSharedChannel(channels map[ChannelName]bool, given ...ChannelName) bool {
+	shared := false
+	for _, channel := range given {
+		if channel == "" {
+			continue
+		}
+
+		if channels[channel] {
+			shared = true
+		}
+		channels[channel] = true
+	}
+
+	return shared
+}
+
+// validatePivot validates that there is only one pivot step, and every step after it is retriable.
+// Parallel and sub-saga steps are never retriable, so they are rejected after the pivot step.
+func (v *validator) validatePivot(steps []Step) {
+	pivoted := false
+	for _, step := range steps {
+		if pivoted && !step.MustBeCompleted() {
+			v.add(ValidationProblem{Step: step.Name(), Err: ErrStepAfterPivotNotRetriable})
+		}
+
+		if optionsOf(step).pivot {
+			if pivoted {
+				v.add(ValidationProblem{Step: step.Name(), Err: ErrMultiplePivotSteps})
+			}
+			pivoted = true
+		}
+	}
+}
+
+// respondedStep is implemented by the steps whose invocations and compensations respond on channels.
+type respondedStep interface {
+	invocationChannels() (success ChannelName, failure ChannelName)
+	compensationChannels() (success ChannelName, failure ChannelName)
+}